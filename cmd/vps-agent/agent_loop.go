@@ -2,14 +2,22 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
+	"runtime"
 	"time"
 
 	"vps-agent/internal/agent"
+	"vps-agent/internal/buildinfo"
 	"vps-agent/internal/config"
 	"vps-agent/internal/reporter"
+	"vps-agent/internal/updater"
 )
 
+// remoteConfigInterval is how often the agent asks the server whether its
+// settings changed. Unchanged settings cost a 304.
+const remoteConfigInterval = time.Minute
+
 func runAgentLoop(ctx context.Context, configPath string) error {
 	cfg, err := config.Load(configPath)
 	if err != nil {
@@ -21,14 +29,33 @@ func runAgentLoop(ctx context.Context, configPath string) error {
 	rep := reporter.New(cfg)
 	collector := agent.NewCollector(cfg)
 	log.Printf("agent started node_id=%s server=%s interval=%s", cfg.NodeID, cfg.Server, cfg.BasicInterval)
-	ticker := time.NewTicker(cfg.BasicInterval)
+	if ns := collector.Namespaces(); ns.Container {
+		log.Printf("running in a container host_pid=%t host_network=%t host_uts=%t host_root=%s", ns.PID, ns.Network, ns.UTS, cfg.HostRoot)
+		if !ns.PID || !ns.Network {
+			log.Print("processes and connections are the container's own; run with the host PID and network namespaces to monitor the host")
+		}
+	}
+	updates, err := newUpdater(collector)
+	if err != nil {
+		return err
+	}
+	interval := cfg.BasicInterval
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
+	var remoteVersion string
+	var lastRemote time.Time
+	stats := &telemetry{started: time.Now()}
 	for {
-		metrics, err := collector.Collect(ctx)
-		if err != nil {
-			log.Printf("collect failed: %v", err)
-		} else if err := rep.Send(ctx, metrics); err != nil {
-			log.Printf("report failed: %v", err)
+		if time.Since(lastRemote) >= remoteConfigInterval {
+			lastRemote = time.Now()
+			remoteVersion = applyRemoteConfig(ctx, rep, collector, &cfg, configPath, remoteVersion)
+			if next := collector.Config().BasicInterval; next != interval {
+				interval = next
+				ticker.Reset(interval)
+			}
+		}
+		if err := report(ctx, rep, collector, updates, stats); err != nil {
+			return err
 		}
 		select {
 		case <-ctx.Done():
@@ -38,3 +65,152 @@ func runAgentLoop(ctx context.Context, configPath string) error {
 		}
 	}
 }
+
+// report collects and sends one sample and acts on an update offer. It only
+// returns an error when the agent has to restart into another executable.
+func report(ctx context.Context, rep *reporter.Reporter, collector *agent.Collector, updates *updater.Updater, stats *telemetry) error {
+	start := time.Now()
+	metrics, err := collector.Collect(ctx)
+	stats.collect = time.Since(start)
+	if err != nil {
+		stats.failed(&stats.collectErrors, err)
+		log.Printf("collect failed: %v", err)
+	} else {
+		metrics.Agent = stats.snapshot()
+		var resp agent.ReportResponse
+		start = time.Now()
+		resp, err = rep.Send(ctx, metrics)
+		stats.send = time.Since(start)
+		if err != nil {
+			stats.failed(&stats.reportErrors, err)
+			log.Printf("report failed: %v", err)
+		} else if updates != nil {
+			updates.Reported()
+			if resp.Update != nil && collector.Config().AutoUpdate {
+				err := updates.Apply(ctx, *resp.Update, rep.Download)
+				if errors.Is(err, updater.ErrRestart) {
+					return err
+				}
+				if err != nil {
+					log.Printf("self-update to %s failed: %v", resp.Update.Version, err)
+				}
+			}
+		}
+	}
+	if err != nil && updates != nil {
+		if err := updates.CheckTrial(); errors.Is(err, updater.ErrRestart) {
+			return err
+		} else if err != nil {
+			log.Printf("rollback failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// telemetry tracks the agent's own health for the next report.
+type telemetry struct {
+	started       time.Time
+	collect       time.Duration
+	send          time.Duration
+	collectErrors int
+	reportErrors  int
+	lastError     string
+	lastErrorAt   time.Time
+}
+
+// maxErrorLength keeps a long response body out of every report.
+const maxErrorLength = 200
+
+func (t *telemetry) failed(counter *int, err error) {
+	*counter++
+	t.lastError = err.Error()
+	if runes := []rune(t.lastError); len(runes) > maxErrorLength {
+		t.lastError = string(runes[:maxErrorLength])
+	}
+	t.lastErrorAt = time.Now()
+}
+
+func (t *telemetry) snapshot() *agent.AgentStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	stats := &agent.AgentStats{
+		UptimeSec:     int64(time.Since(t.started).Seconds()),
+		CollectMs:     durationMs(t.collect),
+		SendMs:        durationMs(t.send),
+		CollectErrors: t.collectErrors,
+		ReportErrors:  t.reportErrors,
+		LastError:     t.lastError,
+		MemoryBytes:   mem.Sys,
+		Goroutines:    runtime.NumGoroutine(),
+	}
+	if !t.lastErrorAt.IsZero() {
+		stats.LastErrorAt = t.lastErrorAt.Unix()
+	}
+	return stats
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// newUpdater enables self-update unless the agent runs in a container, where
+// the image rather than the binary is what gets upgraded. A build that kept
+// failing is rolled back here, before the first report.
+func newUpdater(collector *agent.Collector) (*updater.Updater, error) {
+	if collector.Namespaces().Container {
+		return nil, nil
+	}
+	updates, err := updater.New(buildinfo.Version, collector.Config().ReleaseKey)
+	if errors.Is(err, updater.ErrRestart) {
+		return nil, err
+	}
+	if err != nil {
+		log.Printf("self-update disabled: %v", err)
+		return nil, nil
+	}
+	return updates, nil
+}
+
+// applyRemoteConfig fetches the server's settings and, when the version
+// changed, applies them on top of the local config. Overrides that fail to
+// parse leave the running settings alone. A node ID the server renamed is
+// switched to and written back to the config file at configPath. It returns
+// the version now seen.
+func applyRemoteConfig(ctx context.Context, rep *reporter.Reporter, collector *agent.Collector, local *config.Config, configPath, version string) string {
+	remote, changed, err := rep.FetchConfig(ctx, version)
+	if err != nil {
+		log.Printf("remote config fetch failed: %v", err)
+		return version
+	}
+	if !changed {
+		return version
+	}
+	if remote.NodeID != "" && remote.NodeID != local.NodeID {
+		renamed := *local
+		renamed.NodeID = remote.NodeID
+		if err := renamed.Validate(); err != nil {
+			log.Printf("remote config node_id=%s rejected: %v", remote.NodeID, err)
+		} else {
+			log.Printf("node renamed node_id=%s -> %s", local.NodeID, remote.NodeID)
+			*local = renamed
+			rep.SetNodeID(local.NodeID)
+			if err := config.SetValue(configPath, "NODE_ID", local.NodeID); err != nil {
+				log.Printf("saving renamed node_id failed, the old ID stays an alias: %v", err)
+			}
+		}
+	}
+	effective, err := local.WithOverrides(remote.Settings)
+	if err == nil {
+		err = effective.Validate()
+	}
+	if err != nil {
+		log.Printf("remote config version=%s settings rejected: %v", remote.Version, err)
+	} else {
+		collector.SetConfig(effective)
+		log.Printf("remote config applied version=%s settings=%d probes=%d", remote.Version, len(remote.Settings), len(remote.Probes))
+	}
+	if err := collector.SetRemoteProbes(remote.Probes); err != nil {
+		log.Printf("remote config skipped invalid probes: %v", err)
+	}
+	return remote.Version
+}
@@ -16,11 +16,10 @@ import (
 	"time"
 
 	"vps-agent/internal/agent"
+	"vps-agent/internal/buildinfo"
 	"vps-agent/internal/config"
 )
 
-const version = "0.1.0"
-
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
 
@@ -44,7 +43,7 @@ func main() {
 			log.Fatal(err)
 		}
 	case "version":
-		fmt.Printf("vps-agent %s %s/%s\n", version, runtime.GOOS, runtime.GOARCH)
+		fmt.Printf("vps-agent %s %s/%s\n", buildinfo.Version, runtime.GOOS, runtime.GOARCH)
 	default:
 		usage()
 		os.Exit(2)
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
 )
 
 func runWindowsService(configPath string) error {
@@ -18,9 +19,33 @@ func runWindowsService(configPath string) error {
 	if !isService {
 		return runAgentLoop(context.Background(), configPath)
 	}
+	if err := ensureRecoveryActions(); err != nil {
+		log.Printf("service recovery actions not set: %v", err)
+	}
 	return svc.Run("vps-agent", windowsService{configPath: configPath})
 }
 
+// ensureRecoveryActions has the service manager restart the agent when it
+// stops with an error, which is how the agent restarts into a new build after
+// a self-update. Services created by older installers have no such actions.
+func ensureRecoveryActions() error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	s, err := m.OpenService("vps-agent")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	restart := mgr.RecoveryAction{Type: mgr.ServiceRestart, Delay: 5 * time.Second}
+	if err := s.SetRecoveryActions([]mgr.RecoveryAction{restart, restart, restart}, 86400); err != nil {
+		return err
+	}
+	return s.SetRecoveryActionsOnNonCrashFailures(true)
+}
+
 type windowsService struct {
 	configPath string
 }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"vps-agent/internal/buildinfo"
+	"vps-agent/internal/release"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "keygen":
+		err = keygen(os.Args[2:])
+	case "sign":
+		err = sign(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// keygen writes a new signing key. The key never goes on the server; only
+// the public key printed here ends up in release.pub.
+func keygen(args []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	out := fs.String("out", "release.key", "signing key file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	private, public, err := release.GenerateKey()
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(file, private); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Println(public)
+	return nil
+}
+
+func sign(args []string) error {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	keyPath := fs.String("key", "", "signing key file written by keygen")
+	dir := fs.String("dir", "internal/server/agent_bins", "directory with the vps-agent binaries")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *keyPath == "" {
+		return fmt.Errorf("sign: -key is required")
+	}
+	data, err := os.ReadFile(*keyPath)
+	if err != nil {
+		return err
+	}
+	key, err := release.ParsePrivateKey(string(data))
+	if err != nil {
+		return err
+	}
+	if err := release.WriteSigned(*dir, buildinfo.Version, key); err != nil {
+		return err
+	}
+	fmt.Printf("signed %s/%s for %s\n", *dir, release.ManifestName, buildinfo.Version)
+	return nil
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: vps-release keygen [-out release.key] | sign -key release.key [-dir internal/server/agent_bins]")
+}
@@ -14,38 +14,79 @@ import (
 	"syscall"
 )
 
-func readCPUTimes() (cpuTimes, error) {
-	data, err := os.ReadFile("/proc/stat")
+func (h hostPaths) readCPUTimes() (cpuTimes, error) {
+	data, err := os.ReadFile(h.procPath("stat"))
 	if err != nil {
 		return cpuTimes{}, err
 	}
-	line, _, _ := bytes.Cut(data, []byte("\n"))
-	fields := strings.Fields(string(line))
-	if len(fields) < 5 || fields[0] != "cpu" {
+	return parseCPUStat(data)
+}
+
+func parseCPUStat(data []byte) (cpuTimes, error) {
+	var total cpuTimes
+	found := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		times, err := parseCPUFields(fields[1:])
+		if err != nil {
+			return cpuTimes{}, err
+		}
+		if fields[0] == "cpu" {
+			total = times
+			found = true
+			continue
+		}
+		total.cores = append(total.cores, times)
+	}
+	if err := scanner.Err(); err != nil {
+		return cpuTimes{}, err
+	}
+	if !found {
 		return cpuTimes{}, errors.New("invalid /proc/stat")
 	}
+	return total, nil
+}
 
-	var values [10]uint64
-	for i := 1; i < len(fields) && i <= len(values); i++ {
+// parseCPUFields reads the user through steal columns of a /proc/stat cpu
+// line. The guest and guest_nice columns that follow are already counted in
+// user and nice, so they are left out of the total.
+func parseCPUFields(fields []string) (cpuTimes, error) {
+	var values [8]uint64
+	for i := 0; i < len(fields) && i < len(values); i++ {
 		v, err := strconv.ParseUint(fields[i], 10, 64)
 		if err != nil {
 			return cpuTimes{}, err
 		}
-		values[i-1] = v
+		values[i] = v
 	}
 	var total uint64
 	for _, v := range values {
 		total += v
 	}
-	idle := values[3] + values[4]
-	return cpuTimes{idle: idle, total: total}, nil
+	return cpuTimes{
+		user:   values[0] + values[1],
+		system: values[2],
+		idle:   values[3] + values[4],
+		iowait: values[4],
+		irq:    values[5] + values[6],
+		steal:  values[7],
+		total:  total,
+	}, nil
 }
 
-func readMemory() (Memory, Memory, error) {
-	data, err := os.ReadFile("/proc/meminfo")
+func (h hostPaths) readMemory() (Memory, Memory, *MemoryDetail, error) {
+	data, err := os.ReadFile(h.procPath("meminfo"))
 	if err != nil {
-		return Memory{}, Memory{}, err
+		return Memory{}, Memory{}, nil, err
 	}
+	return parseMemInfo(data)
+}
+
+func parseMemInfo(data []byte) (Memory, Memory, *MemoryDetail, error) {
 	values := map[string]uint64{}
 	scanner := bufio.NewScanner(bytes.NewReader(data))
 	for scanner.Scan() {
@@ -58,10 +99,13 @@ func readMemory() (Memory, Memory, error) {
 		if err != nil {
 			continue
 		}
-		values[key] = v * 1024
+		if len(fields) > 2 && fields[2] == "kB" {
+			v *= 1024
+		}
+		values[key] = v
 	}
 	if err := scanner.Err(); err != nil {
-		return Memory{}, Memory{}, err
+		return Memory{}, Memory{}, nil, err
 	}
 
 	total := values["MemTotal"]
@@ -79,11 +123,78 @@ func readMemory() (Memory, Memory, error) {
 	if swapTotal > swapFree {
 		swapUsed = swapTotal - swapFree
 	}
-	return Memory{Total: total, Used: used, Free: available}, Memory{Total: swapTotal, Used: swapUsed, Free: swapFree}, nil
+	detail := &MemoryDetail{
+		Available:      available,
+		Buffers:        values["Buffers"],
+		Cached:         values["Cached"],
+		Shared:         values["Shmem"],
+		Dirty:          values["Dirty"],
+		Slab:           values["Slab"],
+		HugePagesTotal: values["HugePages_Total"],
+		HugePagesFree:  values["HugePages_Free"],
+		HugePageSize:   values["Hugepagesize"],
+	}
+	return Memory{Total: total, Used: used, Free: available}, Memory{Total: swapTotal, Used: swapUsed, Free: swapFree}, detail, nil
+}
+
+// readPressure returns nil on kernels built without CONFIG_PSI.
+func (h hostPaths) readPressure() *Pressure {
+	pressure := &Pressure{
+		CPU:    readPressureFile(h.procPath("pressure", "cpu")),
+		Memory: readPressureFile(h.procPath("pressure", "memory")),
+		IO:     readPressureFile(h.procPath("pressure", "io")),
+	}
+	if pressure.CPU == nil && pressure.Memory == nil && pressure.IO == nil {
+		return nil
+	}
+	return pressure
+}
+
+func readPressureFile(path string) *PressureStat {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return parsePressure(data)
 }
 
-func readLoad() (Load, error) {
-	data, err := os.ReadFile("/proc/loadavg")
+func parsePressure(data []byte) *PressureStat {
+	var stat PressureStat
+	found := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		values := map[string]float64{}
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			v, err := strconv.ParseFloat(value, 64)
+			if err == nil {
+				values[key] = v
+			}
+		}
+		switch fields[0] {
+		case "some":
+			stat.SomeAvg10, stat.SomeAvg60, stat.SomeAvg300 = values["avg10"], values["avg60"], values["avg300"]
+			found = true
+		case "full":
+			stat.FullAvg10, stat.FullAvg60, stat.FullAvg300 = values["avg10"], values["avg60"], values["avg300"]
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+	return &stat
+}
+
+func (h hostPaths) readLoad() (Load, error) {
+	data, err := os.ReadFile(h.procPath("loadavg"))
 	if err != nil {
 		return Load{}, err
 	}
@@ -97,8 +208,8 @@ func readLoad() (Load, error) {
 	return Load{Load1: l1, Load5: l5, Load15: l15}, nil
 }
 
-func readUptime() (uint64, error) {
-	data, err := os.ReadFile("/proc/uptime")
+func (h hostPaths) readUptime() (uint64, error) {
+	data, err := os.ReadFile(h.procPath("uptime"))
 	if err != nil {
 		return 0, err
 	}
@@ -110,8 +221,8 @@ func readUptime() (uint64, error) {
 	return uint64(v), err
 }
 
-func readNetwork(exclude []string) (netCounters, error) {
-	data, err := os.ReadFile("/proc/net/dev")
+func (h hostPaths) readNetwork(exclude []string) (netCounters, error) {
+	data, err := os.ReadFile(h.procPath("net", "dev"))
 	if err != nil {
 		return netCounters{}, err
 	}
@@ -143,10 +254,22 @@ func readNetwork(exclude []string) (netCounters, error) {
 	return total, scanner.Err()
 }
 
-func readDisks(mounts []string, excludeFS []string) ([]Disk, error) {
-	mountFS := linuxMountTypes()
+// readDisks reports mount points as the host sees them. With the host root
+// mounted into a container, the mount table comes from the host's init
+// process and each mount point is statted below HOST_ROOT.
+func (h hostPaths) readDisks(mounts []string, excludeFS []string) ([]Disk, error) {
+	mountsFile := h.procPath("mounts")
+	if h.root != "/" {
+		mountsFile = h.procPath("1", "mounts")
+	}
+	data, _ := os.ReadFile(mountsFile)
+	entries := parseProcMounts(data)
+	byMount := make(map[string]linuxMount, len(entries))
+	for _, entry := range entries {
+		byMount[entry.mount] = entry
+	}
 	if autoMounts(mounts) {
-		mounts = linuxAutoMounts(mountFS, excludeFS)
+		mounts = linuxAutoMounts(entries, excludeFS)
 	}
 	disks := make([]Disk, 0, len(mounts))
 	seen := map[string]bool{}
@@ -155,50 +278,66 @@ func readDisks(mounts []string, excludeFS []string) ([]Disk, error) {
 			continue
 		}
 		seen[mount] = true
-		fsType := mountFS[mount]
-		if matchAny(fsType, excludeFS) {
+		entry := byMount[mount]
+		if matchAny(entry.fsType, excludeFS) {
 			continue
 		}
 		var stat syscall.Statfs_t
-		if err := syscall.Statfs(mount, &stat); err != nil {
+		if err := syscall.Statfs(h.rootPath(mount), &stat); err != nil {
 			continue
 		}
-		total := stat.Blocks * uint64(stat.Bsize)
-		free := stat.Bavail * uint64(stat.Bsize)
-		used := total - free
-		percent := 0.0
-		if total > 0 {
-			percent = round2(float64(used) / float64(total) * 100)
-		}
-		disks = append(disks, Disk{Mount: mount, FSType: fsType, Total: total, Used: used, Free: free, UsedPercent: percent})
+		disks = append(disks, linuxDiskFromStatfs(mount, entry, stat))
 	}
 	return disks, nil
 }
 
+func linuxDiskFromStatfs(mount string, entry linuxMount, stat syscall.Statfs_t) Disk {
+	total := stat.Blocks * uint64(stat.Bsize)
+	free := stat.Bavail * uint64(stat.Bsize)
+	used := total - free
+	disk := Disk{
+		Mount:    mount,
+		Device:   entry.device,
+		FSType:   entry.fsType,
+		Options:  entry.options,
+		ReadOnly: entry.readOnly() || stat.Flags&linuxStatfsReadOnly != 0,
+		Total:    total,
+		Used:     used,
+		Free:     free,
+	}
+	if total > 0 {
+		disk.UsedPercent = round2(float64(used) / float64(total) * 100)
+	}
+	// Filesystems without a fixed inode table (btrfs, zfs) report zero here.
+	if stat.Files > 0 && stat.Files >= stat.Ffree {
+		disk.InodesTotal = stat.Files
+		disk.InodesUsed = stat.Files - stat.Ffree
+		disk.InodesUsedPercent = round2(float64(disk.InodesUsed) / float64(disk.InodesTotal) * 100)
+	}
+	return disk
+}
+
 func autoMounts(mounts []string) bool {
 	return len(mounts) == 0 || (len(mounts) == 1 && strings.EqualFold(mounts[0], "auto"))
 }
 
-func linuxAutoMounts(mountFS map[string]string, excludeFS []string) []string {
-	data, err := os.ReadFile("/proc/mounts")
-	if err != nil {
-		return []string{"/"}
-	}
+// linuxAutoMounts picks the real filesystems from /proc/mounts. A device
+// mounted more than once (bind mounts, btrfs subvolumes of the same volume)
+// is only reported at its first mount point so totals are not counted twice.
+func linuxAutoMounts(entries []linuxMount, excludeFS []string) []string {
 	out := []string{}
-	seen := map[string]bool{}
-	scanner := bufio.NewScanner(bytes.NewReader(data))
-	for scanner.Scan() {
-		fields := strings.Fields(scanner.Text())
-		if len(fields) < 3 {
+	seenMount := map[string]bool{}
+	seenDevice := map[string]bool{}
+	for _, entry := range entries {
+		if seenMount[entry.mount] || matchAny(entry.fsType, excludeFS) || !linuxLikelyRealDisk(entry.device, entry.fsType, entry.mount) {
 			continue
 		}
-		device, mount, fsType := fields[0], fields[1], fields[2]
-		if seen[mount] || matchAny(fsType, excludeFS) || !linuxLikelyRealDisk(device, fsType, mount) {
+		if strings.HasPrefix(entry.device, "/dev/") && seenDevice[entry.device] {
 			continue
 		}
-		seen[mount] = true
-		mountFS[mount] = fsType
-		out = append(out, mount)
+		seenMount[entry.mount] = true
+		seenDevice[entry.device] = true
+		out = append(out, entry.mount)
 	}
 	if len(out) == 0 {
 		return []string{"/"}
@@ -221,34 +360,93 @@ func linuxLikelyRealDisk(device, fsType, mount string) bool {
 	}
 }
 
-func readConnections() (Connections, error) {
-	tcp := countProcNet("/proc/net/tcp") + countProcNet("/proc/net/tcp6")
-	udp := countProcNet("/proc/net/udp") + countProcNet("/proc/net/udp6")
-	return Connections{TCP: tcp, UDP: udp}, nil
-}
-
-func readDiskCounters() (diskCounters, error) {
-	data, err := os.ReadFile("/proc/diskstats")
+func (h hostPaths) readDiskCounters() (diskCounters, error) {
+	data, err := os.ReadFile(h.procPath("diskstats"))
 	if err != nil {
 		return diskCounters{}, err
 	}
+	return parseDiskStats(data, h.linuxBlockDevices())
+}
+
+// parseDiskStats keeps whole-disk devices only. Stacked devices (md, dm) are
+// reported per device but left out of the totals so that I/O is not counted
+// once for the array and again for its member disks.
+func parseDiskStats(data []byte, blockDevices map[string]bool) (diskCounters, error) {
 	var total diskCounters
 	scanner := bufio.NewScanner(bytes.NewReader(data))
 	for scanner.Scan() {
 		fields := strings.Fields(scanner.Text())
-		if len(fields) < 14 || !linuxLikelyBlockDevice(fields[2]) {
+		if len(fields) < 14 {
+			continue
+		}
+		name := fields[2]
+		stacked, ok := blockDevices[name]
+		if blockDevices == nil {
+			stacked, ok = linuxStackedBlockDevice(name), linuxLikelyBlockDevice(name)
+		}
+		if !ok {
 			continue
 		}
-		sectorsRead, _ := strconv.ParseUint(fields[5], 10, 64)
-		sectorsWritten, _ := strconv.ParseUint(fields[9], 10, 64)
-		total.read += sectorsRead * 512
-		total.write += sectorsWritten * 512
+		var values [11]uint64
+		for i := range values {
+			values[i], _ = strconv.ParseUint(fields[i+3], 10, 64)
+		}
+		dev := diskDeviceCounters{
+			name:          name,
+			reads:         values[0],
+			readBytes:     values[2] * 512,
+			readTicks:     values[3],
+			writes:        values[4],
+			writeBytes:    values[6] * 512,
+			writeTicks:    values[7],
+			ioTicks:       values[9],
+			weightedTicks: values[10],
+		}
+		total.devices = append(total.devices, dev)
+		if !stacked {
+			total.read += dev.readBytes
+			total.write += dev.writeBytes
+		}
 	}
 	return total, scanner.Err()
 }
 
-func readProcessCount() int {
-	entries, err := os.ReadDir("/proc")
+// linuxBlockDevices lists whole-disk devices from /sys/block, which never
+// contains partitions. The value reports whether the device is stacked on
+// other disks (it has entries under slaves/). It returns nil when /sys/block
+// is unavailable so callers fall back to name matching.
+func (h hostPaths) linuxBlockDevices() map[string]bool {
+	entries, err := os.ReadDir(h.sysPath("block"))
+	if err != nil {
+		return nil
+	}
+	out := map[string]bool{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if linuxVirtualBlockDevice(name) {
+			continue
+		}
+		slaves, _ := os.ReadDir(h.sysPath("block", name, "slaves"))
+		out[name] = len(slaves) > 0
+	}
+	return out
+}
+
+func linuxVirtualBlockDevice(name string) bool {
+	for _, prefix := range []string{"loop", "ram", "zram", "sr", "fd", "nbd"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func linuxStackedBlockDevice(name string) bool {
+	return strings.HasPrefix(name, "md") || strings.HasPrefix(name, "dm-")
+}
+
+func (h hostPaths) readProcessCount() int {
+	entries, err := os.ReadDir(h.procPath())
 	if err != nil {
 		return 0
 	}
@@ -261,14 +459,19 @@ func readProcessCount() int {
 	return count
 }
 
-func readHostInfo() HostStaticInfo {
-	info := HostStaticInfo{Kernel: readTrimmed("/proc/sys/kernel/osrelease"), OSName: readOSName(), Virtualization: readVirtualization()}
-	info.CPUModel, info.PhysicalCores = readCPUDetails()
+func (h hostPaths) readHostInfo() HostStaticInfo {
+	info := HostStaticInfo{Kernel: readTrimmed(h.procPath("sys", "kernel", "osrelease")), OSName: h.readOSName(), Virtualization: h.readVirtualization()}
+	info.CPUModel, info.PhysicalCores = h.readCPUDetails()
+	info.Namespaces = h.readNamespaces()
+	info.Hostname = h.readHostname(info.Namespaces)
 	return info
 }
 
-func readOSName() string {
-	data, err := os.ReadFile("/etc/os-release")
+func (h hostPaths) readOSName() string {
+	data, err := os.ReadFile(h.rootPath("etc", "os-release"))
+	if err != nil {
+		data, err = os.ReadFile(h.rootPath("usr", "lib", "os-release"))
+	}
 	if err != nil {
 		return "Linux"
 	}
@@ -289,14 +492,15 @@ func readOSName() string {
 	return "Linux"
 }
 
-func readCPUDetails() (string, int) {
-	data, err := os.ReadFile("/proc/cpuinfo")
+func (h hostPaths) readCPUDetails() (string, int) {
+	data, err := os.ReadFile(h.procPath("cpuinfo"))
 	if err != nil {
 		return "", runtime.NumCPU()
 	}
 	model := ""
 	physicalIDs := map[string]bool{}
 	coreIDs := map[string]bool{}
+	processors := 0
 	scanner := bufio.NewScanner(bytes.NewReader(data))
 	currentPhysicalID := "0"
 	for scanner.Scan() {
@@ -307,6 +511,8 @@ func readCPUDetails() (string, int) {
 		key = strings.TrimSpace(key)
 		value = strings.TrimSpace(value)
 		switch key {
+		case "processor":
+			processors++
 		case "model name":
 			if model == "" {
 				model = value
@@ -322,14 +528,23 @@ func readCPUDetails() (string, int) {
 	if physicalCores == 0 {
 		physicalCores = len(physicalIDs)
 	}
+	if physicalCores == 0 {
+		// arm64 kernels list processors without core or package ids.
+		physicalCores = processors
+	}
 	if physicalCores == 0 {
 		physicalCores = runtime.NumCPU()
 	}
 	return model, physicalCores
 }
 
-func readVirtualization() string {
-	if product := strings.ToLower(readTrimmed("/sys/class/dmi/id/product_name")); product != "" {
+// readVirtualization matches the DMI product and vendor names. QEMU guests
+// report a generic product ("Standard PC (i440FX + PIIX, 1996)") and only name
+// QEMU as the vendor.
+func (h hostPaths) readVirtualization() string {
+	product := readTrimmed(h.sysPath("class", "dmi", "id", "product_name"))
+	vendor := readTrimmed(h.sysPath("class", "dmi", "id", "sys_vendor"))
+	if product := strings.ToLower(strings.TrimSpace(product + " " + vendor)); product != "" {
 		switch {
 		case strings.Contains(product, "kvm") || strings.Contains(product, "qemu"):
 			return "qemu"
@@ -341,7 +556,7 @@ func readVirtualization() string {
 			return "hyper-v"
 		}
 	}
-	if data, err := os.ReadFile("/proc/cpuinfo"); err == nil && bytes.Contains(bytes.ToLower(data), []byte("hypervisor")) {
+	if data, err := os.ReadFile(h.procPath("cpuinfo")); err == nil && bytes.Contains(bytes.ToLower(data), []byte("hypervisor")) {
 		return "virtualized"
 	}
 	return ""
@@ -355,8 +570,24 @@ func readTrimmed(path string) string {
 	return strings.TrimSpace(string(data))
 }
 
+// linuxLikelyBlockDevice matches whole-disk names and rejects their
+// partitions (sda1, nvme0n1p1, mmcblk0p1, md0p1).
 func linuxLikelyBlockDevice(name string) bool {
-	return strings.HasPrefix(name, "sd") || strings.HasPrefix(name, "vd") || strings.HasPrefix(name, "xvd") || strings.HasPrefix(name, "nvme")
+	for _, prefix := range []string{"sd", "vd", "xvd", "hd"} {
+		if rest, ok := strings.CutPrefix(name, prefix); ok {
+			return rest != "" && strings.Trim(rest, "abcdefghijklmnopqrstuvwxyz") == ""
+		}
+	}
+	if rest, ok := strings.CutPrefix(name, "nvme"); ok {
+		controller, namespace, ok := strings.Cut(rest, "n")
+		return ok && isDigits(controller) && isDigits(namespace)
+	}
+	for _, prefix := range []string{"mmcblk", "md", "dm-"} {
+		if rest, ok := strings.CutPrefix(name, prefix); ok {
+			return isDigits(rest)
+		}
+	}
+	return false
 }
 
 func isDigits(value string) bool {
@@ -368,37 +599,60 @@ func isDigits(value string) bool {
 	return value != ""
 }
 
-func linuxMountTypes() map[string]string {
-	data, err := os.ReadFile("/proc/mounts")
-	if err != nil {
-		return nil
+// linuxStatfsReadOnly is ST_RDONLY in statfs f_flags.
+const linuxStatfsReadOnly = 0x1
+
+type linuxMount struct {
+	device  string
+	mount   string
+	fsType  string
+	options string
+}
+
+func (m linuxMount) readOnly() bool {
+	for _, option := range strings.Split(m.options, ",") {
+		if option == "ro" {
+			return true
+		}
 	}
-	out := map[string]string{}
+	return false
+}
+
+// parseProcMounts reads /proc/mounts in order. Paths escape spaces, tabs,
+// newlines and backslashes as octal sequences.
+func parseProcMounts(data []byte) []linuxMount {
+	var out []linuxMount
 	scanner := bufio.NewScanner(bytes.NewReader(data))
 	for scanner.Scan() {
 		fields := strings.Fields(scanner.Text())
-		if len(fields) >= 3 {
-			out[fields[1]] = fields[2]
+		if len(fields) < 3 {
+			continue
+		}
+		entry := linuxMount{device: unescapeMountField(fields[0]), mount: unescapeMountField(fields[1]), fsType: fields[2]}
+		if len(fields) >= 4 {
+			entry.options = fields[3]
 		}
+		out = append(out, entry)
 	}
 	return out
 }
 
-func countProcNet(path string) int {
-	f, err := os.Open(path)
-	if err != nil {
-		return 0
-	}
-	defer f.Close()
-	scanner := bufio.NewScanner(f)
-	count := -1
-	for scanner.Scan() {
-		count++
-	}
-	if count < 0 {
-		return 0
+func unescapeMountField(value string) string {
+	if !strings.Contains(value, "\\") {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+3 < len(value) {
+			if n, err := strconv.ParseUint(value[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(value[i])
 	}
-	return count
+	return b.String()
 }
 
 func matchAny(value string, patterns []string) bool {
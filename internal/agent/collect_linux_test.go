@@ -0,0 +1,332 @@
+//go:build linux
+
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"vps-agent/internal/config"
+)
+
+func TestLinuxLikelyBlockDeviceSkipsPartitions(t *testing.T) {
+	for name, want := range map[string]bool{
+		"sda":       true,
+		"sda1":      false,
+		"vdb":       true,
+		"xvda":      true,
+		"xvda1":     false,
+		"nvme0n1":   true,
+		"nvme0n1p2": false,
+		"mmcblk0":   true,
+		"mmcblk0p1": false,
+		"md0":       true,
+		"md0p1":     false,
+		"dm-0":      true,
+		"loop0":     false,
+		"sr0":       false,
+	} {
+		if got := linuxLikelyBlockDevice(name); got != want {
+			t.Fatalf("linuxLikelyBlockDevice(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestParseDiskStatsSkipsStackedDevicesInTotals(t *testing.T) {
+	data := []byte(`   8       0 sda 100 0 2000 50 200 0 4000 150 0 180 200 0 0 0 0
+   8       1 sda1 90 0 1800 40 190 0 3800 140 0 170 180 0 0 0 0
+   8      16 sdb 10 0 200 5 20 0 400 15 0 18 20 0 0 0 0
+   9       0 md0 110 0 2200 60 220 0 4400 170 0 200 230 0 0 0 0
+ 253       0 dm-0 5 0 100 1 5 0 100 1 0 2 2 0 0 0 0
+`)
+	counters, err := parseDiskStats(data, map[string]bool{"sda": false, "sdb": false, "md0": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counters.read != 2200*512 || counters.write != 4400*512 {
+		t.Fatalf("totals = read %d write %d", counters.read, counters.write)
+	}
+	if len(counters.devices) != 3 {
+		t.Fatalf("devices = %#v", counters.devices)
+	}
+	md := counters.devices[2]
+	if md.name != "md0" || md.reads != 110 || md.writes != 220 || md.ioTicks != 200 || md.weightedTicks != 230 {
+		t.Fatalf("md0 counters = %#v", md)
+	}
+
+	fallback, err := parseDiskStats(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fallback.devices) != 4 || fallback.read != 2200*512 {
+		t.Fatalf("fallback devices = %#v read = %d", fallback.devices, fallback.read)
+	}
+}
+
+func TestDiskDevicesSinceComputesRates(t *testing.T) {
+	prev := diskCounters{devices: []diskDeviceCounters{{name: "sda", reads: 100, writes: 100, readBytes: 1000, writeBytes: 1000, readTicks: 100, writeTicks: 100, ioTicks: 1000, weightedTicks: 1000}}}
+	now := diskCounters{devices: []diskDeviceCounters{
+		{name: "sda", reads: 120, writes: 140, readBytes: 3000, writeBytes: 5000, readTicks: 140, writeTicks: 220, ioTicks: 1500, weightedTicks: 3000},
+		{name: "sdb", reads: 1},
+	}}
+	devices := now.devicesSince(prev, 2)
+	if len(devices) != 1 {
+		t.Fatalf("devices = %#v", devices)
+	}
+	got := devices[0]
+	if got.ReadRate != 1000 || got.WriteRate != 2000 || got.ReadIOPS != 10 || got.WriteIOPS != 20 {
+		t.Fatalf("rates = %#v", got)
+	}
+	if got.AwaitMs != 2.67 || got.QueueDepth != 1 || got.UtilPercent != 25 {
+		t.Fatalf("latency = %#v", got)
+	}
+}
+
+func TestParseCPUStatReadsBreakdownAndCores(t *testing.T) {
+	data := []byte(`cpu  100 20 30 400 50 5 5 40 15 5
+cpu0 50 10 15 200 25 2 3 20 0 0
+cpu1 50 10 15 200 25 3 2 20 15 5
+intr 12345
+`)
+	times, err := parseCPUStat(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if times.user != 120 || times.system != 30 || times.idle != 450 || times.iowait != 50 || times.irq != 10 || times.steal != 40 || times.total != 650 {
+		t.Fatalf("cpu times = %#v", times)
+	}
+	if len(times.cores) != 2 || times.cores[1].total != 325 {
+		t.Fatalf("cores = %#v", times.cores)
+	}
+
+	next := cpuTimes{user: 170, system: 40, idle: 475, iowait: 60, irq: 15, steal: 65, total: 800, cores: []cpuTimes{{idle: 225, total: 400}, {idle: 250, total: 400}}}
+	cpu := next.breakdownSince(times)
+	if cpu.UsagePercent != 83.33 || cpu.UserPercent != 33.33 || cpu.StealPercent != 16.67 || cpu.IOWaitPercent != 6.67 {
+		t.Fatalf("breakdown = %#v", cpu)
+	}
+	if len(cpu.PerCoreUsage) != 2 || cpu.PerCoreUsage[0] != 100 || cpu.PerCoreUsage[1] != 66.67 {
+		t.Fatalf("per core = %#v", cpu.PerCoreUsage)
+	}
+}
+
+func TestParseCPUStatRejectsMissingAggregate(t *testing.T) {
+	if _, err := parseCPUStat([]byte("intr 1\n")); err == nil {
+		t.Fatal("expected invalid /proc/stat error")
+	}
+}
+
+func TestParseMemInfoReadsBreakdown(t *testing.T) {
+	data := []byte(`MemTotal:        2048 kB
+MemFree:          256 kB
+MemAvailable:    1024 kB
+Buffers:           64 kB
+Cached:           512 kB
+SwapTotal:       1024 kB
+SwapFree:         768 kB
+Dirty:             16 kB
+Shmem:             32 kB
+Slab:             128 kB
+HugePages_Total:      4
+HugePages_Free:       1
+Hugepagesize:    2048 kB
+`)
+	mem, swap, detail, err := parseMemInfo(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mem.Total != 2048*1024 || mem.Used != 1024*1024 || swap.Used != 256*1024 {
+		t.Fatalf("memory = %#v swap = %#v", mem, swap)
+	}
+	if detail.Buffers != 64*1024 || detail.Cached != 512*1024 || detail.Shared != 32*1024 || detail.Dirty != 16*1024 || detail.Slab != 128*1024 {
+		t.Fatalf("detail = %#v", detail)
+	}
+	if detail.HugePagesTotal != 4 || detail.HugePagesFree != 1 || detail.HugePageSize != 2048*1024 {
+		t.Fatalf("hugepages = %#v", detail)
+	}
+}
+
+func TestParsePressure(t *testing.T) {
+	stat := parsePressure([]byte("some avg10=1.50 avg60=0.75 avg300=0.10 total=12345\nfull avg10=0.50 avg60=0.25 avg300=0.00 total=6789\n"))
+	if stat == nil || stat.SomeAvg10 != 1.5 || stat.SomeAvg300 != 0.1 || stat.FullAvg60 != 0.25 {
+		t.Fatalf("pressure = %#v", stat)
+	}
+	if parsePressure([]byte("")) != nil {
+		t.Fatal("expected nil pressure for empty input")
+	}
+}
+
+func TestParseProcMountsAndAutoMounts(t *testing.T) {
+	data := []byte(`sysfs /sys sysfs rw,nosuid,nodev,noexec,relatime 0 0
+/dev/vda1 / ext4 rw,relatime,errors=remount-ro 0 0
+tmpfs /run tmpfs rw,nosuid,nodev,size=401428k,mode=755 0 0
+/dev/vdb1 /mnt/backup\040disk xfs ro,relatime 0 0
+/dev/vda1 /var/lib/bind-copy ext4 rw,relatime 0 0
+/dev/loop0 /snap/core/1 squashfs ro,nodev,relatime 0 0
+overlay /var/lib/docker/overlay2/abc/merged overlay rw,relatime 0 0
+tank/data /tank/data zfs rw,xattr,noacl 0 0
+`)
+	entries := parseProcMounts(data)
+	if len(entries) != 8 {
+		t.Fatalf("entries = %#v", entries)
+	}
+	backup := entries[3]
+	if backup.mount != "/mnt/backup disk" || backup.device != "/dev/vdb1" || !backup.readOnly() {
+		t.Fatalf("backup mount = %#v", backup)
+	}
+	if entries[1].readOnly() {
+		t.Fatal("errors=remount-ro must not count as read-only")
+	}
+
+	mounts := linuxAutoMounts(entries, []string{"tmpfs", "overlay", "squashfs", "sysfs"})
+	if got := strings.Join(mounts, ","); got != "/,/mnt/backup disk,/tank/data" {
+		t.Fatalf("auto mounts = %q", got)
+	}
+	if got := linuxAutoMounts(nil, nil); len(got) != 1 || got[0] != "/" {
+		t.Fatalf("fallback mounts = %#v", got)
+	}
+}
+
+func TestLinuxDiskFromStatfsReportsInodesAndReadOnly(t *testing.T) {
+	entry := linuxMount{device: "/dev/vda1", mount: "/", fsType: "ext4", options: "rw,relatime"}
+	disk := linuxDiskFromStatfs("/", entry, syscall.Statfs_t{Bsize: 4096, Blocks: 1000, Bavail: 250, Files: 200, Ffree: 20, Flags: linuxStatfsReadOnly})
+	if disk.Device != "/dev/vda1" || disk.Total != 4096000 || disk.UsedPercent != 75 {
+		t.Fatalf("disk = %#v", disk)
+	}
+	if disk.InodesTotal != 200 || disk.InodesUsed != 180 || disk.InodesUsedPercent != 90 {
+		t.Fatalf("inodes = %#v", disk)
+	}
+	if !disk.ReadOnly {
+		t.Fatal("expected statfs ST_RDONLY to mark the disk read-only")
+	}
+
+	btrfs := linuxDiskFromStatfs("/data", linuxMount{fsType: "btrfs", options: "rw"}, syscall.Statfs_t{Bsize: 4096, Blocks: 10})
+	if btrfs.InodesTotal != 0 || btrfs.InodesUsedPercent != 0 || btrfs.ReadOnly {
+		t.Fatalf("btrfs = %#v", btrfs)
+	}
+}
+
+var updateGolden = flag.Bool("update", false, "rewrite testdata golden files")
+
+// hostFixture is what the procfs and sysfs readers produce for a captured
+// host tree under testdata/hosts.
+type hostFixture struct {
+	Kernel         string
+	OSName         string
+	Virtualization string
+	CPUModel       string
+	PhysicalCores  int
+	CPUCores       int
+	CPUTotal       uint64
+	Memory         Memory
+	Swap           Memory
+	MemoryDetail   *MemoryDetail
+	Pressure       *Pressure
+	Load           Load
+	Uptime         uint64
+	NetworkRx      uint64
+	NetworkTx      uint64
+	DiskRead       uint64
+	DiskWrite      uint64
+	DiskDevices    []string
+}
+
+func readHostFixture(t *testing.T, dir string) hostFixture {
+	t.Helper()
+	host := hostPaths{proc: filepath.Join(dir, "proc"), sys: filepath.Join(dir, "sys"), root: dir}
+
+	info := host.readHostInfo()
+	got := hostFixture{
+		Kernel:         info.Kernel,
+		OSName:         info.OSName,
+		Virtualization: info.Virtualization,
+		CPUModel:       info.CPUModel,
+		PhysicalCores:  info.PhysicalCores,
+		Pressure:       host.readPressure(),
+	}
+	cpu, err := host.readCPUTimes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.CPUCores, got.CPUTotal = len(cpu.cores), cpu.total
+	if got.Memory, got.Swap, got.MemoryDetail, err = host.readMemory(); err != nil {
+		t.Fatal(err)
+	}
+	if got.Load, err = host.readLoad(); err != nil {
+		t.Fatal(err)
+	}
+	if got.Uptime, err = host.readUptime(); err != nil {
+		t.Fatal(err)
+	}
+	network, err := host.readNetwork(config.Default().NetworkExclude)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.NetworkRx, got.NetworkTx = network.rx, network.tx
+	disks, err := host.readDiskCounters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.DiskRead, got.DiskWrite = disks.read, disks.write
+	for _, dev := range disks.devices {
+		got.DiskDevices = append(got.DiskDevices, dev.name)
+	}
+	return got
+}
+
+// TestHostFixturesMatchGolden runs the readers against procfs and sysfs trees
+// captured from real machines. Run with -update after adding a host.
+func TestHostFixturesMatchGolden(t *testing.T) {
+	hosts, err := filepath.Glob(filepath.Join("testdata", "hosts", "*"))
+	if err != nil || len(hosts) == 0 {
+		t.Fatalf("no host fixtures: %v", err)
+	}
+	for _, dir := range hosts {
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			got, err := json.MarshalIndent(readHostFixture(t, dir), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+			golden := filepath.Join(dir, "golden.json")
+			if *updateGolden {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("%s mismatch:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestReadDisksUsesHostMountTable(t *testing.T) {
+	root := t.TempDir()
+	writeSysfsTree(t, root, map[string]string{
+		"proc/mounts":   "overlay / overlay rw,relatime 0 0\n",
+		"proc/1/mounts": "/dev/vda1 / ext4 rw,relatime 0 0\n/dev/vdb1 /data ext4 ro,relatime 0 0\n",
+		"data/.keep":    "",
+	})
+	host := hostPaths{proc: filepath.Join(root, "proc"), sys: filepath.Join(root, "sys"), root: root}
+
+	disks, err := host.readDisks([]string{"auto"}, config.Default().DiskExcludeFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(disks) != 2 || disks[0].Mount != "/" || disks[0].Device != "/dev/vda1" || disks[1].Mount != "/data" || !disks[1].ReadOnly {
+		t.Fatalf("disks = %#v", disks)
+	}
+	if disks[0].Total == 0 {
+		t.Fatalf("host root was not statted: %#v", disks[0])
+	}
+}
@@ -2,15 +2,34 @@
 
 package agent
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
-func readCPUTimes() (cpuTimes, error)                               { return cpuTimes{}, errors.New("unsupported OS") }
-func readMemory() (Memory, Memory, error)                           { return Memory{}, Memory{}, errors.New("unsupported OS") }
-func readLoad() (Load, error)                                       { return Load{}, nil }
-func readUptime() (uint64, error)                                   { return 0, nil }
-func readNetwork(exclude []string) (netCounters, error)             { return netCounters{}, nil }
-func readDisks(mounts []string, excludeFS []string) ([]Disk, error) { return nil, nil }
-func readConnections() (Connections, error)                         { return Connections{}, nil }
-func readDiskCounters() (diskCounters, error)                       { return diskCounters{}, nil }
-func readHostInfo() HostStaticInfo                                  { return HostStaticInfo{} }
-func readProcessCount() int                                         { return 0 }
+func (hostPaths) readCPUTimes() (cpuTimes, error) { return cpuTimes{}, errors.New("unsupported OS") }
+func (hostPaths) readMemory() (Memory, Memory, *MemoryDetail, error) {
+	return Memory{}, Memory{}, nil, errors.New("unsupported OS")
+}
+func (hostPaths) readPressure() *Pressure                                       { return nil }
+func (hostPaths) readLoad() (Load, error)                                       { return Load{}, nil }
+func (hostPaths) readUptime() (uint64, error)                                   { return 0, nil }
+func (hostPaths) readNetwork(exclude []string) (netCounters, error)             { return netCounters{}, nil }
+func (hostPaths) readDisks(mounts []string, excludeFS []string) ([]Disk, error) { return nil, nil }
+func (hostPaths) readConnections(topPeers int, hostNetwork bool) (Connections, error) {
+	return Connections{}, nil
+}
+func (hostPaths) readDiskCounters() (diskCounters, error)     { return diskCounters{}, nil }
+func (hostPaths) readHostInfo() HostStaticInfo                { return HostStaticInfo{} }
+func (hostPaths) readProcessCount() int                       { return 0 }
+func (hostPaths) readProcesses() ([]processSample, error)     { return nil, nil }
+func (hostPaths) readProcessDetails(pid int) (string, string) { return "", "" }
+func (hostPaths) readContainers(patterns []string, cache *containerLabels) ([]containerSample, error) {
+	return nil, nil
+}
+func readServices(units []string) ([]Service, error)         { return nil, nil }
+func (hostPaths) readSensors() []Sensor                      { return nil }
+func (hostPaths) readDiskHealth() ([]RAIDArray, []SMARTDisk) { return nil, nil }
+func pingHost(host string, count int, timeout time.Duration) ([]time.Duration, error) {
+	return nil, errors.New("icmp probes are not supported on this platform")
+}
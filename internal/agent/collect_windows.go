@@ -3,10 +3,13 @@
 package agent
 
 import (
+	"encoding/json"
+	"errors"
 	"os/exec"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 	"unsafe"
 )
 
@@ -65,7 +68,7 @@ type mibIfRow struct {
 	Descr           [256]byte
 }
 
-func readCPUTimes() (cpuTimes, error) {
+func (hostPaths) readCPUTimes() (cpuTimes, error) {
 	var idle, kernel, user filetime
 	r1, _, err := getSystemTimes.Call(uintptr(unsafe.Pointer(&idle)), uintptr(unsafe.Pointer(&kernel)), uintptr(unsafe.Pointer(&user)))
 	if r1 == 0 {
@@ -74,15 +77,19 @@ func readCPUTimes() (cpuTimes, error) {
 	idleTicks := filetimeToUint64(idle)
 	kernelTicks := filetimeToUint64(kernel)
 	userTicks := filetimeToUint64(user)
-	return cpuTimes{idle: idleTicks, total: kernelTicks + userTicks}, nil
+	system := uint64(0)
+	if kernelTicks > idleTicks {
+		system = kernelTicks - idleTicks
+	}
+	return cpuTimes{user: userTicks, system: system, idle: idleTicks, total: kernelTicks + userTicks}, nil
 }
 
-func readMemory() (Memory, Memory, error) {
+func (hostPaths) readMemory() (Memory, Memory, *MemoryDetail, error) {
 	var stat memoryStatusEx
 	stat.Length = uint32(unsafe.Sizeof(stat))
 	r1, _, err := globalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&stat)))
 	if r1 == 0 {
-		return Memory{}, Memory{}, err
+		return Memory{}, Memory{}, nil, err
 	}
 	memUsed := stat.TotalPhys - stat.AvailPhys
 	swapTotal := uint64(0)
@@ -97,19 +104,23 @@ func readMemory() (Memory, Memory, error) {
 	if swapTotal > swapFree {
 		swapUsed = swapTotal - swapFree
 	}
-	return Memory{Total: stat.TotalPhys, Used: memUsed, Free: stat.AvailPhys}, Memory{Total: swapTotal, Used: swapUsed, Free: swapFree}, nil
+	return Memory{Total: stat.TotalPhys, Used: memUsed, Free: stat.AvailPhys}, Memory{Total: swapTotal, Used: swapUsed, Free: swapFree}, &MemoryDetail{Available: stat.AvailPhys}, nil
+}
+
+func (hostPaths) readPressure() *Pressure {
+	return nil
 }
 
-func readLoad() (Load, error) {
+func (hostPaths) readLoad() (Load, error) {
 	return Load{}, nil
 }
 
-func readUptime() (uint64, error) {
+func (hostPaths) readUptime() (uint64, error) {
 	r1, _, _ := getTickCount64.Call()
 	return uint64(r1) / 1000, nil
 }
 
-func readNetwork(exclude []string) (netCounters, error) {
+func (hostPaths) readNetwork(exclude []string) (netCounters, error) {
 	var size uint32
 	getIfTable.Call(0, uintptr(unsafe.Pointer(&size)), 0)
 	if size == 0 {
@@ -134,7 +145,7 @@ func readNetwork(exclude []string) (netCounters, error) {
 	return total, nil
 }
 
-func readDisks(mounts []string, excludeFS []string) ([]Disk, error) {
+func (hostPaths) readDisks(mounts []string, excludeFS []string) ([]Disk, error) {
 	if autoMounts(mounts) {
 		mounts = windowsAutoMounts()
 	}
@@ -179,22 +190,102 @@ func windowsAutoMounts() []string {
 	return mounts
 }
 
-func readConnections() (Connections, error) {
+func (hostPaths) readConnections(topPeers int, hostNetwork bool) (Connections, error) {
 	return Connections{TCP: windowsTCPCount(), UDP: windowsUDPCount()}, nil
 }
 
-func readDiskCounters() (diskCounters, error) {
+func (hostPaths) readDiskCounters() (diskCounters, error) {
 	return diskCounters{}, nil
 }
 
-func readHostInfo() HostStaticInfo {
+func (hostPaths) readHostInfo() HostStaticInfo {
 	return HostStaticInfo{OSName: "Windows"}
 }
 
-func readProcessCount() int {
+func (hostPaths) readProcessCount() int {
 	return powershellCount("Get-Process | Measure-Object | Select-Object -ExpandProperty Count")
 }
 
+const windowsProcessScript = `$now = Get-Date
+$items = Get-Process -IncludeUserName -ErrorAction SilentlyContinue | ForEach-Object {
+  $started = $null
+  try { $started = $_.StartTime } catch {}
+  $cpu = 0
+  try { $cpu = $_.TotalProcessorTime.TotalSeconds } catch {}
+  [pscustomobject]@{
+    Id = $_.Id
+    Name = $_.ProcessName
+    User = $_.UserName
+    Path = $_.Path
+    Threads = $_.Threads.Count
+    WorkingSet = $_.WorkingSet64
+    CPU = [double]$cpu
+    Age = $(if ($started) { ($now - $started).TotalSeconds } else { 0 })
+    Start = $(if ($started) { $started.ToFileTimeUtc() } else { 0 })
+  }
+}
+ConvertTo-Json -Compress -InputObject @($items)`
+
+func (hostPaths) readProcesses() ([]processSample, error) {
+	out, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", windowsProcessScript).Output()
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		ID         int
+		Name       string
+		User       string
+		Path       string
+		Threads    int
+		WorkingSet uint64
+		CPU        float64
+		Age        float64
+		Start      uint64
+	}
+	if err := json.Unmarshal(out, &rows); err != nil {
+		return nil, err
+	}
+	samples := make([]processSample, 0, len(rows))
+	for _, row := range rows {
+		samples = append(samples, processSample{
+			pid:        row.ID,
+			start:      row.Start,
+			name:       row.Name,
+			user:       row.User,
+			command:    row.Path,
+			threads:    row.Threads,
+			rss:        row.WorkingSet,
+			cpuSeconds: row.CPU,
+			ageSeconds: row.Age,
+		})
+	}
+	return samples, nil
+}
+
+func (hostPaths) readProcessDetails(pid int) (string, string) {
+	return "", ""
+}
+
+func (hostPaths) readContainers(patterns []string, cache *containerLabels) ([]containerSample, error) {
+	return nil, nil
+}
+
+func readServices(units []string) ([]Service, error) {
+	return nil, nil
+}
+
+func (hostPaths) readSensors() []Sensor {
+	return nil
+}
+
+func (hostPaths) readDiskHealth() ([]RAIDArray, []SMARTDisk) {
+	return nil, nil
+}
+
+func pingHost(host string, count int, timeout time.Duration) ([]time.Duration, error) {
+	return nil, errors.New("icmp probes are not supported on Windows")
+}
+
 func filetimeToUint64(ft filetime) uint64 {
 	return uint64(ft.HighDateTime)<<32 | uint64(ft.LowDateTime)
 }
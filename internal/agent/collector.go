@@ -4,25 +4,45 @@ import (
 	"context"
 	"os"
 	"runtime"
+	"slices"
 	"sync"
 	"time"
 
+	"vps-agent/internal/buildinfo"
 	"vps-agent/internal/config"
 )
 
 type Collector struct {
-	cfg config.Config
+	cfg  config.Config
+	host hostPaths
 
-	mu         sync.Mutex
-	lastCPU    cpuTimes
-	lastNet    netCounters
-	lastDiskIO diskCounters
-	lastTime   time.Time
+	mu              sync.Mutex
+	lastCPU         cpuTimes
+	lastNet         netCounters
+	lastDiskIO      diskCounters
+	lastTime        time.Time
+	lastProcs       map[processKey]float64
+	lastCgroup      map[string]containerSample
+	containerLabels containerLabels
 
 	disks                []Disk
 	conns                *Connections
+	procs                *TopProcesses
+	containers           []Container
+	services             []Service
+	sensors              []Sensor
+	raid                 []RAIDArray
+	smart                []SMARTDisk
 	lastDisk             time.Time
 	lastConn             time.Time
+	lastProc             time.Time
+	lastContainer        time.Time
+	lastService          time.Time
+	lastSensor           time.Time
+	lastHealth           time.Time
+	lastProbe            time.Time
+	probes               prober
+	remoteProbes         []config.Probe
 	staticHost           string
 	staticCores          int
 	staticPhysicalCores  int
@@ -32,13 +52,19 @@ type Collector struct {
 	staticKernel         string
 	staticVirtualization string
 	staticCPUModel       string
+	namespaces           HostNamespaces
 }
 
 func NewCollector(cfg config.Config) *Collector {
-	host, _ := os.Hostname()
-	hostInfo := readHostInfo()
+	paths := newHostPaths(cfg)
+	hostInfo := paths.readHostInfo()
+	host := hostInfo.Hostname
+	if host == "" {
+		host, _ = os.Hostname()
+	}
 	return &Collector{
 		cfg:                  cfg,
+		host:                 paths,
 		staticHost:           host,
 		staticCores:          runtime.NumCPU(),
 		staticPhysicalCores:  hostInfo.PhysicalCores,
@@ -48,7 +74,37 @@ func NewCollector(cfg config.Config) *Collector {
 		staticKernel:         hostInfo.Kernel,
 		staticVirtualization: hostInfo.Virtualization,
 		staticCPUModel:       hostInfo.CPUModel,
+		namespaces:           hostInfo.Namespaces,
+	}
+}
+
+// Namespaces reports which host namespaces the agent shares, detected once
+// at startup.
+func (c *Collector) Namespaces() HostNamespaces {
+	return c.namespaces
+}
+
+// SetConfig swaps in settings pushed from the server. Every cached section
+// is refreshed on the next collect so new filters and intervals show up at
+// once; rates restart only when the set of counted interfaces changes.
+func (c *Collector) SetConfig(cfg config.Config) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !slices.Equal(cfg.NetworkExclude, c.cfg.NetworkExclude) {
+		c.lastTime = time.Time{}
 	}
+	c.cfg = cfg
+	c.lastDisk, c.lastConn, c.lastProc, c.lastService = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+	c.lastSensor, c.lastHealth, c.lastProbe, c.lastContainer = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+	c.procs, c.services, c.raid, c.smart, c.containers = nil, nil, nil, nil, nil
+}
+
+// Config is the configuration currently in effect, including settings
+// pushed from the server.
+func (c *Collector) Config() config.Config {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cfg
 }
 
 func (c *Collector) Collect(ctx context.Context) (Metrics, error) {
@@ -56,40 +112,90 @@ func (c *Collector) Collect(ctx context.Context) (Metrics, error) {
 	defer c.mu.Unlock()
 
 	now := time.Now()
-	cpuNow, err := readCPUTimes()
+	cpuNow, err := c.host.readCPUTimes()
 	if err != nil {
 		return Metrics{}, err
 	}
-	mem, swap, err := readMemory()
+	mem, swap, memDetail, err := c.host.readMemory()
 	if err != nil {
 		return Metrics{}, err
 	}
-	load, _ := readLoad()
-	uptime, _ := readUptime()
-	netNow, _ := readNetwork(c.cfg.NetworkExclude)
-	diskIONow, _ := readDiskCounters()
+	load, _ := c.host.readLoad()
+	uptime, _ := c.host.readUptime()
+	netNow, _ := c.host.readNetwork(c.cfg.NetworkExclude)
+	diskIONow, _ := c.host.readDiskCounters()
 
 	if c.lastDisk.IsZero() || now.Sub(c.lastDisk) >= c.cfg.DiskInterval {
-		if disks, err := readDisks(c.cfg.Mounts, c.cfg.DiskExcludeFS); err == nil {
+		if disks, err := c.host.readDisks(c.cfg.Mounts, c.cfg.DiskExcludeFS); err == nil {
 			c.disks = disks
 			c.lastDisk = now
 		}
 	}
 	if c.lastConn.IsZero() || now.Sub(c.lastConn) >= c.cfg.ConnectionInterval {
-		if conns, err := readConnections(); err == nil {
+		if conns, err := c.host.readConnections(c.cfg.ConnectionTopPeers, c.namespaces.Network); err == nil {
 			c.conns = &conns
 			c.lastConn = now
 		}
 	}
+	if c.cfg.ProcessTop > 0 && (c.lastProc.IsZero() || now.Sub(c.lastProc) >= c.cfg.ProcessInterval) {
+		if samples, err := c.host.readProcesses(); err == nil {
+			elapsed := 0.0
+			if !c.lastProc.IsZero() {
+				elapsed = now.Sub(c.lastProc).Seconds()
+			}
+			c.procs, c.lastProcs = topProcesses(samples, c.lastProcs, elapsed, c.cfg.ProcessTop)
+			c.procs.fillProcessDetails(c.host)
+			c.lastProc = now
+		}
+	}
+
+	if len(c.cfg.Services) > 0 && (c.lastService.IsZero() || now.Sub(c.lastService) >= c.cfg.ServiceInterval) {
+		if services, err := readServices(c.cfg.Services); err == nil {
+			c.services = services
+			c.lastService = now
+		}
+	}
 
-	cpuUsage := 0.0
+	if c.lastSensor.IsZero() || now.Sub(c.lastSensor) >= c.cfg.SensorInterval {
+		c.sensors = c.host.readSensors()
+		c.lastSensor = now
+	}
+
+	if c.cfg.DiskHealth && (c.lastHealth.IsZero() || now.Sub(c.lastHealth) >= c.cfg.DiskHealthInterval) {
+		c.raid, c.smart = c.host.readDiskHealth()
+		c.lastHealth = now
+	}
+
+	var probeResults []ProbeResult
+	if probes := c.effectiveProbes(); len(probes) > 0 {
+		if c.lastProbe.IsZero() || now.Sub(c.lastProbe) >= c.cfg.ProbeInterval {
+			if c.probes.start(probes) {
+				c.lastProbe = now
+			}
+		}
+		probeResults = c.probes.latest()
+	}
+
+	if len(c.cfg.Containers) > 0 && (c.lastContainer.IsZero() || now.Sub(c.lastContainer) >= c.cfg.ContainerInterval) {
+		if samples, err := c.host.readContainers(c.cfg.Containers, &c.containerLabels); err == nil {
+			elapsed := 0.0
+			if !c.lastContainer.IsZero() {
+				elapsed = now.Sub(c.lastContainer).Seconds()
+			}
+			c.containers, c.lastCgroup = containersSince(samples, c.lastCgroup, elapsed)
+			c.lastContainer = now
+		}
+	}
+
+	cpu := CPU{}
+	var diskDevices []DiskDevice
 	rxRate := uint64(0)
 	txRate := uint64(0)
 	diskReadRate := uint64(0)
 	diskWriteRate := uint64(0)
 	if !c.lastTime.IsZero() {
 		elapsed := now.Sub(c.lastTime).Seconds()
-		cpuUsage = cpuNow.usageSince(c.lastCPU)
+		cpu = cpuNow.breakdownSince(c.lastCPU)
 		if elapsed > 0 {
 			if netNow.rx >= c.lastNet.rx {
 				rxRate = uint64(float64(netNow.rx-c.lastNet.rx) / elapsed)
@@ -103,9 +209,14 @@ func (c *Collector) Collect(ctx context.Context) (Metrics, error) {
 			if diskIONow.write >= c.lastDiskIO.write {
 				diskWriteRate = uint64(float64(diskIONow.write-c.lastDiskIO.write) / elapsed)
 			}
+			diskDevices = diskIONow.devicesSince(c.lastDiskIO, elapsed)
 		}
 	}
 
+	cpu.Cores = c.staticCores
+	cpu.PhysicalCores = c.staticPhysicalCores
+	cpu.ModelName = c.staticCPUModel
+
 	c.lastCPU = cpuNow
 	c.lastNet = netNow
 	c.lastDiskIO = diskIONow
@@ -120,22 +231,33 @@ func (c *Collector) Collect(ctx context.Context) (Metrics, error) {
 	return Metrics{
 		NodeID:         c.cfg.NodeID,
 		Timestamp:      now.Unix(),
+		AgentVersion:   buildinfo.Version,
 		OS:             c.staticOS,
 		Arch:           c.staticArch,
 		Hostname:       c.staticHost,
 		Kernel:         c.staticKernel,
 		OSName:         c.staticOSName,
 		Virtualization: c.staticVirtualization,
-		CPU:            CPU{UsagePercent: round2(cpuUsage), Cores: c.staticCores, PhysicalCores: c.staticPhysicalCores, ModelName: c.staticCPUModel},
+		CPU:            cpu,
 		Memory:         mem,
 		Swap:           swap,
+		MemoryDetail:   memDetail,
+		Pressure:       c.host.readPressure(),
 		Load:           load,
 		Uptime:         uptime,
 		Disks:          c.disks,
 		Network:        Network{RxBytes: netNow.rx, TxBytes: netNow.tx, RxRate: rxRate, TxRate: txRate},
 		DiskIO:         DiskIO{ReadRate: diskReadRate, WriteRate: diskWriteRate},
+		DiskDevices:    diskDevices,
 		Conns:          c.conns,
-		Processes:      readProcessCount(),
+		Processes:      c.host.readProcessCount(),
+		TopProcesses:   c.procs,
+		Containers:     c.containers,
+		Services:       c.services,
+		Sensors:        c.sensors,
+		RAID:           c.raid,
+		SMART:          c.smart,
+		Probes:         probeResults,
 	}, nil
 }
 
@@ -143,9 +265,17 @@ func round2(v float64) float64 {
 	return float64(int(v*100+0.5)) / 100
 }
 
+// cpuTimes holds cumulative CPU ticks. idle includes iowait so that usage
+// reflects time the CPU was not busy; irq covers hard and soft interrupts.
 type cpuTimes struct {
-	idle  uint64
-	total uint64
+	user   uint64
+	system uint64
+	idle   uint64
+	iowait uint64
+	irq    uint64
+	steal  uint64
+	total  uint64
+	cores  []cpuTimes
 }
 
 func (c cpuTimes) usageSince(prev cpuTimes) float64 {
@@ -160,20 +290,117 @@ func (c cpuTimes) usageSince(prev cpuTimes) float64 {
 	return (1 - float64(idle)/float64(total)) * 100
 }
 
+func (c cpuTimes) breakdownSince(prev cpuTimes) CPU {
+	out := CPU{UsagePercent: round2(c.usageSince(prev))}
+	if c.total > prev.total {
+		total := float64(c.total - prev.total)
+		share := func(now, last uint64) float64 {
+			return round2(float64(counterDelta(now, last)) / total * 100)
+		}
+		out.UserPercent = share(c.user, prev.user)
+		out.SystemPercent = share(c.system, prev.system)
+		out.IOWaitPercent = share(c.iowait, prev.iowait)
+		out.StealPercent = share(c.steal, prev.steal)
+		out.IRQPercent = share(c.irq, prev.irq)
+	}
+	if len(c.cores) > 0 && len(c.cores) == len(prev.cores) {
+		out.PerCoreUsage = make([]float64, len(c.cores))
+		for i, core := range c.cores {
+			out.PerCoreUsage[i] = round2(core.usageSince(prev.cores[i]))
+		}
+	}
+	return out
+}
+
 type netCounters struct {
 	rx uint64
 	tx uint64
 }
 
 type diskCounters struct {
-	read  uint64
-	write uint64
+	read    uint64
+	write   uint64
+	devices []diskDeviceCounters
+}
+
+// diskDeviceCounters holds the cumulative /proc/diskstats columns for one
+// block device. Tick values are milliseconds.
+type diskDeviceCounters struct {
+	name          string
+	reads         uint64
+	writes        uint64
+	readBytes     uint64
+	writeBytes    uint64
+	readTicks     uint64
+	writeTicks    uint64
+	ioTicks       uint64
+	weightedTicks uint64
+}
+
+func (c diskCounters) devicesSince(prev diskCounters, elapsed float64) []DiskDevice {
+	if len(c.devices) == 0 || elapsed <= 0 {
+		return nil
+	}
+	last := make(map[string]diskDeviceCounters, len(prev.devices))
+	for _, dev := range prev.devices {
+		last[dev.name] = dev
+	}
+	out := make([]DiskDevice, 0, len(c.devices))
+	for _, dev := range c.devices {
+		p, ok := last[dev.name]
+		if !ok {
+			continue
+		}
+		reads := counterDelta(dev.reads, p.reads)
+		writes := counterDelta(dev.writes, p.writes)
+		await := 0.0
+		if ios := reads + writes; ios > 0 {
+			await = float64(counterDelta(dev.readTicks, p.readTicks)+counterDelta(dev.writeTicks, p.writeTicks)) / float64(ios)
+		}
+		util := float64(counterDelta(dev.ioTicks, p.ioTicks)) / (elapsed * 1000) * 100
+		if util > 100 {
+			util = 100
+		}
+		out = append(out, DiskDevice{
+			Name:        dev.name,
+			ReadBytes:   dev.readBytes,
+			WriteBytes:  dev.writeBytes,
+			ReadRate:    uint64(float64(counterDelta(dev.readBytes, p.readBytes)) / elapsed),
+			WriteRate:   uint64(float64(counterDelta(dev.writeBytes, p.writeBytes)) / elapsed),
+			ReadIOPS:    round2(float64(reads) / elapsed),
+			WriteIOPS:   round2(float64(writes) / elapsed),
+			AwaitMs:     round2(await),
+			QueueDepth:  round2(float64(counterDelta(dev.weightedTicks, p.weightedTicks)) / (elapsed * 1000)),
+			UtilPercent: round2(util),
+		})
+	}
+	return out
+}
+
+func counterDelta(now, prev uint64) uint64 {
+	if now < prev {
+		return 0
+	}
+	return now - prev
 }
 
 type HostStaticInfo struct {
+	Hostname       string
 	Kernel         string
 	OSName         string
 	Virtualization string
 	CPUModel       string
 	PhysicalCores  int
+	Namespaces     HostNamespaces
+}
+
+// HostNamespaces describes an agent running in a container. PID, Network and
+// UTS are true when the agent shares that namespace with the host, as with
+// docker run --pid=host --network=host --uts=host or a DaemonSet using
+// hostPID and hostNetwork.
+type HostNamespaces struct {
+	Container bool
+	PID       bool
+	Network   bool
+	UTS       bool
 }
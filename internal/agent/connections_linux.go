@@ -0,0 +1,281 @@
+//go:build linux
+
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	tcpClose  = 0x07
+	tcpListen = 0x0a
+
+	sockDiagByFamily = 20
+	inetDiagReqLen   = 56
+	inetDiagMsgLen   = 72
+)
+
+var tcpStateNames = map[uint8]string{
+	0x01: "ESTABLISHED",
+	0x02: "SYN_SENT",
+	0x03: "SYN_RECV",
+	0x04: "FIN_WAIT1",
+	0x05: "FIN_WAIT2",
+	0x06: "TIME_WAIT",
+	0x07: "CLOSE",
+	0x08: "CLOSE_WAIT",
+	0x09: "LAST_ACK",
+	0x0a: "LISTEN",
+	0x0b: "CLOSING",
+	0x0c: "NEW_SYN_RECV",
+}
+
+type socketEntry struct {
+	proto      string
+	state      uint8
+	localIP    net.IP
+	localPort  int
+	remoteIP   net.IP
+	remotePort int
+}
+
+// readConnections asks the kernel over netlink, which only sees the agent's
+// own network namespace. An agent that does not share the host's network reads
+// the socket tables of the host's init process under HOST_PROC instead.
+func (h hostPaths) readConnections(topPeers int, hostNetwork bool) (Connections, error) {
+	if !hostNetwork {
+		return summarizeSockets(readSocketsProc(h.procPath("1", "net")), topPeers), nil
+	}
+	entries, err := readSocketsNetlink()
+	if err != nil {
+		entries = readSocketsProc(h.procPath("net"))
+	}
+	return summarizeSockets(entries, topPeers), nil
+}
+
+func summarizeSockets(entries []socketEntry, topPeers int) Connections {
+	out := Connections{TCPStates: map[string]int{}}
+	listening := map[ListenPort]bool{}
+	peers := map[string]int{}
+	for _, entry := range entries {
+		switch entry.proto {
+		case "tcp":
+			out.TCP++
+			name := tcpStateNames[entry.state]
+			if name == "" {
+				name = "UNKNOWN"
+			}
+			out.TCPStates[name]++
+			if entry.state == tcpListen {
+				listening[ListenPort{Proto: "tcp", Address: entry.localIP.String(), Port: entry.localPort}] = true
+				continue
+			}
+			if topPeers > 0 && entry.remoteIP != nil && !entry.remoteIP.IsUnspecified() && !entry.remoteIP.IsLoopback() {
+				peers[entry.remoteIP.String()]++
+			}
+		case "udp":
+			out.UDP++
+			if entry.state == tcpClose && entry.remotePort == 0 {
+				listening[ListenPort{Proto: "udp", Address: entry.localIP.String(), Port: entry.localPort}] = true
+			}
+		}
+	}
+	for port := range listening {
+		out.Listening = append(out.Listening, port)
+	}
+	sort.Slice(out.Listening, func(i, j int) bool {
+		a, b := out.Listening[i], out.Listening[j]
+		if a.Proto != b.Proto {
+			return a.Proto < b.Proto
+		}
+		if a.Port != b.Port {
+			return a.Port < b.Port
+		}
+		return a.Address < b.Address
+	})
+	for address, count := range peers {
+		out.TopPeers = append(out.TopPeers, PeerCount{Address: address, Count: count})
+	}
+	sort.Slice(out.TopPeers, func(i, j int) bool {
+		if out.TopPeers[i].Count != out.TopPeers[j].Count {
+			return out.TopPeers[i].Count > out.TopPeers[j].Count
+		}
+		return out.TopPeers[i].Address < out.TopPeers[j].Address
+	})
+	if len(out.TopPeers) > topPeers {
+		out.TopPeers = out.TopPeers[:topPeers]
+	}
+	return out
+}
+
+// readSocketsNetlink dumps TCP and UDP sockets through NETLINK_SOCK_DIAG,
+// which avoids formatting and parsing /proc/net text on hosts with many
+// connections.
+func readSocketsNetlink() ([]socketEntry, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, syscall.NETLINK_INET_DIAG)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.Close(fd)
+	tv := syscall.Timeval{Sec: 2}
+	_ = syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv)
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return nil, err
+	}
+	var out []socketEntry
+	seq := uint32(0)
+	for _, proto := range []struct {
+		name     string
+		protocol uint8
+	}{{"tcp", syscall.IPPROTO_TCP}, {"udp", syscall.IPPROTO_UDP}} {
+		for _, family := range []uint8{syscall.AF_INET, syscall.AF_INET6} {
+			seq++
+			entries, err := sockDiagDump(fd, seq, family, proto.protocol, proto.name)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, entries...)
+		}
+	}
+	return out, nil
+}
+
+func sockDiagDump(fd int, seq uint32, family, protocol uint8, name string) ([]socketEntry, error) {
+	req := make([]byte, syscall.NLMSG_HDRLEN+inetDiagReqLen)
+	binary.NativeEndian.PutUint32(req[0:4], uint32(len(req)))
+	binary.NativeEndian.PutUint16(req[4:6], sockDiagByFamily)
+	binary.NativeEndian.PutUint16(req[6:8], syscall.NLM_F_REQUEST|syscall.NLM_F_DUMP)
+	binary.NativeEndian.PutUint32(req[8:12], seq)
+	body := req[syscall.NLMSG_HDRLEN:]
+	body[0] = family
+	body[1] = protocol
+	binary.NativeEndian.PutUint32(body[4:8], 0xffffffff)
+	if err := syscall.Sendto(fd, req, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return nil, err
+	}
+
+	var out []socketEntry
+	buf := make([]byte, 64<<10)
+	for {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return nil, err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range msgs {
+			if msg.Header.Seq != seq {
+				continue
+			}
+			switch msg.Header.Type {
+			case syscall.NLMSG_DONE:
+				return out, nil
+			case syscall.NLMSG_ERROR:
+				return nil, errors.New("sock_diag request failed")
+			}
+			if entry, ok := parseInetDiagMsg(msg.Data, name); ok {
+				out = append(out, entry)
+			}
+		}
+	}
+}
+
+func parseInetDiagMsg(data []byte, proto string) (socketEntry, bool) {
+	if len(data) < inetDiagMsgLen {
+		return socketEntry{}, false
+	}
+	family := data[0]
+	entry := socketEntry{
+		proto:      proto,
+		state:      data[1],
+		localPort:  int(binary.BigEndian.Uint16(data[4:6])),
+		remotePort: int(binary.BigEndian.Uint16(data[6:8])),
+	}
+	if family == syscall.AF_INET {
+		entry.localIP = net.IP(append([]byte(nil), data[8:12]...))
+		entry.remoteIP = net.IP(append([]byte(nil), data[24:28]...))
+	} else {
+		entry.localIP = net.IP(append([]byte(nil), data[8:24]...))
+		entry.remoteIP = net.IP(append([]byte(nil), data[24:40]...))
+	}
+	return entry, true
+}
+
+func readSocketsProc(dir string) []socketEntry {
+	var out []socketEntry
+	for _, source := range []struct {
+		proto string
+		name  string
+	}{
+		{"tcp", "tcp"},
+		{"tcp", "tcp6"},
+		{"udp", "udp"},
+		{"udp", "udp6"},
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, source.name))
+		if err != nil {
+			continue
+		}
+		out = append(out, parseProcNetSockets(data, source.proto)...)
+	}
+	return out
+}
+
+// parseProcNetSockets reads /proc/net/{tcp,udp}[6]. Addresses are hex words
+// in host byte order followed by a hex port.
+func parseProcNetSockets(data []byte, proto string) []socketEntry {
+	var out []socketEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[0] == "sl" {
+			continue
+		}
+		localIP, localPort, ok := parseProcNetAddress(fields[1])
+		if !ok {
+			continue
+		}
+		remoteIP, remotePort, ok := parseProcNetAddress(fields[2])
+		if !ok {
+			continue
+		}
+		state, err := strconv.ParseUint(fields[3], 16, 8)
+		if err != nil {
+			continue
+		}
+		out = append(out, socketEntry{proto: proto, state: uint8(state), localIP: localIP, localPort: localPort, remoteIP: remoteIP, remotePort: remotePort})
+	}
+	return out
+}
+
+func parseProcNetAddress(value string) (net.IP, int, bool) {
+	hexIP, hexPort, ok := strings.Cut(value, ":")
+	if !ok {
+		return nil, 0, false
+	}
+	raw, err := hex.DecodeString(hexIP)
+	if err != nil || (len(raw) != 4 && len(raw) != 16) {
+		return nil, 0, false
+	}
+	for i := 0; i < len(raw); i += 4 {
+		binary.BigEndian.PutUint32(raw[i:i+4], binary.NativeEndian.Uint32(raw[i:i+4]))
+	}
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return nil, 0, false
+	}
+	return net.IP(raw), int(port), true
+}
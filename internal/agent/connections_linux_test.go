@@ -0,0 +1,74 @@
+//go:build linux
+
+package agent
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseProcNetSocketsAndSummarize(t *testing.T) {
+	loopback := "0100007F"
+	if binary.NativeEndian.Uint16([]byte{1, 0}) != 1 {
+		loopback = "7F000001"
+	}
+	tcp := []byte(`  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1 1 0000000000000000 100 0 0 10 0
+   1: ` + loopback + `:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 2 1 0000000000000000 100 0 0 10 0
+   2: 0A000002:0016 0B000001:D431 01 00000000:00000000 00:00000000 00000000     0        0 3 1 0000000000000000 100 0 0 10 0
+   3: 0A000002:0016 0B000001:D432 01 00000000:00000000 00:00000000 00000000     0        0 4 1 0000000000000000 100 0 0 10 0
+   4: 0A000002:0016 0C000001:D433 06 00000000:00000000 00:00000000 00000000     0        0 5 1 0000000000000000 100 0 0 10 0
+`)
+	udp := []byte(`   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  10: 00000000:0035 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 6 2 0000000000000000 0
+`)
+	entries := append(parseProcNetSockets(tcp, "tcp"), parseProcNetSockets(udp, "udp")...)
+	if len(entries) != 6 {
+		t.Fatalf("entries = %#v", entries)
+	}
+	if entries[1].localIP.String() != "127.0.0.1" || entries[1].localPort != 8080 {
+		t.Fatalf("loopback entry = %#v", entries[1])
+	}
+
+	conns := summarizeSockets(entries, 1)
+	if conns.TCP != 5 || conns.UDP != 1 {
+		t.Fatalf("counts = tcp %d udp %d", conns.TCP, conns.UDP)
+	}
+	if conns.TCPStates["LISTEN"] != 2 || conns.TCPStates["ESTABLISHED"] != 2 || conns.TCPStates["TIME_WAIT"] != 1 {
+		t.Fatalf("states = %#v", conns.TCPStates)
+	}
+	if len(conns.Listening) != 3 || conns.Listening[0].Port != 22 || conns.Listening[2].Proto != "udp" || conns.Listening[2].Port != 53 {
+		t.Fatalf("listening = %#v", conns.Listening)
+	}
+	if len(conns.TopPeers) != 1 || conns.TopPeers[0].Count != 2 {
+		t.Fatalf("top peers = %#v", conns.TopPeers)
+	}
+
+	if peers := summarizeSockets(entries, 0).TopPeers; len(peers) != 0 {
+		t.Fatalf("top peers disabled = %#v", peers)
+	}
+}
+
+func TestReadConnectionsUsesHostInitTablesWithoutHostNetwork(t *testing.T) {
+	d := t.TempDir()
+	dir := filepath.Join(d, "proc", "1", "net")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	tcp := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1 1 0000000000000000 100 0 0 10 0
+`
+	if err := os.WriteFile(filepath.Join(dir, "tcp"), []byte(tcp), 0644); err != nil {
+		t.Fatal(err)
+	}
+	host := hostPaths{proc: filepath.Join(d, "proc")}
+	conns, err := host.readConnections(0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conns.TCP != 1 || len(conns.Listening) != 1 || conns.Listening[0].Port != 22 {
+		t.Fatalf("connections = %#v", conns)
+	}
+}
@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"sort"
+	"time"
+)
+
+// containerSample holds the cumulative cgroup counters for one container.
+type containerSample struct {
+	id         string
+	name       string
+	runtime    string
+	image      string
+	cpuUsec    uint64
+	memUsed    uint64
+	memLimit   uint64
+	readBytes  uint64
+	writeBytes uint64
+	pids       int
+}
+
+type containerLabel struct {
+	name  string
+	image string
+}
+
+// containerLabels caches the names and images the container runtime reports,
+// by container ID. The Collector keeps one and only uses it under its lock.
+type containerLabels struct {
+	byID    map[string]containerLabel
+	fetched time.Time
+}
+
+// containersSince turns samples into rates against the previous call. The
+// returned map is the baseline for the next call.
+func containersSince(samples []containerSample, prev map[string]containerSample, elapsed float64) ([]Container, map[string]containerSample) {
+	next := make(map[string]containerSample, len(samples))
+	out := make([]Container, 0, len(samples))
+	for _, sample := range samples {
+		next[sample.id] = sample
+		container := Container{
+			ID:          sample.id,
+			Name:        sample.name,
+			Runtime:     sample.runtime,
+			Image:       sample.image,
+			MemoryUsed:  sample.memUsed,
+			MemoryLimit: sample.memLimit,
+			ReadBytes:   sample.readBytes,
+			WriteBytes:  sample.writeBytes,
+			PIDs:        sample.pids,
+		}
+		if last, ok := prev[sample.id]; ok && elapsed > 0 {
+			container.CPUPercent = round2(float64(counterDelta(sample.cpuUsec, last.cpuUsec)) / (elapsed * 1e6) * 100)
+			container.ReadRate = uint64(float64(counterDelta(sample.readBytes, last.readBytes)) / elapsed)
+			container.WriteRate = uint64(float64(counterDelta(sample.writeBytes, last.writeBytes)) / elapsed)
+		}
+		out = append(out, container)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, next
+}
@@ -0,0 +1,272 @@
+//go:build linux
+
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	cgroupMaxDepth        = 8
+	containerLabelRefresh = 30 * time.Second
+)
+
+var (
+	containerScopePattern = regexp.MustCompile(`^(docker|cri-containerd|crio|libpod)-([0-9a-f]{64})\.scope$`)
+	containerIDPattern    = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+	// Podman serves the Docker-compatible API, so both sockets are queried
+	// the same way. containerd only speaks gRPC and is labeled from cgroup
+	// paths instead.
+	containerSockets = []string{"/var/run/docker.sock", "/run/docker.sock", "/run/podman/podman.sock"}
+)
+
+type containerCgroup struct {
+	id      string
+	runtime string
+	path    string
+}
+
+func (h hostPaths) readContainers(patterns []string, cache *containerLabels) ([]containerSample, error) {
+	groups, err := findContainerCgroups(h.sysPath("fs", "cgroup"))
+	if err != nil {
+		return nil, err
+	}
+	labels := h.lookupContainerLabels(cache, groups)
+	out := make([]containerSample, 0, len(groups))
+	for _, group := range groups {
+		sample := readContainerCgroup(group)
+		if label, ok := labels[group.id]; ok {
+			sample.name = label.name
+			sample.image = label.image
+		}
+		if !containerSelected(sample, patterns) {
+			continue
+		}
+		out = append(out, sample)
+	}
+	return out, nil
+}
+
+// containerSelected applies CONTAINERS. "auto" selects everything; any other
+// entry is a glob matched against the container name or short ID.
+func containerSelected(sample containerSample, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.EqualFold(pattern, "auto") {
+			return true
+		}
+	}
+	return matchAny(sample.name, patterns) || matchAny(shortContainerID(sample.id), patterns)
+}
+
+// findContainerCgroups walks a cgroup v2 hierarchy for container scopes. It
+// recognizes the systemd driver layout (docker-<id>.scope and friends) and
+// the cgroupfs layout (<parent>/<64 hex id>).
+func findContainerCgroups(root string) ([]containerCgroup, error) {
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err != nil {
+		return nil, err
+	}
+	var out []containerCgroup
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		if rel != "." && strings.Count(rel, string(filepath.Separator)) >= cgroupMaxDepth {
+			return filepath.SkipDir
+		}
+		name := d.Name()
+		if match := containerScopePattern.FindStringSubmatch(name); match != nil {
+			runtime := match[1]
+			switch runtime {
+			case "cri-containerd":
+				runtime = "containerd"
+			case "libpod":
+				runtime = "podman"
+			}
+			out = append(out, containerCgroup{id: match[2], runtime: runtime, path: path})
+			return filepath.SkipDir
+		}
+		if containerIDPattern.MatchString(name) {
+			runtime := "containerd"
+			if filepath.Base(filepath.Dir(path)) == "docker" {
+				runtime = "docker"
+			}
+			out = append(out, containerCgroup{id: name, runtime: runtime, path: path})
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return out, err
+}
+
+func readContainerCgroup(group containerCgroup) containerSample {
+	sample := containerSample{
+		id:      group.id,
+		name:    group.runtime + ":" + shortContainerID(group.id),
+		runtime: group.runtime,
+	}
+	sample.memUsed, _ = readCgroupUint(filepath.Join(group.path, "memory.current"))
+	sample.memLimit, _ = readCgroupUint(filepath.Join(group.path, "memory.max"))
+	pids, _ := readCgroupUint(filepath.Join(group.path, "pids.current"))
+	sample.pids = int(pids)
+	if data, err := os.ReadFile(filepath.Join(group.path, "cpu.stat")); err == nil {
+		sample.cpuUsec = parseCgroupKeyed(data)["usage_usec"]
+	}
+	if data, err := os.ReadFile(filepath.Join(group.path, "io.stat")); err == nil {
+		sample.readBytes, sample.writeBytes = parseCgroupIOStat(data)
+	}
+	return sample
+}
+
+// readCgroupUint reads a single-value cgroup file. "max" means unlimited and
+// is reported as zero.
+func readCgroupUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+func parseCgroupKeyed(data []byte) map[string]uint64 {
+	out := map[string]uint64{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			out[fields[0]] = v
+		}
+	}
+	return out
+}
+
+// parseCgroupIOStat sums rbytes and wbytes across the devices in io.stat.
+func parseCgroupIOStat(data []byte) (uint64, uint64) {
+	var read, write uint64
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		for _, field := range strings.Fields(scanner.Text()) {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			n, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch key {
+			case "rbytes":
+				read += n
+			case "wbytes":
+				write += n
+			}
+		}
+	}
+	return read, write
+}
+
+// lookupContainerLabels returns the labels in cache, asking the runtime socket
+// again only when an unknown container shows up and the cache is old enough.
+func (h hostPaths) lookupContainerLabels(cache *containerLabels, groups []containerCgroup) map[string]containerLabel {
+	missing := false
+	for _, group := range groups {
+		if _, ok := cache.byID[group.id]; !ok && group.runtime != "containerd" {
+			missing = true
+			break
+		}
+	}
+	if missing && time.Since(cache.fetched) >= containerLabelRefresh {
+		cache.fetched = time.Now()
+		if labels, err := h.readDockerLabels(); err == nil {
+			cache.byID = labels
+		}
+	}
+	return cache.byID
+}
+
+func (h hostPaths) readDockerLabels() (map[string]containerLabel, error) {
+	var lastErr error = os.ErrNotExist
+	for _, socket := range containerSockets {
+		socket = h.rootPath(socket)
+		if _, err := os.Stat(socket); err != nil {
+			continue
+		}
+		labels, err := queryDockerSocket(socket)
+		if err == nil {
+			return labels, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func queryDockerSocket(socket string) (map[string]containerLabel, error) {
+	client := &http.Client{
+		Timeout: 3 * time.Second,
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+	resp, err := client.Get("http://docker/containers/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: GET /containers/json returned %s", socket, resp.Status)
+	}
+	return parseDockerContainers(resp.Body)
+}
+
+func parseDockerContainers(r io.Reader) (map[string]containerLabel, error) {
+	var rows []struct {
+		ID    string   `json:"Id"`
+		Names []string `json:"Names"`
+		Image string   `json:"Image"`
+	}
+	if err := json.NewDecoder(r).Decode(&rows); err != nil {
+		return nil, err
+	}
+	out := make(map[string]containerLabel, len(rows))
+	for _, row := range rows {
+		label := containerLabel{name: shortContainerID(row.ID), image: row.Image}
+		if len(row.Names) > 0 {
+			label.name = strings.TrimPrefix(row.Names[0], "/")
+		}
+		out[row.ID] = label
+	}
+	return out, nil
+}
+
+func shortContainerID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,116 @@
+//go:build linux
+
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeCgroupFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindContainerCgroupsReadsSystemdAndCgroupfsLayouts(t *testing.T) {
+	root := t.TempDir()
+	dockerID := strings.Repeat("a", 64)
+	containerdID := strings.Repeat("b", 64)
+	writeCgroupFiles(t, root, map[string]string{"cgroup.controllers": "cpu io memory pids\n"})
+	writeCgroupFiles(t, filepath.Join(root, "system.slice", "docker-"+dockerID+".scope"), map[string]string{
+		"memory.current": "1048576\n",
+		"memory.max":     "max\n",
+		"pids.current":   "7\n",
+		"cpu.stat":       "usage_usec 5000000\nuser_usec 4000000\nsystem_usec 1000000\n",
+		"io.stat":        "8:0 rbytes=4096 wbytes=8192 rios=1 wios=2\n8:16 rbytes=1024 wbytes=0 rios=1 wios=0\n",
+	})
+	writeCgroupFiles(t, filepath.Join(root, "k8s.io", containerdID), map[string]string{
+		"memory.current": "2048\n",
+		"memory.max":     "4096\n",
+	})
+	writeCgroupFiles(t, filepath.Join(root, "system.slice", "sshd.service"), map[string]string{"memory.current": "1\n"})
+
+	groups, err := findContainerCgroups(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("groups = %#v", groups)
+	}
+	byID := map[string]containerCgroup{}
+	for _, group := range groups {
+		byID[group.id] = group
+	}
+	if byID[dockerID].runtime != "docker" || byID[containerdID].runtime != "containerd" {
+		t.Fatalf("runtimes = %#v", byID)
+	}
+
+	docker := readContainerCgroup(byID[dockerID])
+	if docker.name != "docker:aaaaaaaaaaaa" || docker.memUsed != 1048576 || docker.memLimit != 0 || docker.pids != 7 {
+		t.Fatalf("docker sample = %#v", docker)
+	}
+	if docker.cpuUsec != 5000000 || docker.readBytes != 5120 || docker.writeBytes != 8192 {
+		t.Fatalf("docker counters = %#v", docker)
+	}
+	if limit := readContainerCgroup(byID[containerdID]).memLimit; limit != 4096 {
+		t.Fatalf("containerd memory limit = %d", limit)
+	}
+
+	if _, err := findContainerCgroups(t.TempDir()); err == nil {
+		t.Fatal("expected error for a non cgroup v2 root")
+	}
+}
+
+func TestParseDockerContainersAndSelection(t *testing.T) {
+	id := strings.Repeat("c", 64)
+	labels, err := parseDockerContainers(strings.NewReader(`[{"Id":"` + id + `","Names":["/web"],"Image":"nginx:1.27"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels[id].name != "web" || labels[id].image != "nginx:1.27" {
+		t.Fatalf("labels = %#v", labels)
+	}
+
+	sample := containerSample{id: id, name: "web"}
+	for _, tt := range []struct {
+		patterns []string
+		want     bool
+	}{
+		{[]string{"auto"}, true},
+		{[]string{"web*"}, true},
+		{[]string{"cccccccccccc"}, true},
+		{[]string{"db"}, false},
+	} {
+		if got := containerSelected(sample, tt.patterns); got != tt.want {
+			t.Fatalf("containerSelected(%v) = %v", tt.patterns, got)
+		}
+	}
+}
+
+func TestContainersSinceComputesRates(t *testing.T) {
+	prev := map[string]containerSample{"a": {id: "a", cpuUsec: 1000000, readBytes: 100, writeBytes: 100}}
+	samples := []containerSample{
+		{id: "a", name: "web", cpuUsec: 3000000, readBytes: 2100, writeBytes: 4100, memUsed: 10},
+		{id: "b", name: "db", cpuUsec: 9000000},
+	}
+	containers, next := containersSince(samples, prev, 2)
+	if len(containers) != 2 || len(next) != 2 {
+		t.Fatalf("containers = %#v", containers)
+	}
+	if containers[0].Name != "db" || containers[0].CPUPercent != 0 {
+		t.Fatalf("new container should have no rate yet: %#v", containers[0])
+	}
+	web := containers[1]
+	if web.CPUPercent != 100 || web.ReadRate != 1000 || web.WriteRate != 2000 || web.MemoryUsed != 10 {
+		t.Fatalf("web = %#v", web)
+	}
+}
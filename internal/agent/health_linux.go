@@ -0,0 +1,178 @@
+//go:build linux
+
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	mdstatHeader   = regexp.MustCompile(`^(md\S+)\s*:\s*(\S+)\s*(.*)$`)
+	mdstatCounts   = regexp.MustCompile(`\[(\d+)/(\d+)\]`)
+	mdstatProgress = regexp.MustCompile(`(resync|recovery|check|reshape|repair)\s*=\s*([0-9.]+)%`)
+)
+
+func (h hostPaths) readDiskHealth() ([]RAIDArray, []SMARTDisk) {
+	var raid []RAIDArray
+	if data, err := os.ReadFile(h.procPath("mdstat")); err == nil {
+		raid = parseMdstat(data)
+	}
+	return raid, readSMART()
+}
+
+// parseMdstat reads /proc/mdstat. Each array starts with "mdN : state
+// [level] members..." followed by indented status lines with the
+// [total/active] counts and any sync progress bar.
+func parseMdstat(data []byte) []RAIDArray {
+	var out []RAIDArray
+	var current *RAIDArray
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := mdstatHeader.FindStringSubmatch(line); match != nil {
+			out = append(out, RAIDArray{Name: match[1], State: match[2]})
+			current = &out[len(out)-1]
+			members := strings.Fields(match[3])
+			for len(members) > 0 && strings.HasPrefix(members[0], "(") {
+				current.State += " " + members[0]
+				members = members[1:]
+			}
+			if len(members) > 0 && !strings.Contains(members[0], "[") {
+				current.Level = members[0]
+				members = members[1:]
+			}
+			for _, member := range members {
+				if strings.HasSuffix(member, "(F)") {
+					name, _, _ := strings.Cut(strings.TrimSuffix(member, "(F)"), "[")
+					current.Failed = append(current.Failed, name)
+				}
+			}
+			current.Devices = len(members)
+			current.ActiveDevices = len(members) - len(current.Failed)
+			current.Degraded = len(current.Failed) > 0
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			current = nil
+			continue
+		}
+		if match := mdstatCounts.FindStringSubmatch(line); match != nil {
+			current.Devices, _ = strconv.Atoi(match[1])
+			current.ActiveDevices, _ = strconv.Atoi(match[2])
+			current.Degraded = current.ActiveDevices < current.Devices
+		}
+		if match := mdstatProgress.FindStringSubmatch(line); match != nil {
+			current.SyncAction = match[1]
+			current.SyncPercent, _ = strconv.ParseFloat(match[2], 64)
+		}
+	}
+	return out
+}
+
+// readSMART scans drives with smartctl when it is installed. smartctl uses
+// its exit status as a bit mask of findings, so output is parsed even when
+// the command reports an error.
+func readSMART() []SMARTDisk {
+	if _, err := exec.LookPath("smartctl"); err != nil {
+		return nil
+	}
+	scan, _ := runSmartctl("--scan-open", "--json")
+	var devices struct {
+		Devices []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		} `json:"devices"`
+	}
+	if err := json.Unmarshal(scan, &devices); err != nil {
+		return nil
+	}
+	var out []SMARTDisk
+	for _, device := range devices.Devices {
+		args := []string{"--json", "--info", "--health", "--attributes"}
+		if device.Type != "" {
+			args = append(args, "--device="+device.Type)
+		}
+		data, _ := runSmartctl(append(args, device.Name)...)
+		if disk, ok := parseSmartctl(data, device.Name); ok {
+			out = append(out, disk)
+		}
+	}
+	return out
+}
+
+func runSmartctl(args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	return exec.CommandContext(ctx, "smartctl", args...).Output()
+}
+
+func parseSmartctl(data []byte, device string) (SMARTDisk, bool) {
+	var report struct {
+		ModelName    string `json:"model_name"`
+		SerialNumber string `json:"serial_number"`
+		SmartStatus  *struct {
+			Passed bool `json:"passed"`
+		} `json:"smart_status"`
+		Temperature struct {
+			Current float64 `json:"current"`
+		} `json:"temperature"`
+		PowerOnTime struct {
+			Hours uint64 `json:"hours"`
+		} `json:"power_on_time"`
+		ATAAttributes struct {
+			Table []struct {
+				ID    int `json:"id"`
+				Value int `json:"value"`
+				Raw   struct {
+					Value uint64 `json:"value"`
+				} `json:"raw"`
+			} `json:"table"`
+		} `json:"ata_smart_attributes"`
+		NVMeHealth *struct {
+			PercentageUsed float64 `json:"percentage_used"`
+			MediaErrors    uint64  `json:"media_errors"`
+		} `json:"nvme_smart_health_information_log"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil || report.SmartStatus == nil {
+		return SMARTDisk{}, false
+	}
+	disk := SMARTDisk{
+		Device:       device,
+		Model:        report.ModelName,
+		Serial:       report.SerialNumber,
+		Passed:       report.SmartStatus.Passed,
+		Temperature:  report.Temperature.Current,
+		PowerOnHours: report.PowerOnTime.Hours,
+	}
+	for _, attr := range report.ATAAttributes.Table {
+		switch attr.ID {
+		case 5:
+			disk.ReallocatedSectors = attr.Raw.Value
+		case 197:
+			disk.PendingSectors = attr.Raw.Value
+		case 177, 231, 233:
+			// Wear_Leveling_Count, SSD_Life_Left and Media_Wearout_Indicator
+			// count down from 100 as the drive wears.
+			if attr.Value > 0 && attr.Value <= 100 {
+				disk.WearPercent = float64(100 - attr.Value)
+			}
+		}
+	}
+	if report.NVMeHealth != nil {
+		disk.WearPercent = report.NVMeHealth.PercentageUsed
+		disk.MediaErrors = report.NVMeHealth.MediaErrors
+	}
+	return disk, true
+}
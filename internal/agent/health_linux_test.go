@@ -0,0 +1,77 @@
+//go:build linux
+
+package agent
+
+import "testing"
+
+func TestParseMdstat(t *testing.T) {
+	data := []byte(`Personalities : [raid1] [raid0] [linear]
+md0 : active raid1 sdb1[1] sda1[0]
+      1046528 blocks super 1.2 [2/2] [UU]
+
+md1 : active raid1 sdb2[1](F) sda2[0]
+      976224256 blocks super 1.2 [2/1] [U_]
+      [=>...................]  recovery =  8.5% (83034880/976224256) finish=86.3min speed=172391K/sec
+      bitmap: 8/8 pages [32KB], 65536KB chunk
+
+md2 : active (auto-read-only) raid0 sdc1[1] sdd1[0]
+      2093056 blocks super 1.2 512k chunks
+
+md127 : inactive sde[0](S)
+      976631512 blocks super 1.2
+
+unused devices: <none>
+`)
+	arrays := parseMdstat(data)
+	if len(arrays) != 4 {
+		t.Fatalf("arrays = %#v", arrays)
+	}
+	if got := arrays[0]; got.Name != "md0" || got.Level != "raid1" || got.Devices != 2 || got.ActiveDevices != 2 || got.Degraded {
+		t.Fatalf("md0 = %#v", got)
+	}
+	md1 := arrays[1]
+	if !md1.Degraded || md1.ActiveDevices != 1 || len(md1.Failed) != 1 || md1.Failed[0] != "sdb2" {
+		t.Fatalf("md1 = %#v", md1)
+	}
+	if md1.SyncAction != "recovery" || md1.SyncPercent != 8.5 {
+		t.Fatalf("md1 sync = %#v", md1)
+	}
+	if got := arrays[2]; got.State != "active (auto-read-only)" || got.Level != "raid0" || got.Devices != 2 || got.Degraded {
+		t.Fatalf("md2 = %#v", got)
+	}
+	if got := arrays[3]; got.State != "inactive" || got.Level != "" || got.Devices != 1 {
+		t.Fatalf("md127 = %#v", got)
+	}
+}
+
+func TestParseSmartctl(t *testing.T) {
+	ata := []byte(`{
+  "model_name": "Samsung SSD 860 EVO 500GB",
+  "serial_number": "S3Z1NB0K000000",
+  "smart_status": {"passed": true},
+  "temperature": {"current": 34},
+  "power_on_time": {"hours": 21034},
+  "ata_smart_attributes": {"table": [
+    {"id": 5, "name": "Reallocated_Sector_Ct", "value": 100, "raw": {"value": 12}},
+    {"id": 177, "name": "Wear_Leveling_Count", "value": 93, "raw": {"value": 112}},
+    {"id": 197, "name": "Current_Pending_Sector", "value": 100, "raw": {"value": 2}}
+  ]}
+}`)
+	disk, ok := parseSmartctl(ata, "/dev/sda")
+	if !ok || !disk.Passed || disk.Model != "Samsung SSD 860 EVO 500GB" || disk.Temperature != 34 || disk.PowerOnHours != 21034 {
+		t.Fatalf("ata disk = %#v ok = %v", disk, ok)
+	}
+	if disk.ReallocatedSectors != 12 || disk.PendingSectors != 2 || disk.WearPercent != 7 {
+		t.Fatalf("ata attributes = %#v", disk)
+	}
+
+	nvme := []byte(`{"model_name":"WD Blue SN570","smart_status":{"passed":false},"nvme_smart_health_information_log":{"percentage_used":91,"media_errors":3}}`)
+	disk, ok = parseSmartctl(nvme, "/dev/nvme0")
+	if !ok || disk.Passed || disk.WearPercent != 91 || disk.MediaErrors != 3 {
+		t.Fatalf("nvme disk = %#v ok = %v", disk, ok)
+	}
+
+	if _, ok := parseSmartctl([]byte(`{"smartctl":{"exit_status":2}}`), "/dev/sdz"); ok {
+		t.Fatal("expected a report without smart_status to be skipped")
+	}
+}
@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"path/filepath"
+
+	"vps-agent/internal/config"
+)
+
+// hostPaths are the procfs, sysfs and root directories the Linux collectors
+// read from. HOST_PROC and HOST_SYS move the first two, for example to a
+// host's /proc bind-mounted into a container or to a captured tree in
+// testdata. root is where the host's root filesystem is mounted (HOST_ROOT);
+// it is "/" unless the agent runs in a container. Other platforms ignore
+// them.
+type hostPaths struct {
+	proc string
+	sys  string
+	root string
+}
+
+func newHostPaths(cfg config.Config) hostPaths {
+	h := hostPaths{proc: "/proc", sys: "/sys", root: "/"}
+	if cfg.HostProc != "" {
+		h.proc = filepath.Clean(cfg.HostProc)
+	}
+	if cfg.HostSys != "" {
+		h.sys = filepath.Clean(cfg.HostSys)
+	}
+	if cfg.HostRoot != "" {
+		h.root = filepath.Clean(cfg.HostRoot)
+	}
+	return h
+}
+
+func (h hostPaths) procPath(elem ...string) string {
+	return filepath.Join(append([]string{h.proc}, elem...)...)
+}
+
+func (h hostPaths) sysPath(elem ...string) string {
+	return filepath.Join(append([]string{h.sys}, elem...)...)
+}
+
+func (h hostPaths) rootPath(elem ...string) string {
+	return filepath.Join(append([]string{h.root}, elem...)...)
+}
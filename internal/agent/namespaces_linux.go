@@ -0,0 +1,80 @@
+//go:build linux
+
+package agent
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The initial PID and UTS namespaces have fixed inode numbers
+// (include/linux/proc_ns.h); other namespaces have no fixed number and are
+// compared against the host's init process instead.
+const (
+	linuxInitPIDNamespace = 0xEFFFFFFC
+	linuxInitUTSNamespace = 0xEFFFFFFE
+)
+
+// readNamespaces compares the agent's own namespaces, always read from the
+// real /proc, with the host's. procPath("1") is the host's init only when that
+// procfs belongs to the initial PID namespace.
+func (h hostPaths) readNamespaces() HostNamespaces {
+	ns := HostNamespaces{
+		Container: runningInContainer(),
+		PID:       namespaceInode("/proc/self/ns/pid") == linuxInitPIDNamespace,
+		UTS:       namespaceInode("/proc/self/ns/uts") == linuxInitUTSNamespace,
+	}
+	if namespaceInode(h.procPath("1", "ns", "pid")) == linuxInitPIDNamespace {
+		self, err := os.Readlink("/proc/self/ns/net")
+		host, hostErr := os.Readlink(h.procPath("1", "ns", "net"))
+		ns.Network = err == nil && hostErr == nil && self == host
+	}
+	return ns
+}
+
+func namespaceInode(path string) uint64 {
+	link, err := os.Readlink(path)
+	if err != nil {
+		return 0
+	}
+	return parseNamespaceLink(link)
+}
+
+// parseNamespaceLink reads the inode from a namespace link such as
+// "pid:[4026531836]".
+func parseNamespaceLink(link string) uint64 {
+	_, rest, ok := strings.Cut(link, ":[")
+	if !ok {
+		return 0
+	}
+	inode, err := strconv.ParseUint(strings.TrimSuffix(rest, "]"), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return inode
+}
+
+func runningInContainer() bool {
+	if os.Getenv("container") != "" || os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return true
+	}
+	for _, marker := range []string{"/.dockerenv", "/run/.containerenv"} {
+		if _, err := os.Stat(marker); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// readHostname prefers the host's /etc/hostname when the agent has its own
+// UTS namespace, since the kernel would otherwise return the container's name.
+func (h hostPaths) readHostname(ns HostNamespaces) string {
+	if !ns.UTS {
+		if name := readTrimmed(h.rootPath("etc", "hostname")); name != "" {
+			return name
+		}
+	}
+	name, _ := os.Hostname()
+	return name
+}
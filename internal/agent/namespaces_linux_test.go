@@ -0,0 +1,18 @@
+//go:build linux
+
+package agent
+
+import "testing"
+
+func TestParseNamespaceLink(t *testing.T) {
+	for link, want := range map[string]uint64{
+		"pid:[4026531836]": linuxInitPIDNamespace,
+		"uts:[4026532601]": 4026532601,
+		"net:[]":           0,
+		"garbage":          0,
+	} {
+		if got := parseNamespaceLink(link); got != want {
+			t.Fatalf("parseNamespaceLink(%q) = %d, want %d", link, got, want)
+		}
+	}
+}
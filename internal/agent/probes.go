@@ -0,0 +1,279 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"vps-agent/internal/config"
+)
+
+const (
+	probeICMPCount   = 5
+	probeTCPCount    = 3
+	probeGap         = 200 * time.Millisecond
+	probeTimeout     = 3 * time.Second
+	probeHTTPTimeout = 10 * time.Second
+	probeHTTPMaxBody = 1 << 20
+)
+
+// prober runs probes in the background so that a slow or unreachable target
+// never delays a report. Collect starts a round once the interval has passed
+// and always reports the last finished round.
+type prober struct {
+	mu      sync.Mutex
+	running bool
+	results []ProbeResult
+}
+
+// start begins a round unless the previous one is still running.
+func (p *prober) start(probes []config.Probe) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running {
+		return false
+	}
+	p.running = true
+	go func() {
+		results := runProbes(probes)
+		p.mu.Lock()
+		p.results = results
+		p.running = false
+		p.mu.Unlock()
+	}()
+	return true
+}
+
+func (p *prober) latest() []ProbeResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.results
+}
+
+// runProbes runs every probe concurrently and returns results in config order.
+func runProbes(probes []config.Probe) []ProbeResult {
+	out := make([]ProbeResult, len(probes))
+	var wg sync.WaitGroup
+	for i, probe := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out[i] = runProbe(probe)
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+func runProbe(probe config.Probe) ProbeResult {
+	result := ProbeResult{
+		Name:      probe.Name,
+		Type:      probe.Type,
+		Target:    probe.Target,
+		Timestamp: time.Now().Unix(),
+		WarnMs:    durationMs(probe.Warn),
+	}
+	switch probe.Type {
+	case "icmp":
+		rtts, err := pingHost(probe.Target, probeICMPCount, probeTimeout)
+		result.summarize(rtts, probeICMPCount, err)
+	case "tcp":
+		rtts, err := dialTCP(probe.Target, probeTCPCount)
+		result.summarize(rtts, probeTCPCount, err)
+	case "http":
+		result.fetchHTTP(probe.Target)
+	}
+	return result
+}
+
+// dialTCP measures connect time. The connection is closed straight away
+// without sending anything.
+func dialTCP(target string, count int) ([]time.Duration, error) {
+	var rtts []time.Duration
+	var lastErr error
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			time.Sleep(probeGap)
+		}
+		start := time.Now()
+		conn, err := net.DialTimeout("tcp", target, probeTimeout)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		rtts = append(rtts, time.Since(start))
+		conn.Close()
+	}
+	return rtts, lastErr
+}
+
+// summarize fills in loss and latency from the round trips that succeeded.
+// The error is kept only when nothing got through.
+func (r *ProbeResult) summarize(rtts []time.Duration, sent int, err error) {
+	r.Sent = sent
+	r.Lost = sent - len(rtts)
+	if sent > 0 {
+		r.LossPercent = round2(float64(r.Lost) / float64(sent) * 100)
+	}
+	if len(rtts) == 0 {
+		if err != nil {
+			r.Error = err.Error()
+		}
+		return
+	}
+	var sum, jitter float64
+	r.MinMs = math.MaxFloat64
+	for i, rtt := range rtts {
+		ms := durationMs(rtt)
+		sum += ms
+		r.MinMs = math.Min(r.MinMs, ms)
+		r.MaxMs = math.Max(r.MaxMs, ms)
+		if i > 0 {
+			jitter += math.Abs(ms - durationMs(rtts[i-1]))
+		}
+	}
+	r.LatencyMs = round2(sum / float64(len(rtts)))
+	if len(rtts) > 1 {
+		r.JitterMs = round2(jitter / float64(len(rtts)-1))
+	}
+}
+
+// fetchHTTP sends one GET without following redirects and records the phase
+// timings. Any response counts as delivered; the server flags error statuses.
+func (r *ProbeResult) fetchHTTP(target string) {
+	r.Sent = 1
+	// Trace hooks run on the transport's dial goroutine, which can outlive a
+	// timed out request, so phase timings are only read under the lock.
+	var mu sync.Mutex
+	var dns, connect, handshake, firstByte float64
+	var dnsStart, connectStart, tlsStart time.Time
+	start := time.Now()
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { mu.Lock(); dnsStart = time.Now(); mu.Unlock() },
+		DNSDone:  func(httptrace.DNSDoneInfo) { mu.Lock(); dns = sinceMs(dnsStart); mu.Unlock() },
+		ConnectStart: func(string, string) {
+			mu.Lock()
+			if connectStart.IsZero() {
+				connectStart = time.Now()
+			}
+			mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			mu.Lock()
+			if err == nil {
+				connect = sinceMs(connectStart)
+			}
+			mu.Unlock()
+		},
+		TLSHandshakeStart:    func() { mu.Lock(); tlsStart = time.Now(); mu.Unlock() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { mu.Lock(); handshake = sinceMs(tlsStart); mu.Unlock() },
+		GotFirstResponseByte: func() { mu.Lock(); firstByte = sinceMs(start); mu.Unlock() },
+	}
+	defer func() {
+		mu.Lock()
+		r.DNSMs, r.ConnectMs, r.TLSMs, r.FirstByteMs = dns, connect, handshake, firstByte
+		mu.Unlock()
+	}()
+	ctx, cancel := context.WithTimeout(httptrace.WithClientTrace(context.Background(), trace), probeHTTPTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	req.Header.Set("User-Agent", "vps-agent-probe")
+	client := &http.Client{
+		Transport: &http.Transport{DisableKeepAlives: true},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	_, err = io.Copy(io.Discard, io.LimitReader(resp.Body, probeHTTPMaxBody))
+	resp.Body.Close()
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	r.StatusCode = resp.StatusCode
+	r.LatencyMs = sinceMs(start)
+	r.MinMs, r.MaxMs = r.LatencyMs, r.LatencyMs
+}
+
+func (r *ProbeResult) fail(err error) {
+	r.Lost = r.Sent
+	r.LossPercent = 100
+	r.Error = err.Error()
+}
+
+func durationMs(d time.Duration) float64 {
+	return round2(float64(d) / float64(time.Millisecond))
+}
+
+func sinceMs(start time.Time) float64 {
+	if start.IsZero() {
+		return 0
+	}
+	return durationMs(time.Since(start))
+}
+
+// SetRemoteProbes replaces the probe targets handed out by the server and
+// starts a new round on the next collect. Invalid entries are skipped and
+// reported together.
+func (c *Collector) SetRemoteProbes(items []RemoteProbe) error {
+	probes := make([]config.Probe, 0, len(items))
+	var errs []error
+	for _, item := range items {
+		probe := config.Probe{
+			Name:   item.Name,
+			Type:   item.Type,
+			Target: item.Target,
+			Warn:   time.Duration(item.WarnMs) * time.Millisecond,
+		}
+		if probe.Name == "" {
+			errs = append(errs, fmt.Errorf("remote probe %q has no name", probe.Target))
+			continue
+		}
+		if err := probe.Validate(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		probes = append(probes, probe)
+	}
+	c.mu.Lock()
+	c.remoteProbes = probes
+	c.lastProbe = time.Time{}
+	c.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// effectiveProbes is the local PROBES list followed by the server's targets.
+// A local probe wins over a server one with the same name.
+func (c *Collector) effectiveProbes() []config.Probe {
+	if len(c.remoteProbes) == 0 {
+		return c.cfg.Probes
+	}
+	local := make(map[string]bool, len(c.cfg.Probes))
+	for _, probe := range c.cfg.Probes {
+		local[probe.Name] = true
+	}
+	probes := append([]config.Probe(nil), c.cfg.Probes...)
+	for _, probe := range c.remoteProbes {
+		if !local[probe.Name] {
+			probes = append(probes, probe)
+		}
+	}
+	return probes
+}
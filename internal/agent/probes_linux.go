@@ -0,0 +1,106 @@
+//go:build linux
+
+package agent
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+const (
+	icmpEchoRequest   = 8
+	icmpEchoReply     = 0
+	icmpv6EchoRequest = 128
+	icmpv6EchoReply   = 129
+)
+
+// pingHost sends ICMP echo requests over an unprivileged ping socket
+// (SOCK_DGRAM, IPPROTO_ICMP). The kernel allows these for groups listed in
+// net.ipv4.ping_group_range and rewrites the echo identifier itself, so
+// replies are matched by sequence number only.
+func pingHost(host string, count int, timeout time.Duration) ([]time.Duration, error) {
+	addr, err := net.ResolveIPAddr("ip", host)
+	if err != nil {
+		return nil, err
+	}
+	family, proto, request, reply := syscall.AF_INET, syscall.IPPROTO_ICMP, byte(icmpEchoRequest), byte(icmpEchoReply)
+	if addr.IP.To4() == nil {
+		family, proto, request, reply = syscall.AF_INET6, syscall.IPPROTO_ICMPV6, icmpv6EchoRequest, icmpv6EchoReply
+	}
+	fd, err := syscall.Socket(family, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, proto)
+	if err != nil {
+		return nil, fmt.Errorf("ping socket: %w (check net.ipv4.ping_group_range)", err)
+	}
+	file := os.NewFile(uintptr(fd), "ping")
+	conn, err := net.FilePacketConn(file)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	dst := &net.UDPAddr{IP: addr.IP, Zone: addr.Zone}
+	buf := make([]byte, 1500)
+	var rtts []time.Duration
+	var lastErr error
+	for seq := 1; seq <= count; seq++ {
+		if seq > 1 {
+			time.Sleep(probeGap)
+		}
+		start := time.Now()
+		if _, err := conn.WriteTo(icmpEcho(request, uint16(seq)), dst); err != nil {
+			lastErr = err
+			continue
+		}
+		if err := conn.SetReadDeadline(start.Add(timeout)); err != nil {
+			return rtts, err
+		}
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				lastErr = err
+				break
+			}
+			if n >= 8 && buf[0] == reply && binary.BigEndian.Uint16(buf[6:8]) == uint16(seq) {
+				rtts = append(rtts, time.Since(start))
+				break
+			}
+		}
+	}
+	if len(rtts) == 0 && lastErr != nil && errors.Is(lastErr, os.ErrDeadlineExceeded) {
+		lastErr = errors.New("request timed out")
+	}
+	return rtts, lastErr
+}
+
+// icmpEcho builds an echo request with a small payload. The checksum is
+// filled in for ICMPv4; the kernel computes the ICMPv6 one.
+func icmpEcho(typ byte, seq uint16) []byte {
+	packet := make([]byte, 8+16)
+	packet[0] = typ
+	binary.BigEndian.PutUint16(packet[6:8], seq)
+	copy(packet[8:], "vps-agent-probe.")
+	if typ == icmpEchoRequest {
+		binary.BigEndian.PutUint16(packet[2:4], icmpChecksum(packet))
+	}
+	return packet
+}
+
+func icmpChecksum(data []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(data[i])<<8 | uint32(data[i+1])
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,28 @@
+//go:build linux
+
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestICMPEchoChecksum(t *testing.T) {
+	packet := icmpEcho(icmpEchoRequest, 7)
+	if packet[0] != icmpEchoRequest || packet[7] != 7 {
+		t.Fatalf("packet = % x", packet)
+	}
+	if got := icmpChecksum(packet); got != 0 {
+		t.Fatalf("checksum over packet = %#x, want 0", got)
+	}
+}
+
+func TestPingHostLoopback(t *testing.T) {
+	rtts, err := pingHost("127.0.0.1", 2, time.Second)
+	if err != nil && len(rtts) == 0 {
+		t.Skipf("ping sockets unavailable: %v", err)
+	}
+	if len(rtts) != 2 {
+		t.Fatalf("rtts = %v err = %v", rtts, err)
+	}
+}
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"vps-agent/internal/config"
+)
+
+func TestProbeSummarizeComputesLossAndJitter(t *testing.T) {
+	var result ProbeResult
+	result.summarize([]time.Duration{10 * time.Millisecond, 14 * time.Millisecond, 12 * time.Millisecond}, 5, nil)
+	if result.Sent != 5 || result.Lost != 2 || result.LossPercent != 40 {
+		t.Fatalf("loss = %#v", result)
+	}
+	if result.LatencyMs != 12 || result.MinMs != 10 || result.MaxMs != 14 || result.JitterMs != 3 {
+		t.Fatalf("latency = %#v", result)
+	}
+
+	var down ProbeResult
+	down.summarize(nil, 3, net.ErrClosed)
+	if down.Lost != 3 || down.LossPercent != 100 || down.Error == "" {
+		t.Fatalf("down = %#v", down)
+	}
+}
+
+func TestRunProbesTCPAndHTTP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := closed.Addr().String()
+	closed.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	results := runProbes([]config.Probe{
+		{Name: "open", Type: "tcp", Target: listener.Addr().String(), Warn: 250 * time.Millisecond},
+		{Name: "closed", Type: "tcp", Target: closedAddr},
+		{Name: "api", Type: "http", Target: server.URL},
+		{Name: "moved", Type: "http", Target: server.URL + "/moved"},
+	})
+	if len(results) != 4 {
+		t.Fatalf("results = %#v", results)
+	}
+	if open := results[0]; open.Name != "open" || open.Sent != probeTCPCount || open.Lost != 0 || open.LatencyMs <= 0 || open.WarnMs != 250 || open.Timestamp == 0 {
+		t.Fatalf("open = %#v", open)
+	}
+	if closed := results[1]; closed.Lost != probeTCPCount || closed.LossPercent != 100 || closed.Error == "" {
+		t.Fatalf("closed = %#v", closed)
+	}
+	if api := results[2]; api.StatusCode != http.StatusServiceUnavailable || api.Lost != 0 || api.LatencyMs <= 0 || api.ConnectMs <= 0 {
+		t.Fatalf("api = %#v", api)
+	}
+	if moved := results[3]; moved.StatusCode != http.StatusFound {
+		t.Fatalf("redirect was followed: %#v", moved)
+	}
+}
+
+func TestSetRemoteProbesMergesWithLocalProbes(t *testing.T) {
+	c := &Collector{cfg: config.Config{Probes: []config.Probe{{Name: "CT", Type: "icmp", Target: "202.96.209.133"}}}}
+	c.lastProbe = time.Now()
+	err := c.SetRemoteProbes([]RemoteProbe{
+		{Name: "CT", Type: "icmp", Target: "1.1.1.1"},
+		{Name: "origin", Type: "tcp", Target: "origin.example.com:443", WarnMs: 250},
+		{Name: "broken", Type: "tcp", Target: "origin.example.com"},
+		{Type: "icmp", Target: "8.8.8.8"},
+	})
+	if err == nil {
+		t.Fatal("expected error for invalid remote probe")
+	}
+	if !c.lastProbe.IsZero() {
+		t.Fatal("remote probes should start a new round")
+	}
+	probes := c.effectiveProbes()
+	if len(probes) != 2 || probes[0].Target != "202.96.209.133" || probes[1].Name != "origin" || probes[1].Warn != 250*time.Millisecond {
+		t.Fatalf("effective probes = %#v", probes)
+	}
+}
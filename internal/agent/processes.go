@@ -0,0 +1,122 @@
+package agent
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+const maxProcessCommandLen = 256
+
+// processSample is one process as read from the platform. cpuSeconds is the
+// cumulative user+system time and ageSeconds how long the process has run;
+// start distinguishes a reused PID from the process seen last time.
+type processSample struct {
+	pid        int
+	start      uint64
+	name       string
+	user       string
+	command    string
+	threads    int
+	rss        uint64
+	cpuSeconds float64
+	ageSeconds float64
+}
+
+type processKey struct {
+	pid   int
+	start uint64
+}
+
+// topProcesses ranks samples by CPU and RSS. CPU usage is measured against
+// the previous snapshot; processes that were not in it fall back to their
+// lifetime average. The returned map is the baseline for the next call.
+func topProcesses(samples []processSample, prev map[processKey]float64, elapsed float64, n int) (*TopProcesses, map[processKey]float64) {
+	next := make(map[processKey]float64, len(samples))
+	if n <= 0 || len(samples) == 0 {
+		for _, sample := range samples {
+			next[processKey{sample.pid, sample.start}] = sample.cpuSeconds
+		}
+		return nil, next
+	}
+	infos := make([]ProcessInfo, len(samples))
+	for i, sample := range samples {
+		key := processKey{sample.pid, sample.start}
+		next[key] = sample.cpuSeconds
+		usage := 0.0
+		if last, ok := prev[key]; ok && elapsed > 0 && sample.cpuSeconds >= last {
+			usage = (sample.cpuSeconds - last) / elapsed * 100
+		} else if sample.ageSeconds > 0 {
+			usage = sample.cpuSeconds / sample.ageSeconds * 100
+		}
+		infos[i] = ProcessInfo{
+			PID:        sample.pid,
+			Name:       sample.name,
+			User:       sample.user,
+			Command:    truncateCommand(sample.command),
+			Threads:    sample.threads,
+			CPUPercent: round2(usage),
+			RSS:        sample.rss,
+		}
+	}
+	pick := func(less func(a, b ProcessInfo) bool) []ProcessInfo {
+		sorted := append([]ProcessInfo(nil), infos...)
+		sort.SliceStable(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+		if len(sorted) > n {
+			sorted = sorted[:n]
+		}
+		return sorted
+	}
+	return &TopProcesses{
+		ByCPU: pick(func(a, b ProcessInfo) bool {
+			if a.CPUPercent != b.CPUPercent {
+				return a.CPUPercent > b.CPUPercent
+			}
+			return a.PID < b.PID
+		}),
+		ByMemory: pick(func(a, b ProcessInfo) bool {
+			if a.RSS != b.RSS {
+				return a.RSS > b.RSS
+			}
+			return a.PID < b.PID
+		}),
+	}, next
+}
+
+// fillProcessDetails resolves the fields that are too costly to read for
+// every process, only for the ones that made it into the snapshot.
+func (t *TopProcesses) fillProcessDetails(h hostPaths) {
+	if t == nil {
+		return
+	}
+	type details struct{ user, command string }
+	seen := map[int]details{}
+	for _, list := range [][]ProcessInfo{t.ByCPU, t.ByMemory} {
+		for i := range list {
+			if list[i].User != "" && list[i].Command != "" {
+				continue
+			}
+			d, ok := seen[list[i].PID]
+			if !ok {
+				d.user, d.command = h.readProcessDetails(list[i].PID)
+				seen[list[i].PID] = d
+			}
+			if list[i].User == "" {
+				list[i].User = d.user
+			}
+			if list[i].Command == "" {
+				list[i].Command = truncateCommand(d.command)
+			}
+		}
+	}
+}
+
+func truncateCommand(command string) string {
+	if len(command) <= maxProcessCommandLen {
+		return command
+	}
+	cut := maxProcessCommandLen
+	for cut > 0 && !utf8.RuneStart(command[cut]) {
+		cut--
+	}
+	return command[:cut] + "…"
+}
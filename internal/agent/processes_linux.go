@@ -0,0 +1,114 @@
+//go:build linux
+
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// linuxClockTicks is USER_HZ, which is 100 on every mainstream architecture.
+const linuxClockTicks = 100
+
+var (
+	processUsersMu sync.Mutex
+	processUsers   = map[uint32]string{}
+)
+
+func (h hostPaths) readProcesses() ([]processSample, error) {
+	entries, err := os.ReadDir(h.procPath())
+	if err != nil {
+		return nil, err
+	}
+	uptime, _ := h.readUptime()
+	pageSize := uint64(os.Getpagesize())
+	out := make([]processSample, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() || !isDigits(entry.Name()) {
+			continue
+		}
+		data, err := os.ReadFile(h.procPath(entry.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		sample, err := parseProcessStat(data, pageSize, uptime)
+		if err != nil {
+			continue
+		}
+		out = append(out, sample)
+	}
+	return out, nil
+}
+
+// parseProcessStat reads /proc/[pid]/stat. The command name is wrapped in
+// parentheses and may itself contain spaces or ')', so fields are taken
+// after the last ')'.
+func parseProcessStat(data []byte, pageSize, uptime uint64) (processSample, error) {
+	open := bytes.IndexByte(data, '(')
+	end := bytes.LastIndexByte(data, ')')
+	if open <= 0 || end < open {
+		return processSample{}, errors.New("invalid /proc/[pid]/stat")
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data[:open])))
+	if err != nil {
+		return processSample{}, err
+	}
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 22 {
+		return processSample{}, errors.New("short /proc/[pid]/stat")
+	}
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	threads, _ := strconv.Atoi(fields[17])
+	start, _ := strconv.ParseUint(fields[19], 10, 64)
+	rssPages, _ := strconv.ParseUint(fields[21], 10, 64)
+	sample := processSample{
+		pid:        pid,
+		start:      start,
+		name:       string(data[open+1 : end]),
+		threads:    threads,
+		rss:        rssPages * pageSize,
+		cpuSeconds: float64(utime+stime) / linuxClockTicks,
+	}
+	if started := float64(start) / linuxClockTicks; float64(uptime) > started {
+		sample.ageSeconds = float64(uptime) - started
+	}
+	return sample, nil
+}
+
+func (h hostPaths) readProcessDetails(pid int) (string, string) {
+	dir := h.procPath(strconv.Itoa(pid))
+	owner := ""
+	if info, err := os.Stat(dir); err == nil {
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			owner = processUserName(stat.Uid)
+		}
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "cmdline"))
+	return owner, parseProcessCmdline(data)
+}
+
+func parseProcessCmdline(data []byte) string {
+	return strings.TrimSpace(string(bytes.ReplaceAll(bytes.TrimRight(data, "\x00"), []byte{0}, []byte{' '})))
+}
+
+func processUserName(uid uint32) string {
+	processUsersMu.Lock()
+	defer processUsersMu.Unlock()
+	if name, ok := processUsers[uid]; ok {
+		return name
+	}
+	name := strconv.FormatUint(uint64(uid), 10)
+	if u, err := user.LookupId(name); err == nil {
+		name = u.Username
+	}
+	processUsers[uid] = name
+	return name
+}
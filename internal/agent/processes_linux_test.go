@@ -0,0 +1,31 @@
+//go:build linux
+
+package agent
+
+import "testing"
+
+func TestParseProcessStatHandlesParensInName(t *testing.T) {
+	data := []byte("1234 (my (odd) proc) S 1 1234 1234 0 -1 4194560 100 0 0 0 250 50 0 0 20 0 6 0 1000 123456 300 18446744073709551615\n")
+	sample, err := parseProcessStat(data, 4096, 110)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sample.pid != 1234 || sample.name != "my (odd) proc" || sample.threads != 6 {
+		t.Fatalf("sample = %#v", sample)
+	}
+	if sample.cpuSeconds != 3 || sample.start != 1000 || sample.ageSeconds != 100 || sample.rss != 300*4096 {
+		t.Fatalf("counters = %#v", sample)
+	}
+	if _, err := parseProcessStat([]byte("garbage"), 4096, 0); err == nil {
+		t.Fatal("expected invalid stat error")
+	}
+}
+
+func TestParseProcessCmdline(t *testing.T) {
+	if got := parseProcessCmdline([]byte("nginx: master\x00-g\x00daemon off;\x00")); got != "nginx: master -g daemon off;" {
+		t.Fatalf("cmdline = %q", got)
+	}
+	if got := parseProcessCmdline(nil); got != "" {
+		t.Fatalf("empty cmdline = %q", got)
+	}
+}
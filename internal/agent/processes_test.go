@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTopProcessesRanksByCPUAndMemory(t *testing.T) {
+	samples := []processSample{
+		{pid: 1, start: 10, name: "init", threads: 1, rss: 10, cpuSeconds: 50, ageSeconds: 1000},
+		{pid: 2, start: 20, name: "busy", threads: 4, rss: 20, cpuSeconds: 130},
+		{pid: 3, start: 30, name: "fat", threads: 8, rss: 900, cpuSeconds: 5},
+		{pid: 4, start: 99, name: "reused", threads: 1, rss: 5, cpuSeconds: 40, ageSeconds: 200},
+	}
+	prev := map[processKey]float64{{1, 10}: 40, {2, 20}: 100, {3, 30}: 5, {4, 40}: 1}
+
+	top, next := topProcesses(samples, prev, 60, 2)
+	if len(next) != 4 || next[processKey{2, 20}] != 130 {
+		t.Fatalf("next baseline = %#v", next)
+	}
+	if len(top.ByCPU) != 2 || top.ByCPU[0].Name != "busy" || top.ByCPU[0].CPUPercent != 50 {
+		t.Fatalf("by cpu = %#v", top.ByCPU)
+	}
+	if top.ByCPU[1].Name != "reused" || top.ByCPU[1].CPUPercent != 20 {
+		t.Fatalf("reused pid should use lifetime average: %#v", top.ByCPU[1])
+	}
+	if len(top.ByMemory) != 2 || top.ByMemory[0].Name != "fat" || top.ByMemory[0].Threads != 8 {
+		t.Fatalf("by memory = %#v", top.ByMemory)
+	}
+
+	if disabled, _ := topProcesses(samples, prev, 60, 0); disabled != nil {
+		t.Fatalf("disabled snapshot = %#v", disabled)
+	}
+}
+
+func TestTruncateCommandKeepsRunes(t *testing.T) {
+	if got := truncateCommand("nginx -g daemon off;"); got != "nginx -g daemon off;" {
+		t.Fatalf("short command = %q", got)
+	}
+	long := strings.Repeat("a", maxProcessCommandLen-1) + "é" + "tail"
+	got := truncateCommand(long)
+	if !strings.HasSuffix(got, "…") || strings.Contains(got, "�") || len(got) > maxProcessCommandLen+len("…") {
+		t.Fatalf("truncated command = %q", got)
+	}
+}
@@ -0,0 +1,148 @@
+//go:build linux
+
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// hwmonKinds maps hwmon attribute prefixes to the reported kind and the
+// divisor that converts the raw sysfs value to base units.
+var hwmonKinds = []struct {
+	prefix  string
+	kind    string
+	divisor float64
+}{
+	{"temp", "temperature", 1000},
+	{"fan", "fan", 1},
+	{"power", "power", 1000000},
+	{"in", "voltage", 1000},
+}
+
+func (h hostPaths) readSensors() []Sensor {
+	return readSensorsFrom(h.sys)
+}
+
+// readSensorsFrom reads hwmon chips and thermal zones below a sysfs root.
+// Thermal zones already exposed as a hwmon chip of the same name are skipped.
+func readSensorsFrom(sysRoot string) []Sensor {
+	out, chips := readHwmonSensors(filepath.Join(sysRoot, "class", "hwmon"))
+	out = append(out, readThermalZones(filepath.Join(sysRoot, "class", "thermal"), chips)...)
+	return out
+}
+
+func readHwmonSensors(dir string) ([]Sensor, map[string]bool) {
+	chips := map[string]bool{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, chips
+	}
+	var out []Sensor
+	for _, entry := range entries {
+		chipDir := filepath.Join(dir, entry.Name())
+		chip := readTrimmed(filepath.Join(chipDir, "name"))
+		if chip == "" {
+			// Older drivers keep their attributes on the parent device.
+			chipDir = filepath.Join(chipDir, "device")
+			chip = readTrimmed(filepath.Join(chipDir, "name"))
+		}
+		if chip == "" {
+			continue
+		}
+		chips[chip] = true
+		out = append(out, readHwmonChip(chipDir, chip)...)
+	}
+	return out, chips
+}
+
+func readHwmonChip(dir, chip string) []Sensor {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var out []Sensor
+	for _, file := range files {
+		name := file.Name()
+		base, ok := strings.CutSuffix(name, "_input")
+		if !ok {
+			base, ok = strings.CutSuffix(name, "_average")
+			if !ok || hwmonHasInput(dir, base) {
+				continue
+			}
+		}
+		for _, kind := range hwmonKinds {
+			index, ok := strings.CutPrefix(base, kind.prefix)
+			if !ok || !isDigits(index) {
+				continue
+			}
+			raw, ok := readSensorValue(filepath.Join(dir, name))
+			if !ok {
+				break
+			}
+			label := readTrimmed(filepath.Join(dir, base+"_label"))
+			if label == "" {
+				label = base
+			}
+			sensor := Sensor{Chip: chip, Label: label, Kind: kind.kind, Value: round2(raw / kind.divisor)}
+			if crit, ok := readSensorValue(filepath.Join(dir, base+"_crit")); ok && crit > 0 {
+				sensor.Critical = round2(crit / kind.divisor)
+			}
+			out = append(out, sensor)
+			break
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind > out[j].Kind
+		}
+		return out[i].Label < out[j].Label
+	})
+	return out
+}
+
+func hwmonHasInput(dir, base string) bool {
+	_, err := os.Stat(filepath.Join(dir, base+"_input"))
+	return err == nil
+}
+
+func readThermalZones(dir string, hwmonChips map[string]bool) []Sensor {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var out []Sensor
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "thermal_zone") {
+			continue
+		}
+		zoneDir := filepath.Join(dir, entry.Name())
+		zone := readTrimmed(filepath.Join(zoneDir, "type"))
+		if zone == "" || hwmonChips[zone] {
+			continue
+		}
+		raw, ok := readSensorValue(filepath.Join(zoneDir, "temp"))
+		if !ok {
+			continue
+		}
+		out = append(out, Sensor{Chip: "thermal", Label: zone, Kind: "temperature", Value: round2(raw / 1000)})
+	}
+	return out
+}
+
+// readSensorValue reads one integer attribute. Drivers return an error on
+// read for disconnected inputs, which is treated as no reading.
+func readSensorValue(path string) (float64, bool) {
+	value := readTrimmed(path)
+	if value == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return float64(n), true
+}
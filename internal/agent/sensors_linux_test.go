@@ -0,0 +1,89 @@
+//go:build linux
+
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeSysfsTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadSensorsFromFakeSysfs(t *testing.T) {
+	root := t.TempDir()
+	writeSysfsTree(t, root, map[string]string{
+		"class/hwmon/hwmon0/name":              "coretemp\n",
+		"class/hwmon/hwmon0/temp1_input":       "45000\n",
+		"class/hwmon/hwmon0/temp1_label":       "Package id 0\n",
+		"class/hwmon/hwmon0/temp1_crit":        "100000\n",
+		"class/hwmon/hwmon0/temp2_input":       "41500\n",
+		"class/hwmon/hwmon1/device/name":       "nct6775\n",
+		"class/hwmon/hwmon1/device/fan1_input": "1250\n",
+		"class/hwmon/hwmon1/device/fan1_label": "CPU Fan\n",
+		"class/hwmon/hwmon1/device/in0_input":  "1016\n",
+		"class/hwmon/hwmon2/name":              "power_meter\n",
+		"class/hwmon/hwmon2/power1_average":    "142500000\n",
+		"class/hwmon/hwmon3/name":              "acpitz\n",
+		"class/hwmon/hwmon3/temp1_input":       "27800\n",
+		"class/thermal/thermal_zone0/type":     "acpitz\n",
+		"class/thermal/thermal_zone0/temp":     "27800\n",
+		"class/thermal/thermal_zone1/type":     "x86_pkg_temp\n",
+		"class/thermal/thermal_zone1/temp":     "46000\n",
+		"class/thermal/cooling_device0/type":   "Processor\n",
+	})
+
+	sensors := readSensorsFrom(root)
+	find := func(chip, label string) Sensor {
+		t.Helper()
+		for _, sensor := range sensors {
+			if sensor.Chip == chip && sensor.Label == label {
+				return sensor
+			}
+		}
+		t.Fatalf("sensor %s/%s missing from %#v", chip, label, sensors)
+		return Sensor{}
+	}
+
+	if got := find("coretemp", "Package id 0"); got.Kind != "temperature" || got.Value != 45 || got.Critical != 100 {
+		t.Fatalf("package temp = %#v", got)
+	}
+	if got := find("coretemp", "temp2"); got.Value != 41.5 {
+		t.Fatalf("unlabeled temp = %#v", got)
+	}
+	if got := find("nct6775", "CPU Fan"); got.Kind != "fan" || got.Value != 1250 {
+		t.Fatalf("fan = %#v", got)
+	}
+	if got := find("nct6775", "in0"); got.Kind != "voltage" || got.Value != 1.02 {
+		t.Fatalf("voltage = %#v", got)
+	}
+	if got := find("power_meter", "power1"); got.Kind != "power" || got.Value != 142.5 {
+		t.Fatalf("power = %#v", got)
+	}
+	if got := find("thermal", "x86_pkg_temp"); got.Value != 46 {
+		t.Fatalf("thermal zone = %#v", got)
+	}
+	for _, sensor := range sensors {
+		if sensor.Chip == "thermal" && sensor.Label == "acpitz" {
+			t.Fatalf("thermal zone duplicated an hwmon chip: %#v", sensors)
+		}
+	}
+	if len(sensors) != 7 {
+		t.Fatalf("sensors = %#v", sensors)
+	}
+
+	if got := readSensorsFrom(t.TempDir()); len(got) != 0 {
+		t.Fatalf("empty sysfs sensors = %#v", got)
+	}
+}
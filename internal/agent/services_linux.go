@@ -0,0 +1,79 @@
+//go:build linux
+
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const serviceProperties = "Id,LoadState,ActiveState,SubState,NRestarts,MemoryCurrent"
+
+// readServices asks systemctl for the watched units in one call. Units that
+// do not exist still come back with LoadState=not-found.
+func readServices(units []string) ([]Service, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	args := append([]string{"show", "--no-pager", "--property=" + serviceProperties, "--"}, serviceUnitNames(units)...)
+	out, err := exec.CommandContext(ctx, "systemctl", args...).Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseSystemctlShow(out, units), nil
+}
+
+func serviceUnitNames(units []string) []string {
+	out := make([]string, len(units))
+	for i, unit := range units {
+		if !strings.Contains(unit, ".") {
+			unit += ".service"
+		}
+		out[i] = unit
+	}
+	return out
+}
+
+// parseSystemctlShow reads the blank-line separated blocks printed by
+// systemctl show. Blocks come back in the order the units were requested,
+// which is used to report the name as configured.
+func parseSystemctlShow(data []byte, units []string) []Service {
+	var out []Service
+	current := map[string]string{}
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		service := Service{
+			Name:        current["Id"],
+			LoadState:   current["LoadState"],
+			ActiveState: current["ActiveState"],
+			SubState:    current["SubState"],
+		}
+		if i := len(out); i < len(units) {
+			service.Name = units[i]
+		}
+		service.Restarts, _ = strconv.Atoi(current["NRestarts"])
+		service.Memory, _ = strconv.ParseUint(current["MemoryCurrent"], 10, 64)
+		out = append(out, service)
+		current = map[string]string{}
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			flush()
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if ok {
+			current[key] = value
+		}
+	}
+	flush()
+	return out
+}
@@ -0,0 +1,49 @@
+//go:build linux
+
+package agent
+
+import "testing"
+
+func TestParseSystemctlShow(t *testing.T) {
+	data := []byte(`Id=nginx.service
+LoadState=loaded
+ActiveState=active
+SubState=running
+NRestarts=2
+MemoryCurrent=12582912
+
+Id=postgresql.service
+LoadState=loaded
+ActiveState=failed
+SubState=failed
+NRestarts=5
+MemoryCurrent=[not set]
+
+Id=missing.service
+LoadState=not-found
+ActiveState=inactive
+SubState=dead
+NRestarts=0
+MemoryCurrent=[not set]
+`)
+	services := parseSystemctlShow(data, []string{"nginx", "postgresql", "missing"})
+	if len(services) != 3 {
+		t.Fatalf("services = %#v", services)
+	}
+	if got := services[0]; got.Name != "nginx" || got.ActiveState != "active" || got.SubState != "running" || got.Restarts != 2 || got.Memory != 12582912 {
+		t.Fatalf("nginx = %#v", got)
+	}
+	if got := services[1]; got.ActiveState != "failed" || got.Restarts != 5 || got.Memory != 0 {
+		t.Fatalf("postgresql = %#v", got)
+	}
+	if got := services[2]; got.Name != "missing" || got.LoadState != "not-found" {
+		t.Fatalf("missing = %#v", got)
+	}
+}
+
+func TestServiceUnitNamesAddsServiceSuffix(t *testing.T) {
+	got := serviceUnitNames([]string{"nginx", "docker.socket", "backup.timer"})
+	if got[0] != "nginx.service" || got[1] != "docker.socket" || got[2] != "backup.timer" {
+		t.Fatalf("units = %#v", got)
+	}
+}
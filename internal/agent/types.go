@@ -1,31 +1,49 @@
 package agent
 
 type Metrics struct {
-	NodeID         string       `json:"node_id"`
-	Timestamp      int64        `json:"ts"`
-	OS             string       `json:"os"`
-	Arch           string       `json:"arch"`
-	Hostname       string       `json:"hostname"`
-	Kernel         string       `json:"kernel"`
-	OSName         string       `json:"os_name"`
-	Virtualization string       `json:"virtualization"`
-	CPU            CPU          `json:"cpu"`
-	Memory         Memory       `json:"memory"`
-	Swap           Memory       `json:"swap"`
-	Load           Load         `json:"load"`
-	Uptime         uint64       `json:"uptime"`
-	Disks          []Disk       `json:"disks"`
-	Network        Network      `json:"network"`
-	DiskIO         DiskIO       `json:"disk_io"`
-	Conns          *Connections `json:"connections,omitempty"`
-	Processes      int          `json:"processes"`
+	NodeID         string        `json:"node_id"`
+	Timestamp      int64         `json:"ts"`
+	AgentVersion   string        `json:"agent_version,omitempty"`
+	Agent          *AgentStats   `json:"agent,omitempty"`
+	OS             string        `json:"os"`
+	Arch           string        `json:"arch"`
+	Hostname       string        `json:"hostname"`
+	Kernel         string        `json:"kernel"`
+	OSName         string        `json:"os_name"`
+	Virtualization string        `json:"virtualization"`
+	CPU            CPU           `json:"cpu"`
+	Memory         Memory        `json:"memory"`
+	Swap           Memory        `json:"swap"`
+	MemoryDetail   *MemoryDetail `json:"memory_detail,omitempty"`
+	Pressure       *Pressure     `json:"pressure,omitempty"`
+	Load           Load          `json:"load"`
+	Uptime         uint64        `json:"uptime"`
+	Disks          []Disk        `json:"disks"`
+	Network        Network       `json:"network"`
+	DiskIO         DiskIO        `json:"disk_io"`
+	DiskDevices    []DiskDevice  `json:"disk_devices,omitempty"`
+	Conns          *Connections  `json:"connections,omitempty"`
+	Processes      int           `json:"processes"`
+	TopProcesses   *TopProcesses `json:"top_processes,omitempty"`
+	Containers     []Container   `json:"containers,omitempty"`
+	Services       []Service     `json:"services,omitempty"`
+	Sensors        []Sensor      `json:"sensors,omitempty"`
+	RAID           []RAIDArray   `json:"raid,omitempty"`
+	SMART          []SMARTDisk   `json:"smart,omitempty"`
+	Probes         []ProbeResult `json:"probes,omitempty"`
 }
 
 type CPU struct {
-	UsagePercent  float64 `json:"usage_percent"`
-	Cores         int     `json:"cores"`
-	PhysicalCores int     `json:"physical_cores"`
-	ModelName     string  `json:"model_name"`
+	UsagePercent  float64   `json:"usage_percent"`
+	UserPercent   float64   `json:"user_percent"`
+	SystemPercent float64   `json:"system_percent"`
+	IOWaitPercent float64   `json:"iowait_percent"`
+	StealPercent  float64   `json:"steal_percent"`
+	IRQPercent    float64   `json:"irq_percent"`
+	PerCoreUsage  []float64 `json:"per_core_usage,omitempty"`
+	Cores         int       `json:"cores"`
+	PhysicalCores int       `json:"physical_cores"`
+	ModelName     string    `json:"model_name"`
 }
 
 type Memory struct {
@@ -34,6 +52,35 @@ type Memory struct {
 	Free  uint64 `json:"free"`
 }
 
+type MemoryDetail struct {
+	Available      uint64 `json:"available"`
+	Buffers        uint64 `json:"buffers"`
+	Cached         uint64 `json:"cached"`
+	Shared         uint64 `json:"shared"`
+	Dirty          uint64 `json:"dirty"`
+	Slab           uint64 `json:"slab"`
+	HugePagesTotal uint64 `json:"hugepages_total"`
+	HugePagesFree  uint64 `json:"hugepages_free"`
+	HugePageSize   uint64 `json:"hugepage_size"`
+}
+
+// Pressure carries the PSI averages from /proc/pressure. A nil resource means
+// the kernel did not expose it.
+type Pressure struct {
+	CPU    *PressureStat `json:"cpu,omitempty"`
+	Memory *PressureStat `json:"memory,omitempty"`
+	IO     *PressureStat `json:"io,omitempty"`
+}
+
+type PressureStat struct {
+	SomeAvg10  float64 `json:"some_avg10"`
+	SomeAvg60  float64 `json:"some_avg60"`
+	SomeAvg300 float64 `json:"some_avg300"`
+	FullAvg10  float64 `json:"full_avg10"`
+	FullAvg60  float64 `json:"full_avg60"`
+	FullAvg300 float64 `json:"full_avg300"`
+}
+
 type Load struct {
 	Load1  float64 `json:"load1"`
 	Load5  float64 `json:"load5"`
@@ -41,12 +88,18 @@ type Load struct {
 }
 
 type Disk struct {
-	Mount       string  `json:"mount"`
-	FSType      string  `json:"fs_type,omitempty"`
-	Total       uint64  `json:"total"`
-	Used        uint64  `json:"used"`
-	Free        uint64  `json:"free"`
-	UsedPercent float64 `json:"used_percent"`
+	Mount             string  `json:"mount"`
+	Device            string  `json:"device,omitempty"`
+	FSType            string  `json:"fs_type,omitempty"`
+	Options           string  `json:"options,omitempty"`
+	ReadOnly          bool    `json:"read_only,omitempty"`
+	Total             uint64  `json:"total"`
+	Used              uint64  `json:"used"`
+	Free              uint64  `json:"free"`
+	UsedPercent       float64 `json:"used_percent"`
+	InodesTotal       uint64  `json:"inodes_total,omitempty"`
+	InodesUsed        uint64  `json:"inodes_used,omitempty"`
+	InodesUsedPercent float64 `json:"inodes_used_percent,omitempty"`
 }
 
 type Network struct {
@@ -61,7 +114,193 @@ type DiskIO struct {
 	WriteRate uint64 `json:"write_rate"`
 }
 
+type DiskDevice struct {
+	Name        string  `json:"name"`
+	ReadBytes   uint64  `json:"read_bytes"`
+	WriteBytes  uint64  `json:"write_bytes"`
+	ReadRate    uint64  `json:"read_rate"`
+	WriteRate   uint64  `json:"write_rate"`
+	ReadIOPS    float64 `json:"read_iops"`
+	WriteIOPS   float64 `json:"write_iops"`
+	AwaitMs     float64 `json:"await_ms"`
+	QueueDepth  float64 `json:"queue_depth"`
+	UtilPercent float64 `json:"util_percent"`
+}
+
 type Connections struct {
-	TCP int `json:"tcp"`
-	UDP int `json:"udp"`
+	TCP       int            `json:"tcp"`
+	UDP       int            `json:"udp"`
+	TCPStates map[string]int `json:"tcp_states,omitempty"`
+	Listening []ListenPort   `json:"listening,omitempty"`
+	TopPeers  []PeerCount    `json:"top_peers,omitempty"`
+}
+
+type ListenPort struct {
+	Proto   string `json:"proto"`
+	Address string `json:"address"`
+	Port    int    `json:"port"`
+}
+
+type PeerCount struct {
+	Address string `json:"address"`
+	Count   int    `json:"count"`
+}
+
+// TopProcesses is an interval-gated snapshot of the heaviest processes.
+// CPUPercent is relative to one core, like top.
+type TopProcesses struct {
+	ByCPU    []ProcessInfo `json:"by_cpu"`
+	ByMemory []ProcessInfo `json:"by_memory"`
+}
+
+type ProcessInfo struct {
+	PID        int     `json:"pid"`
+	Name       string  `json:"name"`
+	User       string  `json:"user,omitempty"`
+	Command    string  `json:"command,omitempty"`
+	Threads    int     `json:"threads"`
+	CPUPercent float64 `json:"cpu_percent"`
+	RSS        uint64  `json:"rss"`
+}
+
+// Container is one cgroup v2 container scope. Name falls back to the runtime
+// and short ID when no runtime socket could label it.
+type Container struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Runtime     string  `json:"runtime"`
+	Image       string  `json:"image,omitempty"`
+	CPUPercent  float64 `json:"cpu_percent"`
+	MemoryUsed  uint64  `json:"memory_used"`
+	MemoryLimit uint64  `json:"memory_limit,omitempty"`
+	ReadBytes   uint64  `json:"read_bytes"`
+	WriteBytes  uint64  `json:"write_bytes"`
+	ReadRate    uint64  `json:"read_rate"`
+	WriteRate   uint64  `json:"write_rate"`
+	PIDs        int     `json:"pids"`
+}
+
+// Service is the systemd state of one unit listed in SERVICES.
+type Service struct {
+	Name        string `json:"name"`
+	LoadState   string `json:"load_state"`
+	ActiveState string `json:"active_state"`
+	SubState    string `json:"sub_state"`
+	Restarts    int    `json:"restarts"`
+	Memory      uint64 `json:"memory,omitempty"`
+}
+
+// Sensor is one hwmon or thermal zone reading converted to base units:
+// °C for temperature, RPM for fans, W for power and V for voltage.
+type Sensor struct {
+	Chip     string  `json:"chip"`
+	Label    string  `json:"label"`
+	Kind     string  `json:"kind"`
+	Value    float64 `json:"value"`
+	Critical float64 `json:"critical,omitempty"`
+}
+
+// RAIDArray is one Linux software RAID array from /proc/mdstat. SyncAction is
+// empty unless a resync, recovery, check or reshape is running.
+type RAIDArray struct {
+	Name          string   `json:"name"`
+	Level         string   `json:"level"`
+	State         string   `json:"state"`
+	Devices       int      `json:"devices"`
+	ActiveDevices int      `json:"active_devices"`
+	Degraded      bool     `json:"degraded"`
+	Failed        []string `json:"failed,omitempty"`
+	SyncAction    string   `json:"sync_action,omitempty"`
+	SyncPercent   float64  `json:"sync_percent,omitempty"`
+}
+
+// SMARTDisk is the health summary smartctl reports for one drive.
+// WearPercent is the share of rated endurance used, for SSDs that expose it.
+type SMARTDisk struct {
+	Device             string  `json:"device"`
+	Model              string  `json:"model,omitempty"`
+	Serial             string  `json:"serial,omitempty"`
+	Passed             bool    `json:"passed"`
+	Temperature        float64 `json:"temperature,omitempty"`
+	PowerOnHours       uint64  `json:"power_on_hours,omitempty"`
+	ReallocatedSectors uint64  `json:"reallocated_sectors"`
+	PendingSectors     uint64  `json:"pending_sectors"`
+	MediaErrors        uint64  `json:"media_errors,omitempty"`
+	WearPercent        float64 `json:"wear_percent,omitempty"`
+}
+
+// ProbeResult is the outcome of one round of a synthetic check. LatencyMs is
+// the mean round trip for icmp, the mean connect time for tcp and the total
+// request time for http. WarnMs echoes the configured latency threshold so
+// the server can flag slow targets.
+type ProbeResult struct {
+	Name        string  `json:"name"`
+	Type        string  `json:"type"`
+	Target      string  `json:"target"`
+	Timestamp   int64   `json:"ts"`
+	Sent        int     `json:"sent"`
+	Lost        int     `json:"lost"`
+	LossPercent float64 `json:"loss_percent"`
+	LatencyMs   float64 `json:"latency_ms"`
+	MinMs       float64 `json:"min_ms,omitempty"`
+	MaxMs       float64 `json:"max_ms,omitempty"`
+	JitterMs    float64 `json:"jitter_ms,omitempty"`
+	StatusCode  int     `json:"status_code,omitempty"`
+	DNSMs       float64 `json:"dns_ms,omitempty"`
+	ConnectMs   float64 `json:"connect_ms,omitempty"`
+	TLSMs       float64 `json:"tls_ms,omitempty"`
+	FirstByteMs float64 `json:"first_byte_ms,omitempty"`
+	WarnMs      float64 `json:"warn_ms,omitempty"`
+	Error       string  `json:"error,omitempty"`
+}
+
+// AgentStats is the agent's own health. CollectMs is the collect that
+// produced the report and SendMs the previous send; error counts run since
+// the agent started. There is no buffer size: a report that fails to send is
+// dropped, not queued for the next one.
+type AgentStats struct {
+	UptimeSec     int64   `json:"uptime_sec"`
+	CollectMs     float64 `json:"collect_ms"`
+	SendMs        float64 `json:"send_ms"`
+	CollectErrors int     `json:"collect_errors,omitempty"`
+	ReportErrors  int     `json:"report_errors,omitempty"`
+	LastError     string  `json:"last_error,omitempty"`
+	LastErrorAt   int64   `json:"last_error_at,omitempty"`
+	MemoryBytes   uint64  `json:"memory_bytes"`
+	Goroutines    int     `json:"goroutines"`
+}
+
+// RemoteConfig is the settings the server hands an agent from
+// /api/agent/config. Version changes whenever the content does, so the agent
+// only re-applies it on change. NodeID is the node's current ID, which
+// differs from the agent's after an admin renamed the node. Settings
+// overrides config.env keys.
+type RemoteConfig struct {
+	Version  string            `json:"version"`
+	NodeID   string            `json:"node_id,omitempty"`
+	Probes   []RemoteProbe     `json:"probes"`
+	Settings map[string]string `json:"settings,omitempty"`
+}
+
+// RemoteProbe is a probe target defined in the admin console.
+type RemoteProbe struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Target string `json:"target"`
+	WarnMs int    `json:"warn_ms,omitempty"`
+}
+
+// ReportResponse is the server's answer to a report. Update is set when the
+// server embeds a newer agent build for the node's platform.
+type ReportResponse struct {
+	OK     string       `json:"ok"`
+	Update *UpdateOffer `json:"update,omitempty"`
+}
+
+// UpdateOffer points at an agent binary on the server. URL is relative to
+// the server address.
+type UpdateOffer struct {
+	Version string `json:"version"`
+	URL     string `json:"url"`
+	SHA256  string `json:"sha256"`
 }
@@ -0,0 +1,36 @@
+// Package buildinfo holds the release version shared by the agent and the
+// server. A server advertises the agent binaries it embeds under this
+// version, so both are built from the same tree.
+package buildinfo
+
+import (
+	"strconv"
+	"strings"
+)
+
+const Version = "0.1.0"
+
+// Newer reports whether dotted version a is greater than b. Missing and
+// non-numeric parts compare as zero. An empty b is never older: agents that
+// do not report a version predate self-update.
+func Newer(a, b string) bool {
+	if b == "" {
+		return false
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		x, y := versionPart(as, i), versionPart(bs, i)
+		if x != y {
+			return x > y
+		}
+	}
+	return false
+}
+
+func versionPart(parts []string, i int) int {
+	if i >= len(parts) {
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.TrimPrefix(parts[i], "v"))
+	return n
+}
@@ -0,0 +1,24 @@
+package buildinfo
+
+import "testing"
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "0.2.0", b: "0.1.0", want: true},
+		{a: "0.10.0", b: "0.9.3", want: true},
+		{a: "1.0", b: "0.9.9", want: true},
+		{a: "0.1.0", b: "0.1.0"},
+		{a: "0.1", b: "0.1.0"},
+		{a: "0.1.0", b: "0.2.0"},
+		{a: "0.2.0", b: ""},
+	}
+
+	for _, tt := range tests {
+		if got := Newer(tt.a, tt.b); got != tt.want {
+			t.Fatalf("Newer(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -4,12 +4,17 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"net"
 	"net/url"
 	"os"
+	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"vps-agent/internal/release"
 )
 
 type Config struct {
@@ -19,9 +24,36 @@ type Config struct {
 	BasicInterval      time.Duration
 	DiskInterval       time.Duration
 	ConnectionInterval time.Duration
+	ConnectionTopPeers int
+	ProcessInterval    time.Duration
+	ProcessTop         int
+	Containers         []string
+	ContainerInterval  time.Duration
+	Services           []string
+	ServiceInterval    time.Duration
+	SensorInterval     time.Duration
+	DiskHealth         bool
+	DiskHealthInterval time.Duration
 	Mounts             []string
 	NetworkExclude     []string
 	DiskExcludeFS      []string
+	HostProc           string
+	HostSys            string
+	HostRoot           string
+	Probes             []Probe
+	ProbeInterval      time.Duration
+	AutoUpdate         bool
+	ReleaseKey         string
+}
+
+// Probe is a synthetic check run by the agent. Type is icmp, tcp or http;
+// Target is a host for icmp, host:port for tcp and a URL for http. Warn is
+// the latency at which the server flags the probe, zero for none.
+type Probe struct {
+	Name   string
+	Type   string
+	Target string
+	Warn   time.Duration
 }
 
 func Default() Config {
@@ -34,9 +66,19 @@ func Default() Config {
 		BasicInterval:      2 * time.Second,
 		DiskInterval:       30 * time.Second,
 		ConnectionInterval: 60 * time.Second,
+		ProcessInterval:    60 * time.Second,
+		ContainerInterval:  10 * time.Second,
+		ServiceInterval:    30 * time.Second,
+		SensorInterval:     30 * time.Second,
+		DiskHealthInterval: 10 * time.Minute,
 		Mounts:             []string{"auto"},
 		NetworkExclude:     []string{"lo", "docker*", "veth*", "br-*"},
 		DiskExcludeFS:      []string{"tmpfs", "devtmpfs", "overlay", "squashfs", "proc", "sysfs", "cgroup", "cgroup2"},
+		HostProc:           "/proc",
+		HostSys:            "/sys",
+		HostRoot:           "/",
+		ProbeInterval:      60 * time.Second,
+		AutoUpdate:         true,
 	}
 }
 
@@ -57,6 +99,7 @@ func Load(path string) (Config, error) {
 
 	scanner := bufio.NewScanner(f)
 	lineNo := 0
+	nodeIDSet := false
 	for scanner.Scan() {
 		lineNo++
 		line := strings.TrimSpace(scanner.Text())
@@ -72,8 +115,21 @@ func Load(path string) (Config, error) {
 		if err := apply(&cfg, key, value); err != nil {
 			return Config{}, fmt.Errorf("invalid config line %d: %w", lineNo, err)
 		}
+		nodeIDSet = nodeIDSet || strings.EqualFold(key, "NODE_ID")
+	}
+	if err := scanner.Err(); err != nil {
+		return Config{}, err
+	}
+	// In a container the default node ID would be the container's hostname,
+	// so prefer the host's when its root filesystem is mounted.
+	if !nodeIDSet && cfg.HostRoot != "/" {
+		if data, err := os.ReadFile(filepath.Join(cfg.HostRoot, "etc", "hostname")); err == nil {
+			if host := strings.TrimSpace(string(data)); host != "" {
+				cfg.NodeID = host
+			}
+		}
 	}
-	return cfg, scanner.Err()
+	return cfg, nil
 }
 
 func (c Config) Validate() error {
@@ -102,6 +158,78 @@ func (c Config) Validate() error {
 	return nil
 }
 
+// WithOverrides returns a copy of c with settings pushed from the server
+// applied on top, in the config.env key format. SERVER, TOKEN and NODE_ID
+// identify the agent to the server, RELEASE_KEY decides which builds it
+// trusts and HOST_PROC, HOST_SYS and HOST_ROOT describe how it is mounted,
+// so they always come from the local file. AUTO_UPDATE can only be turned
+// off remotely.
+func (c Config) WithOverrides(values map[string]string) (Config, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	autoUpdate := c.AutoUpdate
+	for _, key := range keys {
+		switch strings.ToUpper(strings.TrimSpace(key)) {
+		case "SERVER", "TOKEN", "NODE_ID", "RELEASE_KEY", "HOST_PROC", "HOST_SYS", "HOST_ROOT":
+			return Config{}, fmt.Errorf("key %q cannot be set remotely", key)
+		}
+		if err := apply(&c, strings.TrimSpace(key), trimValue(values[key])); err != nil {
+			return Config{}, fmt.Errorf("invalid remote %s: %w", key, err)
+		}
+	}
+	c.AutoUpdate = c.AutoUpdate && autoUpdate
+	return c, nil
+}
+
+// SetValue rewrites key in the config file at path and leaves every other
+// line as it was. The agent uses it to keep a NODE_ID the server renamed.
+func SetValue(path, key, value string) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("invalid value for %s", key)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	newline := "\n"
+	if strings.Contains(string(data), "\r\n") {
+		newline = "\r\n"
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\r\n"), newline)
+	out := make([]string, 0, len(lines)+1)
+	written := false
+	for _, line := range lines {
+		name, _, ok := strings.Cut(line, "=")
+		if ok && strings.EqualFold(strings.TrimPrefix(strings.TrimSpace(name), "\ufeff"), key) {
+			if written {
+				continue
+			}
+			line = key + "=" + value
+			written = true
+		}
+		out = append(out, line)
+	}
+	if !written {
+		out = append(out, key+"="+value)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(out, newline)+newline), info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
 func validNodeID(value string) bool {
 	value = strings.TrimSpace(value)
 	if value == "" || len([]rune(value)) > 96 {
@@ -136,18 +264,169 @@ func apply(c *Config, key, value string) error {
 			return err
 		}
 		c.ConnectionInterval = d
+	case "CONNECTION_TOP_PEERS":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid CONNECTION_TOP_PEERS %q", value)
+		}
+		c.ConnectionTopPeers = n
+	case "PROCESS_INTERVAL":
+		d, err := parseDuration(value)
+		if err != nil {
+			return err
+		}
+		c.ProcessInterval = d
+	case "PROCESS_TOP":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid PROCESS_TOP %q", value)
+		}
+		c.ProcessTop = n
+	case "CONTAINERS":
+		c.Containers = nil
+		for _, item := range splitList(value) {
+			if !strings.EqualFold(item, "off") {
+				c.Containers = append(c.Containers, item)
+			}
+		}
+	case "CONTAINER_INTERVAL":
+		d, err := parseDuration(value)
+		if err != nil {
+			return err
+		}
+		c.ContainerInterval = d
+	case "SERVICES":
+		c.Services = splitList(value)
+	case "SERVICE_INTERVAL":
+		d, err := parseDuration(value)
+		if err != nil {
+			return err
+		}
+		c.ServiceInterval = d
+	case "SENSOR_INTERVAL":
+		d, err := parseDuration(value)
+		if err != nil {
+			return err
+		}
+		c.SensorInterval = d
+	case "DISK_HEALTH":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid DISK_HEALTH %q", value)
+		}
+		c.DiskHealth = enabled
+	case "DISK_HEALTH_INTERVAL":
+		d, err := parseDuration(value)
+		if err != nil {
+			return err
+		}
+		c.DiskHealthInterval = d
 	case "MOUNTS":
 		c.Mounts = splitList(value)
 	case "NETWORK_EXCLUDE":
 		c.NetworkExclude = splitList(value)
 	case "DISK_EXCLUDE_FS":
 		c.DiskExcludeFS = splitList(value)
+	case "HOST_PROC":
+		if !strings.HasPrefix(value, "/") {
+			return fmt.Errorf("invalid HOST_PROC %q", value)
+		}
+		c.HostProc = value
+	case "HOST_SYS":
+		if !strings.HasPrefix(value, "/") {
+			return fmt.Errorf("invalid HOST_SYS %q", value)
+		}
+		c.HostSys = value
+	case "HOST_ROOT":
+		if !strings.HasPrefix(value, "/") {
+			return fmt.Errorf("invalid HOST_ROOT %q", value)
+		}
+		c.HostRoot = value
+	case "PROBES":
+		c.Probes = nil
+		for _, item := range splitList(value) {
+			probe, err := parseProbe(item)
+			if err != nil {
+				return err
+			}
+			c.Probes = append(c.Probes, probe)
+		}
+	case "PROBE_INTERVAL":
+		d, err := parseDuration(value)
+		if err != nil {
+			return err
+		}
+		c.ProbeInterval = d
+	case "AUTO_UPDATE":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid AUTO_UPDATE %q", value)
+		}
+		c.AutoUpdate = enabled
+	case "RELEASE_KEY":
+		if _, err := release.ParsePublicKey(value); err != nil {
+			return fmt.Errorf("invalid RELEASE_KEY %q", value)
+		}
+		c.ReleaseKey = value
 	default:
 		return fmt.Errorf("unknown key %q", key)
 	}
 	return nil
 }
 
+// parseProbe reads one PROBES entry: [name=]target[|warn], where target is
+// icmp:host, tcp:host:port or an http(s) URL, for example
+// "CT=icmp:202.96.209.133|300ms" or "https://origin.example.com/health".
+func parseProbe(value string) (Probe, error) {
+	entry := value
+	var probe Probe
+	if name, rest, ok := strings.Cut(entry, "="); ok && !strings.ContainsAny(name, ":/") {
+		probe.Name, entry = strings.TrimSpace(name), strings.TrimSpace(rest)
+	}
+	if target, warn, ok := strings.Cut(entry, "|"); ok {
+		d, err := parseDuration(strings.TrimSpace(warn))
+		if err != nil || d <= 0 {
+			return Probe{}, fmt.Errorf("invalid PROBES entry %q", value)
+		}
+		probe.Warn, entry = d, strings.TrimSpace(target)
+	}
+	switch {
+	case strings.HasPrefix(entry, "icmp:"):
+		probe.Type, probe.Target = "icmp", strings.TrimPrefix(entry, "icmp:")
+	case strings.HasPrefix(entry, "tcp:"):
+		probe.Type, probe.Target = "tcp", strings.TrimPrefix(entry, "tcp:")
+	default:
+		probe.Type, probe.Target = "http", entry
+	}
+	if err := probe.Validate(); err != nil {
+		return Probe{}, fmt.Errorf("invalid PROBES entry %q", value)
+	}
+	return probe, nil
+}
+
+// Validate checks that the target suits the probe type. It is shared with
+// the server, which hands out probe targets defined in the admin console.
+func (p Probe) Validate() error {
+	if p.Target == "" || strings.ContainsAny(p.Target, " \t") || p.Warn < 0 {
+		return fmt.Errorf("invalid probe %q", p.Target)
+	}
+	switch p.Type {
+	case "icmp":
+	case "tcp":
+		if _, _, err := net.SplitHostPort(p.Target); err != nil {
+			return fmt.Errorf("invalid tcp probe target %q", p.Target)
+		}
+	case "http":
+		u, err := url.Parse(p.Target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid http probe target %q", p.Target)
+		}
+	default:
+		return fmt.Errorf("invalid probe type %q", p.Type)
+	}
+	return nil
+}
+
 func parseDuration(value string) (time.Duration, error) {
 	if _, err := strconv.Atoi(value); err == nil {
 		value += "s"
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -16,9 +17,25 @@ func TestLoadParsesConfigFile(t *testing.T) {
 		"BASIC_INTERVAL=5\n" +
 		"DISK_INTERVAL=45s\n" +
 		"CONNECTION_INTERVAL=2m\n" +
+		"CONNECTION_TOP_PEERS=5\n" +
+		"PROCESS_INTERVAL=90s\n" +
+		"PROCESS_TOP=8\n" +
+		"CONTAINERS=web*, db\n" +
+		"CONTAINER_INTERVAL=20s\n" +
+		"SERVICES=nginx, postgresql\n" +
+		"SERVICE_INTERVAL=15s\n" +
+		"SENSOR_INTERVAL=1m\n" +
+		"DISK_HEALTH=true\n" +
+		"DISK_HEALTH_INTERVAL=30m\n" +
 		"MOUNTS=/,/data\n" +
 		"NETWORK_EXCLUDE=lo, docker*, veth*\n" +
-		"DISK_EXCLUDE_FS=tmpfs, overlay\n"
+		"DISK_EXCLUDE_FS=tmpfs, overlay\n" +
+		"HOST_PROC=/host/proc\n" +
+		"HOST_SYS=/host/sys\n" +
+		"PROBES=CT=icmp:202.96.209.133|300ms, tcp:origin.example.com:443, API=https://api.example.com/health?a=b\n" +
+		"PROBE_INTERVAL=30s\n" +
+		"AUTO_UPDATE=false\n" +
+		"RELEASE_KEY=11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo=\n"
 	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
 		t.Fatal(err)
 	}
@@ -46,6 +63,24 @@ func TestLoadParsesConfigFile(t *testing.T) {
 	if cfg.ConnectionInterval != 2*time.Minute {
 		t.Fatalf("connection interval = %s", cfg.ConnectionInterval)
 	}
+	if cfg.ConnectionTopPeers != 5 {
+		t.Fatalf("connection top peers = %d", cfg.ConnectionTopPeers)
+	}
+	if cfg.ProcessInterval != 90*time.Second || cfg.ProcessTop != 8 {
+		t.Fatalf("process interval = %s top = %d", cfg.ProcessInterval, cfg.ProcessTop)
+	}
+	if got := strings.Join(cfg.Containers, ","); got != "web*,db" || cfg.ContainerInterval != 20*time.Second {
+		t.Fatalf("containers = %q interval = %s", got, cfg.ContainerInterval)
+	}
+	if got := strings.Join(cfg.Services, ","); got != "nginx,postgresql" || cfg.ServiceInterval != 15*time.Second {
+		t.Fatalf("services = %q interval = %s", got, cfg.ServiceInterval)
+	}
+	if cfg.SensorInterval != time.Minute {
+		t.Fatalf("sensor interval = %s", cfg.SensorInterval)
+	}
+	if !cfg.DiskHealth || cfg.DiskHealthInterval != 30*time.Minute {
+		t.Fatalf("disk health = %v interval = %s", cfg.DiskHealth, cfg.DiskHealthInterval)
+	}
 	if got := strings.Join(cfg.Mounts, ","); got != "/,/data" {
 		t.Fatalf("mounts = %q", got)
 	}
@@ -55,6 +90,52 @@ func TestLoadParsesConfigFile(t *testing.T) {
 	if got := strings.Join(cfg.DiskExcludeFS, ","); got != "tmpfs,overlay" {
 		t.Fatalf("disk exclude fs = %q", got)
 	}
+	if cfg.HostProc != "/host/proc" || cfg.HostSys != "/host/sys" {
+		t.Fatalf("host proc = %q sys = %q", cfg.HostProc, cfg.HostSys)
+	}
+	wantProbes := []Probe{
+		{Name: "CT", Type: "icmp", Target: "202.96.209.133", Warn: 300 * time.Millisecond},
+		{Type: "tcp", Target: "origin.example.com:443"},
+		{Name: "API", Type: "http", Target: "https://api.example.com/health?a=b"},
+	}
+	if cfg.AutoUpdate {
+		t.Fatal("auto update should be disabled")
+	}
+	if cfg.ReleaseKey != "11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo=" {
+		t.Fatalf("release key = %q", cfg.ReleaseKey)
+	}
+	if !reflect.DeepEqual(cfg.Probes, wantProbes) || cfg.ProbeInterval != 30*time.Second {
+		t.Fatalf("probes = %#v interval = %s", cfg.Probes, cfg.ProbeInterval)
+	}
+}
+
+func TestLoadDefaultsNodeIDToHostHostname(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "host")
+	if err := os.MkdirAll(filepath.Join(root, "etc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "etc", "hostname"), []byte("edge-host-01\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "config.env")
+	if err := os.WriteFile(path, []byte("HOST_ROOT="+root+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.NodeID != "edge-host-01" || cfg.HostRoot != root {
+		t.Fatalf("node id = %q host root = %q", cfg.NodeID, cfg.HostRoot)
+	}
+
+	if err := os.WriteFile(path, []byte("HOST_ROOT="+root+"\nNODE_ID=custom\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err = Load(path); err != nil || cfg.NodeID != "custom" {
+		t.Fatalf("explicit node id = %q err = %v", cfg.NodeID, err)
+	}
 }
 
 func TestLoadRejectsInvalidConfig(t *testing.T) {
@@ -65,6 +146,16 @@ func TestLoadRejectsInvalidConfig(t *testing.T) {
 		{name: "malformed line", content: "SERVER\n"},
 		{name: "unknown key", content: "UNKNOWN=value\n"},
 		{name: "bad duration", content: "BASIC_INTERVAL=soon\n"},
+		{name: "negative top peers", content: "CONNECTION_TOP_PEERS=-1\n"},
+		{name: "bad process top", content: "PROCESS_TOP=many\n"},
+		{name: "bad disk health", content: "DISK_HEALTH=maybe\n"},
+		{name: "relative host proc", content: "HOST_PROC=host/proc\n"},
+		{name: "relative host root", content: "HOST_ROOT=host\n"},
+		{name: "unknown probe type", content: "PROBES=udp:1.1.1.1:53\n"},
+		{name: "tcp probe without port", content: "PROBES=tcp:1.1.1.1\n"},
+		{name: "bad probe warn", content: "PROBES=icmp:1.1.1.1|soon\n"},
+		{name: "bad auto update", content: "AUTO_UPDATE=sometimes\n"},
+		{name: "short release key", content: "RELEASE_KEY=AAAA\n"},
 	}
 
 	for _, tt := range tests {
@@ -116,3 +207,103 @@ func TestValidateRejectsUnsafeConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestProbeValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		probe Probe
+		ok    bool
+	}{
+		{name: "icmp", probe: Probe{Name: "CT", Type: "icmp", Target: "202.96.209.133"}, ok: true},
+		{name: "tcp", probe: Probe{Name: "origin", Type: "tcp", Target: "[2001:db8::1]:443", Warn: time.Second}, ok: true},
+		{name: "http", probe: Probe{Name: "api", Type: "http", Target: "https://api.example.com/health"}, ok: true},
+		{name: "without name", probe: Probe{Type: "icmp", Target: "1.1.1.1"}, ok: true},
+		{name: "missing target", probe: Probe{Name: "CT", Type: "icmp"}},
+		{name: "unknown type", probe: Probe{Name: "dns", Type: "udp", Target: "1.1.1.1:53"}},
+		{name: "tcp without port", probe: Probe{Name: "origin", Type: "tcp", Target: "1.1.1.1"}},
+		{name: "http without scheme", probe: Probe{Name: "api", Type: "http", Target: "api.example.com"}},
+		{name: "target with space", probe: Probe{Name: "bad", Type: "icmp", Target: "1.1.1.1 -f"}},
+		{name: "negative warn", probe: Probe{Name: "CT", Type: "icmp", Target: "1.1.1.1", Warn: -time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.probe.Validate(); (err == nil) != tt.ok {
+				t.Fatalf("Validate() = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestWithOverridesAppliesRemoteSettings(t *testing.T) {
+	local := Default()
+	local.Server, local.Token, local.NodeID = "https://monitor.example.com", "token", "CN-test-001"
+
+	cfg, err := local.WithOverrides(map[string]string{
+		"BASIC_INTERVAL":  "5",
+		"MOUNTS":          "/, /data",
+		"NETWORK_EXCLUDE": "lo,tun*",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BasicInterval != 5*time.Second || !reflect.DeepEqual(cfg.Mounts, []string{"/", "/data"}) || !reflect.DeepEqual(cfg.NetworkExclude, []string{"lo", "tun*"}) {
+		t.Fatalf("overridden config = %#v", cfg)
+	}
+	if cfg.Server != local.Server || cfg.NodeID != local.NodeID || local.BasicInterval != 2*time.Second {
+		t.Fatalf("identity or base config changed: %#v", cfg)
+	}
+
+	local.AutoUpdate = false
+	if cfg, err := local.WithOverrides(map[string]string{"AUTO_UPDATE": "true"}); err != nil || cfg.AutoUpdate {
+		t.Fatalf("remote AUTO_UPDATE=true enabled updates: %v", err)
+	}
+	local.AutoUpdate = true
+	if cfg, err := local.WithOverrides(map[string]string{"AUTO_UPDATE": "false"}); err != nil || cfg.AutoUpdate {
+		t.Fatalf("remote AUTO_UPDATE=false did not disable updates: %v", err)
+	}
+
+	for _, values := range []map[string]string{
+		{"SERVER": "https://evil.example.com"},
+		{"token": "stolen"},
+		{"RELEASE_KEY": "11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="},
+		{"HOST_PROC": "/host/proc"},
+		{"host_root": "/host"},
+		{"DISK_INTERVAL": "soon"},
+		{"UNKNOWN": "1"},
+	} {
+		if _, err := local.WithOverrides(values); err == nil {
+			t.Fatalf("expected error for %v", values)
+		}
+	}
+}
+
+func TestSetValueRewritesOneKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.env")
+	if err := os.WriteFile(path, []byte("SERVER=https://monitor.example.com\r\n# old name\r\nNODE_ID=CN-old\r\nTOKEN=token\r\nnode_id=CN-dup\r\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValue(path, "NODE_ID", "CN-new"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "SERVER=https://monitor.example.com\r\n# old name\r\nNODE_ID=CN-new\r\nTOKEN=token\r\n"; string(data) != want {
+		t.Fatalf("config = %q, want %q", data, want)
+	}
+	if err := SetValue(path, "BASIC_INTERVAL", "5s"); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.NodeID != "CN-new" || cfg.BasicInterval != 5*time.Second {
+		t.Fatalf("loaded config = %#v", cfg)
+	}
+	if err := SetValue(path, "NODE_ID", "CN-a\nTOKEN=stolen"); err == nil {
+		t.Fatal("expected error for a value with a newline")
+	}
+}
@@ -0,0 +1,159 @@
+// Package release describes the agent binaries a server hands out. A release
+// build writes manifest.json next to the binaries with the SHA-256 of each
+// one and signs it with an ed25519 key kept off the server; agents and the
+// install scripts check the signature against a public key pinned at install
+// time, so a server that was tampered with cannot push its own builds.
+package release
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	ManifestName  = "manifest.json"
+	SignatureName = "manifest.json.sig"
+	PublicKeyName = "release.pub"
+)
+
+type Manifest struct {
+	Version string `json:"version"`
+	Files   []File `json:"files"`
+}
+
+type File struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// Lookup returns the entry for a file name.
+func (m Manifest) Lookup(name string) (File, bool) {
+	for _, file := range m.Files {
+		if file.Name == name {
+			return file, true
+		}
+	}
+	return File{}, false
+}
+
+// Check reports whether data is the file listed under name.
+func (m Manifest) Check(name string, data []byte) error {
+	file, ok := m.Lookup(name)
+	if !ok {
+		return fmt.Errorf("%s is not in the release manifest", name)
+	}
+	sum := sha256.Sum256(data)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), file.SHA256) || int64(len(data)) != file.Size {
+		return fmt.Errorf("%s does not match the release manifest", name)
+	}
+	return nil
+}
+
+// Build lists the vps-agent-* binaries in dir.
+func Build(dir, version string) (Manifest, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "vps-agent-*"))
+	if err != nil {
+		return Manifest{}, err
+	}
+	sort.Strings(paths)
+	manifest := Manifest{Version: version, Files: []File{}}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Manifest{}, err
+		}
+		sum := sha256.Sum256(data)
+		manifest.Files = append(manifest.Files, File{
+			Name:   filepath.Base(path),
+			SHA256: hex.EncodeToString(sum[:]),
+			Size:   int64(len(data)),
+		})
+	}
+	if len(manifest.Files) == 0 {
+		return Manifest{}, fmt.Errorf("no vps-agent binaries in %s", dir)
+	}
+	return manifest, nil
+}
+
+// WriteSigned builds the manifest for dir and writes it there together with
+// its signature and the public key that verifies it.
+func WriteSigned(dir, version string, key ed25519.PrivateKey) error {
+	manifest, err := Build(dir, version)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	pub := base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+	files := map[string][]byte{
+		ManifestName:  data,
+		SignatureName: []byte(Sign(key, data) + "\n"),
+		PublicKeyName: []byte(pub + "\n"),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sign returns the base64 signature of the exact manifest bytes.
+func Sign(key ed25519.PrivateKey, manifest []byte) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, manifest))
+}
+
+// Verify checks the base64 signature of a manifest and decodes it.
+func Verify(key ed25519.PublicKey, manifest []byte, signature string) (Manifest, error) {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+	if err != nil || !ed25519.Verify(key, manifest, sig) {
+		return Manifest{}, errors.New("release manifest signature is not valid")
+	}
+	var out Manifest
+	if err := json.Unmarshal(manifest, &out); err != nil {
+		return Manifest{}, err
+	}
+	return out, nil
+}
+
+// ParsePublicKey decodes a base64 ed25519 public key as written to
+// release.pub and to RELEASE_KEY in config.env.
+func ParsePublicKey(value string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, errors.New("release key must be a base64 ed25519 public key")
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// ParsePrivateKey decodes a base64 ed25519 seed as written by GenerateKey.
+func ParsePrivateKey(value string) (ed25519.PrivateKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil || len(raw) != ed25519.SeedSize {
+		return nil, errors.New("signing key must be a base64 ed25519 seed")
+	}
+	return ed25519.NewKeyFromSeed(raw), nil
+}
+
+// GenerateKey returns a new signing key as a base64 seed and its public key
+// in the form ParsePublicKey reads.
+func GenerateKey() (private, public string, err error) {
+	pub, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(key.Seed()), base64.StdEncoding.EncodeToString(pub), nil
+}
@@ -0,0 +1,89 @@
+package release
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteSignedManifestVerifies(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"vps-agent-linux-amd64":       "linux build",
+		"vps-agent-windows-amd64.exe": "windows build",
+		".gitkeep":                    "",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	private, public, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParsePrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteSigned(dir, "0.2.0", key); err != nil {
+		t.Fatal(err)
+	}
+
+	data := readFile(t, dir, ManifestName)
+	signature := readFile(t, dir, SignatureName)
+	if got := readFile(t, dir, PublicKeyName); got != public+"\n" {
+		t.Fatalf("release.pub = %q, want %q", got, public)
+	}
+	pub, err := ParsePublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := Verify(pub, []byte(data), signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Version != "0.2.0" || len(manifest.Files) != 2 {
+		t.Fatalf("manifest = %+v", manifest)
+	}
+	if err := manifest.Check("vps-agent-linux-amd64", []byte("linux build")); err != nil {
+		t.Fatal(err)
+	}
+	if err := manifest.Check("vps-agent-linux-amd64", []byte("linux buile")); err == nil {
+		t.Fatal("expected mismatch for altered binary")
+	}
+	if err := manifest.Check("vps-agent-linux-arm64", []byte("linux build")); err == nil {
+		t.Fatal("expected error for unlisted binary")
+	}
+
+	if _, err := Verify(pub, []byte(data+" "), signature); err == nil {
+		t.Fatal("expected signature error for altered manifest")
+	}
+	_, other, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ParsePublicKey(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(otherKey, []byte(data), signature); err == nil {
+		t.Fatal("expected signature error for another key")
+	}
+}
+
+func TestParsePublicKeyRejectsMalformedKeys(t *testing.T) {
+	for _, value := range []string{"", "not base64!", "AAAA"} {
+		if _, err := ParsePublicKey(value); err == nil {
+			t.Fatalf("ParsePublicKey(%q) succeeded", value)
+		}
+	}
+}
+
+func readFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
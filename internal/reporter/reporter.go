@@ -33,16 +33,26 @@ func New(cfg config.Config) *Reporter {
 	}
 }
 
-func (r *Reporter) Send(ctx context.Context, metrics agent.Metrics) error {
+// SetNodeID changes the node ID sent with later requests, after the server
+// renamed the node. Like the rest of the reporter it is not safe to call
+// while a request is in flight.
+func (r *Reporter) SetNodeID(nodeID string) {
+	r.cfg.NodeID = nodeID
+}
+
+// maxDownloadSize bounds an agent binary fetched for a self-update.
+const maxDownloadSize = 64 << 20
+
+func (r *Reporter) Send(ctx context.Context, metrics agent.Metrics) (agent.ReportResponse, error) {
 	body, err := json.Marshal(metrics)
 	if err != nil {
-		return err
+		return agent.ReportResponse{}, err
 	}
 
 	url := strings.TrimRight(r.cfg.Server, "/") + "/api/agent/report"
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return err
+		return agent.ReportResponse{}, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+r.cfg.Token)
@@ -50,14 +60,79 @@ func (r *Reporter) Send(ctx context.Context, metrics agent.Metrics) error {
 
 	resp, err := r.client.Do(req)
 	if err != nil {
-		return err
+		return agent.ReportResponse{}, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return responseError(resp)
+		return agent.ReportResponse{}, responseError(resp)
+	}
+	// Older servers answer with an empty body or a bare {"ok":"true"}; only a
+	// well-formed update offer matters here.
+	var out agent.ReportResponse
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out)
+	return out, nil
+}
+
+// Download fetches a file the server advertised, such as an agent binary.
+// The client timeout is too short for a large binary on a slow link, so the
+// caller's context bounds the transfer instead.
+func (r *Reporter) Download(ctx context.Context, path string) ([]byte, error) {
+	url := strings.TrimRight(r.cfg.Server, "/") + "/" + strings.TrimLeft(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Transport: r.client.Transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDownloadSize {
+		return nil, fmt.Errorf("download exceeds %d bytes", maxDownloadSize)
+	}
+	return data, nil
+}
+
+// FetchConfig asks the server for the agent's settings. It returns false when
+// the server still holds version, or predates the endpoint and answers 404.
+func (r *Reporter) FetchConfig(ctx context.Context, version string) (agent.RemoteConfig, bool, error) {
+	url := strings.TrimRight(r.cfg.Server, "/") + "/api/agent/config"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return agent.RemoteConfig{}, false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+r.cfg.Token)
+	req.Header.Set("X-Node-ID", r.cfg.NodeID)
+	if version != "" {
+		req.Header.Set("If-None-Match", `"`+version+`"`)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return agent.RemoteConfig{}, false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified || resp.StatusCode == http.StatusNotFound:
+		return agent.RemoteConfig{}, false, nil
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return agent.RemoteConfig{}, false, responseError(resp)
+	}
+	var remote agent.RemoteConfig
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&remote); err != nil {
+		return agent.RemoteConfig{}, false, err
 	}
-	return nil
+	return remote, remote.Version != version, nil
 }
 
 func responseError(resp *http.Response) error {
@@ -43,7 +43,7 @@ func TestSendPostsMetricsWithAgentIdentity(t *testing.T) {
 	defer server.Close()
 
 	reporter := New(config.Config{Server: server.URL + "/", Token: "agent-token", NodeID: "CN-agent-001"})
-	err := reporter.Send(context.Background(), agent.Metrics{
+	_, err := reporter.Send(context.Background(), agent.Metrics{
 		NodeID:  "CN-agent-001",
 		Network: agent.Network{RxBytes: 123},
 	})
@@ -62,7 +62,7 @@ func TestSendIncludesServerErrorBody(t *testing.T) {
 	defer server.Close()
 
 	reporter := New(config.Config{Server: server.URL, Token: "bad-token", NodeID: "CN-agent-001"})
-	err := reporter.Send(context.Background(), agent.Metrics{})
+	_, err := reporter.Send(context.Background(), agent.Metrics{})
 	if err == nil {
 		t.Fatal("expected reporter error")
 	}
@@ -74,3 +74,71 @@ func TestSendIncludesServerErrorBody(t *testing.T) {
 		t.Fatalf("error missing response body: %q", message)
 	}
 }
+
+func TestFetchConfigSendsVersionAndDetectsChange(t *testing.T) {
+	const current = "abc123"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/agent/config" {
+			t.Fatalf("request = %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer agent-token" {
+			t.Fatalf("authorization = %q", got)
+		}
+		if r.Header.Get("If-None-Match") == `"`+current+`"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(agent.RemoteConfig{Version: current, Probes: []agent.RemoteProbe{{Name: "CT", Type: "icmp", Target: "202.96.209.133"}}})
+	}))
+	defer server.Close()
+
+	reporter := New(config.Config{Server: server.URL, Token: "agent-token", NodeID: "CN-agent-001"})
+	remote, changed, err := reporter.FetchConfig(context.Background(), "")
+	if err != nil || !changed || remote.Version != current || len(remote.Probes) != 1 {
+		t.Fatalf("first fetch = %#v changed=%v err=%v", remote, changed, err)
+	}
+	_, changed, err = reporter.FetchConfig(context.Background(), current)
+	if err != nil || changed {
+		t.Fatalf("second fetch changed=%v err=%v", changed, err)
+	}
+}
+
+func TestFetchConfigTreatsMissingEndpointAsUnchanged(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	reporter := New(config.Config{Server: server.URL, Token: "agent-token", NodeID: "CN-agent-001"})
+	if _, changed, err := reporter.FetchConfig(context.Background(), ""); err != nil || changed {
+		t.Fatalf("changed=%v err=%v", changed, err)
+	}
+}
+
+func TestSendReturnsUpdateOfferAndDownloads(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/agent/report":
+			_, _ = w.Write([]byte(`{"ok":"true","update":{"version":"0.2.0","url":"/download/vps-agent-linux-amd64","sha256":"abc"}}`))
+		case "/download/vps-agent-linux-amd64":
+			_, _ = w.Write([]byte("binary"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	reporter := New(config.Config{Server: server.URL, Token: "agent-token", NodeID: "CN-agent-001"})
+	resp, err := reporter.Send(context.Background(), agent.Metrics{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Update == nil || resp.Update.Version != "0.2.0" || resp.Update.URL != "/download/vps-agent-linux-amd64" {
+		t.Fatalf("response = %#v", resp)
+	}
+	data, err := reporter.Download(context.Background(), resp.Update.URL)
+	if err != nil || string(data) != "binary" {
+		t.Fatalf("download = %q err = %v", data, err)
+	}
+	if _, err := reporter.Download(context.Background(), "/download/missing"); err == nil {
+		t.Fatal("expected download error")
+	}
+}
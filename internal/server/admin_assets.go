@@ -15,6 +15,7 @@ const adminHTML = `<!doctype html>
     .shell{display:grid;grid-template-columns:268px 1fr;min-height:100vh}.side{padding:26px;background:#111;color:#fff;border-right:1px solid #111}.mark{width:44px;height:44px;border-radius:12px;background:#fff;color:#111;display:grid;place-items:center;font-weight:900;font-size:21px;border:1px solid rgba(255,255,255,.18)}.brand h1{margin:18px 0 8px;font-size:22px}.brand p{color:#c9c9c9;line-height:1.7}.nav{margin-top:30px}.nav a{display:block;color:#f5f5f5;text-decoration:none;padding:12px 13px;border-radius:10px;margin-bottom:8px;border:1px solid rgba(255,255,255,.12);background:#1b1b1b}.nav a:hover{background:#fff;color:#111;border-color:#fff}
     .main{padding:28px;min-width:0}.top{display:flex;align-items:stretch;justify-content:space-between;gap:16px;margin-bottom:18px}.hero{flex:1;padding:24px;border:1px solid var(--line);border-radius:14px;background:#fff;box-shadow:0 12px 36px rgba(17,17,17,.06)}.hero h2{font-size:30px;margin:0 0 8px;letter-spacing:-.04em}.muted{color:var(--muted)}.grid{display:grid;grid-template-columns:1fr 1fr;gap:16px}.card{background:#fff;border:1px solid var(--line);border-radius:14px;padding:20px;margin-bottom:16px;box-shadow:0 10px 28px rgba(17,17,17,.05)}.card h3{margin:0 0 14px;font-size:17px}.row{display:flex;gap:10px;flex-wrap:wrap;align-items:center}input,select{height:42px;border:1px solid var(--line);border-radius:8px;padding:0 12px;font-size:14px;min-width:210px;background:#fff;color:var(--ink);outline:none}input:focus,select:focus{border-color:#111;box-shadow:0 0 0 3px rgba(17,17,17,.12)}select{cursor:pointer}.check{height:42px;display:flex;align-items:center;gap:8px;color:var(--ink);font-size:14px}.check input{min-width:0;width:16px;height:16px;accent-color:#111}button{height:42px;border:0;border-radius:8px;background:#111;color:#fff;padding:0 16px;font-weight:800;cursor:pointer}button:hover{background:#2a2a2a}button.secondary{background:#f3f4f6;color:#111;border:1px solid var(--line)}button.ghost{background:#fff;color:#111;border:1px solid var(--line)}button.danger{background:#fff;color:var(--red);border:1px solid #fecaca}.hidden{display:none!important}.statbar{display:grid;grid-template-columns:repeat(3,1fr);gap:14px;margin:16px 0}.stat{padding:16px;border-radius:14px;background:#fff;border:1px solid var(--line)}.stat b{display:block;font-size:28px}.stat span{font-size:12px;color:var(--muted);letter-spacing:.1em}
     table{width:100%;border-collapse:separate;border-spacing:0;overflow:hidden}th,td{text-align:left;padding:13px;border-bottom:1px solid #edf0f5;font-size:14px}th{color:var(--muted);font-size:12px;text-transform:uppercase;letter-spacing:.08em;background:#f8f8f8}.ok{color:var(--green);font-weight:800}.off{color:var(--red);font-weight:800}.pill{display:inline-block;border-radius:999px;padding:5px 10px;background:#f3f4f6;color:#111;font-weight:800;font-size:12px}textarea{width:100%;min-height:118px;border:1px solid #222;border-radius:10px;padding:12px;background:#111;color:#f5f5f5;font-family:ui-monospace,SFMono-Regular,Consolas,monospace;font-size:12px;line-height:1.6}.login{min-height:100vh;display:grid;grid-template-columns:minmax(320px,42vw) 1fr;background:#fff}.login-hero{padding:56px;display:flex;flex-direction:column;justify-content:space-between;background:#111;color:#fff}.login-hero .mark{background:#fff;color:#111}.login-hero h1{margin:48px 0 14px;font-size:46px;line-height:1;letter-spacing:-.06em}.login-hero p{max-width:520px;color:#d1d5db;font-size:16px;line-height:1.8}.login-points{display:grid;gap:10px;margin-top:36px}.login-points span{display:block;padding:12px 0;border-top:1px solid rgba(255,255,255,.14);color:#f5f5f5}.login-form{padding:56px;display:flex;align-items:center;justify-content:center}.login-panel{width:100%;max-width:460px}.login-panel h2{margin:0 0 10px;font-size:30px;letter-spacing:-.04em}.login-panel p{margin:0 0 28px}.login-panel input,.login-panel button{width:100%;min-width:0}.login-panel .row{display:grid;gap:12px}.toast{position:fixed;right:24px;bottom:24px;background:#111;color:#fff;padding:11px 15px;border-radius:10px;box-shadow:0 18px 50px rgba(17,17,17,.22)}
+    .detail-grid{display:grid;grid-template-columns:repeat(auto-fit,minmax(240px,1fr));gap:14px}.detail-block{border:1px solid var(--line);border-radius:10px;padding:14px;background:var(--soft)}.detail-block h4{margin:0 0 10px;font-size:13px;color:var(--muted);letter-spacing:.08em}.kv{display:flex;justify-content:space-between;gap:12px;padding:5px 0;font-size:13px;border-bottom:1px dashed #e5e7eb}.kv:last-child{border-bottom:0}.kv span:first-child{font-family:ui-monospace,SFMono-Regular,Consolas,monospace;overflow:hidden;text-overflow:ellipsis;white-space:nowrap}
     @media(max-width:900px){.shell{display:block}.side{border-radius:0 0 28px 28px}.grid,.statbar{grid-template-columns:1fr}.top{display:block}.main{padding:18px}input,select{min-width:100%;width:100%}button{width:100%}table{display:block;overflow-x:auto;white-space:nowrap}.login{display:block}.login-hero{min-height:42vh;padding:30px}.login-hero h1{font-size:36px;margin-top:36px}.login-form{padding:28px;display:block}.login-panel{max-width:none}}
   </style>
 </head>
@@ -33,17 +34,22 @@ const adminHTML = `<!doctype html>
     </section>
   </div>
   <div id="panel" class="shell hidden">
-    <aside class="side"><div class="brand"><div class="mark">M</div><h1>Monitor Party</h1><p>节点接入、安装命令和在线状态管理。</p></div><div class="nav"><a href="/">公开面板</a><a href="#nodes">节点管理</a><a href="#commands">安装命令</a></div></aside>
+    <aside class="side"><div class="brand"><div class="mark">M</div><h1>Monitor Party</h1><p>节点接入、安装命令和在线状态管理。</p></div><div class="nav"><a href="/">公开面板</a><a href="#nodes">节点管理</a><a href="#commands">安装命令</a><a href="#uptime">可用性报告</a></div></aside>
     <main class="main">
       <div class="top"><div class="hero"><h2>Agent 接入控制台</h2><div class="muted">统一管理节点、购买周期和免输入安装命令。</div></div><button class="danger" onclick="logout()">退出登录</button></div>
       <div class="statbar"><div class="stat"><b id="totalCount">0</b><span>TOTAL</span></div><div class="stat"><b id="onlineCount">0</b><span>ONLINE</span></div><div class="stat"><b id="offlineCount">0</b><span>PENDING</span></div></div>
       <div class="grid">
         <section class="card"><h3>添加节点</h3><div class="row"><input id="nodeId" placeholder="US-node-001"><button onclick="addNode()">添加并生成</button><button class="secondary" onclick="loadNodes()">刷新</button><button class="ghost" onclick="exportNodes()">一键导出</button><button class="ghost" onclick="nodeImportFile.click()">一键导入</button><input id="nodeImportFile" type="file" accept="application/json,.json" class="hidden" onchange="importNodes(this)"></div><p class="muted">Node ID 必须唯一，建议前两位使用国家或地区代码。导入会合并节点和套餐信息，不会删除现有节点。</p></section>
-        <section class="card"><h3>站点设置</h3><div class="row"><input id="siteName" placeholder="Monitor Party"><button onclick="saveSettings()">保存设置</button></div><p class="muted">站名默认 Monitor Party，可在这里修改。</p></section>
+      <section class="card"><h3>批量操作</h3><div class="row"><select id="bulkOp"><option value="add">添加节点</option><option value="token">生成安装命令</option><option value="update">修改主机信息</option><option value="delete">删除节点</option></select><input id="bulkSeller" placeholder="卖家，留空不改"><input id="bulkPrice" placeholder="价格，留空不改"><input id="bulkDueTime" type="date" min="1970-01-01" max="9999-12-31" title="到期时间，留空不改"><input id="bulkResetDay" type="number" min="1" max="31" placeholder="流量重置日，留空不改"><label class="check"><input id="bulkAtomic" type="checkbox"> 任一失败则全部不生效</label><button onclick="runBulk()">执行</button></div><textarea id="bulkNodes" placeholder="每行一个 Node ID"></textarea><div id="bulkResults"></div><textarea id="bulkCommands" class="hidden" readonly></textarea><p class="muted">卖家、价格、到期时间和重置日只在“修改主机信息”时使用。生成安装命令会为每个节点签发新 token，旧 token 随即失效。</p></section>
+        <section class="card"><h3>探测目标</h3><div class="row"><input id="probeName" placeholder="名称，例如 CT"><select id="probeType"><option value="icmp">ICMP</option><option value="tcp">TCP</option><option value="http">HTTP</option></select><input id="probeTarget" placeholder="主机 / 主机:端口 / URL"><input id="probeWarn" type="number" min="0" placeholder="延迟告警 ms"><input id="probeNodes" placeholder="限定节点，逗号分隔"><input id="probeTags" placeholder="限定分组或标签，逗号分隔"><button onclick="addProbeTarget()">添加</button></div><div id="probeTargets"></div><p class="muted">节点和标签都留空则下发到全部节点，否则下发到列出的节点和带有任一标签的节点。Agent 每分钟拉取一次，本地 PROBES 同名时以本地为准。</p></section>
+        <section class="card"><h3>公开面板</h3><div class="row"><input id="dashSlug" placeholder="路径，例如 customer-a"><input id="dashName" placeholder="站名"><input id="dashNodes" placeholder="节点，逗号分隔"><input id="dashTags" placeholder="分组或标签，逗号分隔"><select id="dashTheme"><option value="">主题跟随访客</option><option value="light">浅色</option><option value="dark">深色</option></select><input id="dashPassword" type="password" autocomplete="new-password" placeholder="访问密码，留空则公开"><button onclick="addDashboard()">添加</button></div><div id="dashboards"></div><p class="muted">每个面板在 /d/路径 下访问，只显示列出的节点和带有任一标签的节点，二者至少填写一项。隐藏和仅管理员可见的节点同样生效。修改密码后已登录的访客需要重新输入。</p></section>
+        <section class="card"><h3>Agent 配置下发</h3><div class="row"><input id="overrideNode" placeholder="节点 ID，留空为全局" onchange="showAgentOverrides()"><button class="secondary" onclick="showAgentOverrides()">读取</button><button onclick="saveAgentOverrides()">保存</button></div><textarea id="overrideText" placeholder="BASIC_INTERVAL=5s&#10;MOUNTS=/,/data"></textarea><p class="muted">每行一个 config.env 配置项，节点配置优先于全局配置，二者都优先于节点本地文件；SERVER、TOKEN、NODE_ID 只能在本地修改。Agent 每分钟拉取一次，无需重启。</p></section>
+        <section class="card"><h3>站点设置</h3><div class="row"><input id="siteName" placeholder="Monitor Party"><button onclick="saveSettings()">保存设置</button><button class="danger" onclick="resetShares()">撤销全部分享链接</button></div><p class="muted">首页的站名，默认 Monitor Party。公开面板各自使用自己的站名。节点列表中的“分享”可为单个节点生成有时效的只读链接。</p></section>
       </div>
-      <section id="editInfo" class="card hidden"><h3>编辑主机信息</h3><div class="row"><input id="editNodeName" readonly><input id="editSeller" placeholder="卖家"><input id="editPrice" placeholder="价格"><select id="editCycle"><option value="">选择周期</option><option value="日">日</option><option value="月">月</option><option value="半年">半年</option><option value="年">年</option><option value="三年">三年</option><option value="五年">五年</option><option value="十年">十年</option></select><input id="editBandwidth" placeholder="带宽，例如 1Gbps"><input id="editTraffic" placeholder="月流量，例如 1TB/月"><input id="editTrafficResetDay" type="number" min="1" max="31" placeholder="流量重置日，默认 1"><input id="editDueTime" type="date" min="1970-01-01" max="9999-12-31" title="到期时间" oninput="normalizeDueDateInput()" onchange="normalizeDueDateInput()"><input id="editBuyUrl" placeholder="购买链接"><label class="check"><input id="editShowPurchase" type="checkbox"> 此节点前台显示购买信息</label><button onclick="saveNodeInfo()">保存信息</button><button class="secondary" onclick="hideEditInfo()">取消</button></div><p class="muted">流量重置日支持 1-31 号，小月没有该日期时自动按当月最后一天重置。</p></section>
-      <section id="commands" class="card hidden"><h3>免输入安装 / 卸载命令</h3><p><span class="pill">Linux 安装</span></p><textarea id="linuxCmd" readonly></textarea><p><button class="secondary" onclick="copyText('linuxCmd')">复制 Linux 安装命令</button></p><p><span class="pill">Linux 卸载</span></p><textarea id="linuxUninstallCmd" readonly></textarea><p><button class="secondary" onclick="copyText('linuxUninstallCmd')">复制 Linux 卸载命令</button></p><p><span class="pill">Windows PowerShell 管理员安装</span></p><textarea id="windowsCmd" readonly></textarea><p><button class="secondary" onclick="copyText('windowsCmd')">复制 Windows 安装命令</button></p><p><span class="pill">Windows PowerShell 管理员卸载</span></p><textarea id="windowsUninstallCmd" readonly></textarea><p><button class="secondary" onclick="copyText('windowsUninstallCmd')">复制 Windows 卸载命令</button></p></section>
-      <section id="nodes" class="card"><h3>节点列表</h3><table><thead><tr><th>节点</th><th>状态</th><th>卖家</th><th>价格</th><th>周期</th><th>带宽</th><th>月流量</th><th>重置日</th><th>到期时间</th><th>最后上报</th><th>操作</th></tr></thead><tbody id="nodeRows"></tbody></table></section>
+      <section id="editInfo" class="card hidden"><h3>编辑主机信息</h3><div class="row"><input id="editNodeName" readonly><input id="editDisplayName" placeholder="显示名称，留空则用节点 ID"><input id="editSeller" placeholder="卖家"><input id="editPrice" placeholder="价格"><select id="editCycle"><option value="">选择周期</option><option value="日">日</option><option value="月">月</option><option value="半年">半年</option><option value="年">年</option><option value="三年">三年</option><option value="五年">五年</option><option value="十年">十年</option></select><input id="editBandwidth" placeholder="带宽，例如 1Gbps"><input id="editTraffic" placeholder="月流量，例如 1TB/月"><input id="editTrafficResetDay" type="number" min="1" max="31" placeholder="流量重置日，默认 1"><input id="editDueTime" type="date" min="1970-01-01" max="9999-12-31" title="到期时间" oninput="normalizeDueDateInput()" onchange="normalizeDueDateInput()"><input id="editBuyUrl" placeholder="购买链接"><input id="editGroup" placeholder="分组，例如 US"><input id="editTags" placeholder="标签，逗号分隔"><select id="editVisibility" title="前台可见性"><option value="">前台公开</option><option value="private">仅登录管理员可见</option><option value="hidden">前台隐藏</option></select><input id="editPublicName" placeholder="公开别名，前台代替节点 ID"><label class="check"><input id="editRedactHost" type="checkbox"> 前台隐藏主机名和 IP</label><label class="check"><input id="editShowPurchase" type="checkbox"> 此节点前台显示购买信息</label><button onclick="saveNodeInfo()">保存信息</button><button class="secondary" onclick="hideEditInfo()">取消</button></div><p class="muted">流量重置日支持 1-31 号，小月没有该日期时自动按当月最后一天重置。分组和标签会显示在前台，用于分组展示和筛选。可见性、公开别名和隐藏主机名由中心端在输出前台数据时处理，被隐藏的节点不会出现在 /api/nodes、/ws 和 /info 里；公开别名会代替节点 ID 作为前台标识，应保持唯一。显示名称只改前台标题；要改节点 ID 请用列表里的“改名”，旧 ID 会保留为别名，agent 下次拉取配置时自动换成新 ID。</p></section>
+      <section id="nodeDetail" class="card hidden"><h3 id="nodeDetailTitle">节点详情</h3><div id="nodeDetailBody" class="detail-grid"></div><p><button class="secondary" onclick="hideNodeDetail()">关闭</button></p></section>
+      <section id="commands" class="card hidden"><h3>免输入安装 / 卸载命令</h3><p id="installNote" class="muted hidden"></p><p><span class="pill">Linux 安装</span></p><textarea id="linuxCmd" readonly></textarea><p><button class="secondary" onclick="copyText('linuxCmd')">复制 Linux 安装命令</button></p><p><span class="pill">Linux 卸载</span></p><textarea id="linuxUninstallCmd" readonly></textarea><p><button class="secondary" onclick="copyText('linuxUninstallCmd')">复制 Linux 卸载命令</button></p><p><span class="pill">Windows PowerShell 管理员安装</span></p><textarea id="windowsCmd" readonly></textarea><p><button class="secondary" onclick="copyText('windowsCmd')">复制 Windows 安装命令</button></p><p><span class="pill">Windows PowerShell 管理员卸载</span></p><textarea id="windowsUninstallCmd" readonly></textarea><p><button class="secondary" onclick="copyText('windowsUninstallCmd')">复制 Windows 卸载命令</button></p></section>
+      <section id="nodes" class="card"><h3>节点列表</h3><div class="row"><input id="tagFilter" placeholder="按分组或标签筛选" onchange="loadNodes()"><input id="bulkGroup" placeholder="分组，留空不改"><input id="bulkAdd" placeholder="添加标签，逗号分隔"><input id="bulkRemove" placeholder="移除标签，逗号分隔"><button class="secondary" onclick="bulkTags()">批量修改列表中的节点</button></div><table><thead><tr><th>节点</th><th>状态</th><th>卖家</th><th>价格</th><th>周期</th><th>带宽</th><th>月流量</th><th>重置日</th><th>到期时间</th><th>最后上报</th><th>操作</th></tr></thead><tbody id="nodeRows"></tbody></table></section><section id="fleet" class="card"><h3>Agent 版本</h3><p id="fleetSummary" class="muted"></p><table><thead><tr><th>版本</th><th>节点数</th><th>节点</th></tr></thead><tbody id="fleetRows"></tbody></table></section><section id="uptime" class="card"><h3>可用性报告</h3><div class="row"><input id="uptimeMonth" type="month" title="月份" onchange="loadUptime()"><button class="secondary" onclick="loadUptime()">查看</button><button class="ghost" onclick="exportUptime('')">导出 CSV</button><button class="ghost" onclick="exportUptime('outages')">导出故障记录 CSV</button></div><table><thead><tr><th>节点</th><th>卖家</th><th>今日</th><th>当月</th><th>停机时长</th><th>故障次数</th><th>账单周期</th><th>周期可用率</th><th>操作</th></tr></thead><tbody id="uptimeRows"></tbody></table><p class="muted">两次上报间隔超过离线判定时间即记为一次故障，从故障前最后一次上报算到恢复后第一次上报；当前离线的节点按仍在故障中计算。只统计中心端开始记录之后的时间。账单周期按到期时间和周期推算，没有填写时按流量重置日的月周期。列表按上方“按分组或标签筛选”过滤。</p></section>
     </main>
   </div>
 <script>
@@ -56,10 +62,32 @@ async function login(){try{await api('/api/admin/login',{method:'POST',headers:{
 async function logout(){await api('/api/admin/logout',{method:'POST'});location.reload()}
 async function loadSettings(){try{const s=await api('/api/admin/settings');siteName.value=s.site_name||'Monitor Party'}catch(e){}}
 async function saveSettings(){try{await api('/api/admin/settings',{method:'POST',headers:{'Content-Type':'application/json'},body:JSON.stringify({site_name:siteName.value.trim()||'Monitor Party'})});toast('设置已保存')}catch(e){toast(e.message)}}
+async function loadProbeTargets(){try{window.probeTargetCache=await api('/api/admin/probes');renderProbeTargets()}catch(e){}}
+function renderProbeTargets(){probeTargets.replaceChildren();(window.probeTargetCache||[]).forEach(function(p,i){const row=document.createElement('div');row.className='row';const text=document.createElement('span');text.textContent=p.name+' · '+p.type+' '+p.target+(p.warn_ms?' · '+p.warn_ms+'ms':'')+' · '+((p.nodes||[]).concat((p.tags||[]).map(function(t){return '#'+t})).join(', ')||'全部节点');row.appendChild(text);const del=document.createElement('button');del.className='ghost';del.textContent='删除';del.onclick=function(){removeProbeTarget(i)};row.appendChild(del);probeTargets.appendChild(row)})}
+async function saveProbeTargets(list){await api('/api/admin/probes',{method:'POST',headers:{'Content-Type':'application/json'},body:JSON.stringify(list)});window.probeTargetCache=list;renderProbeTargets()}
+async function addProbeTarget(){const target=probeTarget.value.trim();if(!target){toast('请输入探测目标');return}const p={name:probeName.value.trim()||target,type:probeType.value,target:target,warn_ms:parseInt(probeWarn.value,10)||0,nodes:splitList(probeNodes.value),tags:splitList(probeTags.value)};try{await saveProbeTargets((window.probeTargetCache||[]).concat([p]));probeName.value='';probeTarget.value='';probeWarn.value='';probeNodes.value='';probeTags.value='';toast('探测目标已保存')}catch(e){toast(e.message)}}
+async function removeProbeTarget(i){const list=(window.probeTargetCache||[]).slice();list.splice(i,1);try{await saveProbeTargets(list);toast('探测目标已删除')}catch(e){toast(e.message)}}
+async function loadDashboards(){try{window.dashboardCache=await api('/api/admin/dashboards');renderDashboards()}catch(e){}}
+function renderDashboards(){dashboards.replaceChildren();(window.dashboardCache||[]).forEach(function(d,i){const row=document.createElement('div');row.className='row';const link=document.createElement('a');link.href='/d/'+d.slug;link.target='_blank';link.textContent='/d/'+d.slug;row.appendChild(link);const text=document.createElement('span');text.textContent=d.site_name+' · '+((d.nodes||[]).concat((d.tags||[]).map(function(t){return '#'+t})).join(', ')||'全部节点')+(d.theme?' · '+(d.theme==='dark'?'深色':'浅色'):'')+(d.has_password?' · 需要密码':'');row.appendChild(text);const del=document.createElement('button');del.className='ghost';del.textContent='删除';del.onclick=function(){removeDashboard(i)};row.appendChild(del);dashboards.appendChild(row)})}
+async function saveDashboards(list){await api('/api/admin/dashboards',{method:'POST',headers:{'Content-Type':'application/json'},body:JSON.stringify(list)});await loadDashboards()}
+async function addDashboard(){const slug=dashSlug.value.trim().toLowerCase();if(!slug){toast('请输入面板路径');return}const d={slug:slug,site_name:dashName.value.trim()||slug,nodes:splitList(dashNodes.value),tags:splitList(dashTags.value),theme:dashTheme.value,password:dashPassword.value};if(!d.nodes.length&&!d.tags.length){toast('请填写节点或标签');return}try{await saveDashboards((window.dashboardCache||[]).filter(function(x){return x.slug!==slug}).concat([d]));dashSlug.value='';dashName.value='';dashNodes.value='';dashTags.value='';dashTheme.value='';dashPassword.value='';toast('面板已保存')}catch(e){toast(e.message)}}
+async function removeDashboard(i){const list=(window.dashboardCache||[]).slice();list.splice(i,1);try{await saveDashboards(list);toast('面板已删除')}catch(e){toast(e.message)}}
+async function loadAgentOverrides(){try{window.agentOverrides=await api('/api/admin/agent-config');showAgentOverrides()}catch(e){}}
+function showAgentOverrides(){const o=window.agentOverrides||{};const id=overrideNode.value.trim();const values=(id?(o.nodes||{})[id]:o.global)||{};overrideText.value=Object.keys(values).sort().map(function(k){return k+'='+values[k]}).join('\n')}
+async function saveAgentOverrides(){const o=JSON.parse(JSON.stringify(window.agentOverrides||{}));const id=overrideNode.value.trim();const values={};overrideText.value.split('\n').forEach(function(line){line=line.trim();if(!line||line[0]==='#')return;const i=line.indexOf('=');if(i>0)values[line.slice(0,i).trim()]=line.slice(i+1).trim()});if(id){o.nodes=o.nodes||{};o.nodes[id]=values}else{o.global=values}try{await api('/api/admin/agent-config',{method:'POST',headers:{'Content-Type':'application/json'},body:JSON.stringify(o)});await loadAgentOverrides();toast('配置已保存')}catch(e){toast(e.message)}}
 async function addNode(){const id=nodeId.value.trim();if(!id){toast('请输入节点 ID');return}try{await api('/api/admin/nodes',{method:'POST',headers:{'Content-Type':'application/json'},body:JSON.stringify({node_id:id})});await showCommands(id);await loadNodes();toast('节点已添加')}catch(e){toast(e.message)}}
-async function exportNodes(){try{const data=await api('/api/admin/nodes/export');const blob=new Blob([JSON.stringify(data,null,2)],{type:'application/json'});const a=document.createElement('a');a.href=URL.createObjectURL(blob);a.download='monitor-nodes-'+new Date().toISOString().slice(0,10)+'.json';document.body.appendChild(a);a.click();URL.revokeObjectURL(a.href);a.remove();toast('节点已导出')}catch(e){toast(e.message)}}
+function splitList(v){return v.split(',').map(function(x){return x.trim()}).filter(Boolean)}
+function uptimeQuery(view){const q=[];if(uptimeMonth.value)q.push('month='+uptimeMonth.value);const tag=tagFilter.value.trim();if(tag)q.push('tag='+encodeURIComponent(tag));if(view!==undefined){q.push('format=csv');if(view)q.push('view='+view)}return q.length?'?'+q.join('&'):''}
+function percentText(s){return s&&s.monitored_seconds?s.uptime_percent.toFixed(3)+'%':'-'}
+function dayText(sec){return new Date(sec*1000).toLocaleDateString()}
+function downtimeText(sec){const d=Math.floor(sec/86400),h=Math.floor(sec%86400/3600),m=Math.floor(sec%3600/60);return (d?d+' 天 ':'')+(d||h?h+' 小时 ':'')+(d||h||m?m+' 分 ':'')+(sec%60)+' 秒'}
+async function loadUptime(){try{const r=await api('/api/admin/uptime'+uptimeQuery());if(!uptimeMonth.value)uptimeMonth.value=r.month;window.uptimeCache=r.nodes;uptimeRows.replaceChildren();r.nodes.forEach(function(n){const tr=document.createElement('tr');tr.appendChild(cell((n.display_name?n.display_name+' ('+n.node_id+')':n.node_id)+(n.online?'':' · 离线'),n.online?'':'off'));tr.appendChild(cell(n.seller||'-'));tr.appendChild(cell(percentText(n.today)));tr.appendChild(cell(percentText(n.month)));tr.appendChild(cell(downtimeText(n.month.downtime_seconds)));tr.appendChild(cell(String(n.month.outages)));tr.appendChild(cell(dayText(n.billing.start)+' ~ '+dayText(n.billing.end)));tr.appendChild(cell(percentText(n.billing)));const actions=document.createElement('td');actions.appendChild(actionButton('详情','ghost',function(){showUptime(n.node_id)}));tr.appendChild(actions);uptimeRows.appendChild(tr)})}catch(e){}}
+function showUptime(id){const n=(window.uptimeCache||[]).find(function(x){return x.node_id===id});if(!n)return;nodeDetailTitle.textContent='可用性 · '+id+' · '+uptimeMonth.value;const days=n.days.filter(function(d){return d.monitored_seconds}).map(function(d){return [dayText(d.start),percentText(d)+(d.outages?' · 停机 '+downtimeText(d.downtime_seconds):'')]});const outages=n.outages.map(function(o){return [new Date(o.start*1000).toLocaleString(),(o.ongoing?'仍未恢复 · ':'恢复于 '+new Date(o.end*1000).toLocaleString()+' · ')+downtimeText(o.duration_seconds)]});nodeDetailBody.replaceChildren(detailBlock('每日可用率',days),detailBlock('故障记录',outages),detailBlock('汇总',[['开始记录',new Date(n.since*1000).toLocaleString()],['当月',percentText(n.month)],['账单周期',percentText(n.billing)]]));nodeDetail.classList.remove('hidden');nodeDetail.scrollIntoView({behavior:'smooth',block:'start'})}
+async function exportUptime(view){try{const r=await fetch('/api/admin/uptime'+uptimeQuery(view),{credentials:'include'});if(!r.ok)throw new Error(await r.text());const blob=await r.blob();const a=document.createElement('a');a.href=URL.createObjectURL(blob);a.download=(view?'monitor-outages-':'monitor-uptime-')+(uptimeMonth.value||new Date().toISOString().slice(0,7))+'.csv';document.body.appendChild(a);a.click();URL.revokeObjectURL(a.href);a.remove()}catch(e){toast(e.message)}}
+function tagQuery(){const tag=tagFilter.value.trim();return tag?'?tag='+encodeURIComponent(tag):''}
+async function exportNodes(){try{const data=await api('/api/admin/nodes/export'+tagQuery());const blob=new Blob([JSON.stringify(data,null,2)],{type:'application/json'});const a=document.createElement('a');a.href=URL.createObjectURL(blob);a.download='monitor-nodes-'+new Date().toISOString().slice(0,10)+'.json';document.body.appendChild(a);a.click();URL.revokeObjectURL(a.href);a.remove();toast('节点已导出')}catch(e){toast(e.message)}}
 async function importNodes(input){const file=input.files&&input.files[0];input.value='';if(!file)return;if(!confirm('导入会合并节点和套餐信息，不会删除现有节点。继续导入？'))return;try{const text=await file.text();JSON.parse(text);const r=await api('/api/admin/nodes/import',{method:'POST',headers:{'Content-Type':'application/json'},body:text});await loadNodes();toast('已导入 '+r.imported+' 个节点')}catch(e){toast('导入失败：'+e.message)}}
-async function showCommands(id){const r=await api('/api/admin/install-command?node_id='+encodeURIComponent(id),{method:'POST'});linuxCmd.value=r.linux;windowsCmd.value=r.windows;linuxUninstallCmd.value=r.linux_uninstall;windowsUninstallCmd.value=r.windows_uninstall;commands.classList.remove('hidden');commands.scrollIntoView({behavior:'smooth',block:'start'})}
+async function showCommands(id){const r=await api('/api/admin/install-command?node_id='+encodeURIComponent(id),{method:'POST'});linuxCmd.value=r.linux;windowsCmd.value=r.windows;linuxUninstallCmd.value=r.linux_uninstall;windowsUninstallCmd.value=r.windows_uninstall;installNote.textContent=r.note||'';installNote.classList.toggle('hidden',!r.note);commands.classList.remove('hidden');commands.scrollIntoView({behavior:'smooth',block:'start'})}
 function dateValue(v){if(!v)return '';const t=Number(v);const d=new Date(t>0&&t<1000000000000?t*1000:v);if(isNaN(d.getTime()))return '';return d.toISOString().slice(0,10)}
 function dateText(v){return dateValue(v)||'-'}
 function validDueDate(v){return !v||/^\d{4}-\d{2}-\d{2}$/.test(v)}
@@ -67,10 +95,29 @@ function normalizeDueDateInput(){if(!validDueDate(editDueTime.value)){editDueTim
 function normalizeResetDay(v){v=Number(v)||1;if(v<1)return 1;if(v>31)return 31;return Math.floor(v)}
 function cell(text,className){const td=document.createElement('td');if(className)td.className=className;td.textContent=text;return td}
 function actionButton(text,className,handler){const btn=document.createElement('button');btn.className=className;btn.type='button';btn.textContent=text;btn.addEventListener('click',handler);return btn}
-async function loadNodes(){await loadSettings();const list=await api('/api/admin/nodes');window.nodeCache=list;totalCount.textContent=list.length;onlineCount.textContent=list.filter(function(n){return n.online}).length;offlineCount.textContent=list.filter(function(n){return !n.online}).length;nodeRows.replaceChildren();list.forEach(function(n){const info=n.info||{};const tr=document.createElement('tr');const nameCell=document.createElement('td');const bold=document.createElement('b');bold.textContent=n.node_id;nameCell.appendChild(bold);tr.appendChild(nameCell);tr.appendChild(cell(n.online?'在线':'待安装/离线',n.online?'ok':'off'));tr.appendChild(cell(info.seller||'-'));tr.appendChild(cell(info.price||'-'));tr.appendChild(cell(info.cycle||'-'));tr.appendChild(cell(info.bandwidth||'-'));tr.appendChild(cell(info.traffic||'-'));tr.appendChild(cell('每月 '+normalizeResetDay(info.traffic_reset_day)+' 日'));tr.appendChild(cell(dateText(info.due_time)));tr.appendChild(cell(n.last_seen?new Date(n.last_seen*1000).toLocaleString():'-'));const actions=document.createElement('td');actions.appendChild(actionButton('命令','ghost',function(){showCommands(n.node_id)}));actions.appendChild(document.createTextNode(' '));actions.appendChild(actionButton('编辑','ghost',function(){editNode(n.node_id)}));actions.appendChild(document.createTextNode(' '));actions.appendChild(actionButton('删除','danger',function(){deleteNode(n.node_id)}));tr.appendChild(actions);nodeRows.appendChild(tr)})}
-function editNode(id){const n=(window.nodeCache||[]).find(function(x){return x.node_id===id})||{};const info=n.info||{};editNodeName.value=id;editSeller.value=info.seller||'';editPrice.value=info.price||'';editCycle.value=info.cycle||'';editBandwidth.value=info.bandwidth||'';editTraffic.value=info.traffic||'';editTrafficResetDay.value=normalizeResetDay(info.traffic_reset_day);editDueTime.value=dateValue(info.due_time);editBuyUrl.value=info.buy_url||'';editShowPurchase.checked=!!info.show_purchase_info;editInfo.classList.remove('hidden');editInfo.scrollIntoView({behavior:'smooth',block:'start'})}
+async function loadNodes(){await loadSettings();await loadProbeTargets();await loadDashboards();await loadAgentOverrides();await loadFleet();await loadUptime();const list=await api('/api/admin/nodes'+tagQuery());window.nodeCache=list;totalCount.textContent=list.length;onlineCount.textContent=list.filter(function(n){return n.online}).length;offlineCount.textContent=list.filter(function(n){return !n.online}).length;nodeRows.replaceChildren();list.forEach(function(n){const info=n.info||{};const tr=document.createElement('tr');const nameCell=document.createElement('td');const bold=document.createElement('b');bold.textContent=info.display_name?info.display_name+' ('+n.node_id+')':n.node_id;nameCell.appendChild(bold);const labels=(info.group?[info.group]:[]).concat((info.tags||[]).map(function(t){return '#'+t}));if((n.aliases||[]).length){labels.push('曾用 ID: '+n.aliases.join(', '))}if(info.visibility){labels.push(info.visibility==='hidden'?'前台隐藏':'仅管理员可见')}if(info.public_name){labels.push('公开别名: '+info.public_name)}if(labels.length){const small=document.createElement('div');small.className='muted';small.textContent=labels.join(' ');nameCell.appendChild(small)}tr.appendChild(nameCell);const failed=n.failed_services||[];const health=(n.health||[]).concat(n.agent_flags||[]);tr.appendChild(cell((n.online?'在线':'待安装/离线')+(failed.length?' · 服务异常: '+failed.join(', '):'')+(health.length?' · '+health.join(', '):''),n.online&&!failed.length&&!health.length?'ok':'off'));tr.appendChild(cell(info.seller||'-'));tr.appendChild(cell(info.price||'-'));tr.appendChild(cell(info.cycle||'-'));tr.appendChild(cell(info.bandwidth||'-'));tr.appendChild(cell(info.traffic||'-'));tr.appendChild(cell('每月 '+normalizeResetDay(info.traffic_reset_day)+' 日'));tr.appendChild(cell(dateText(info.due_time)));tr.appendChild(cell(n.last_seen?new Date(n.last_seen*1000).toLocaleString():'-'));const actions=document.createElement('td');actions.appendChild(actionButton('详情','ghost',function(){showNodeDetail(n.node_id)}));actions.appendChild(document.createTextNode(' '));actions.appendChild(actionButton('命令','ghost',function(){showCommands(n.node_id)}));actions.appendChild(document.createTextNode(' '));actions.appendChild(actionButton('编辑','ghost',function(){editNode(n.node_id)}));actions.appendChild(document.createTextNode(' '));actions.appendChild(actionButton('改名','ghost',function(){renameNode(n.node_id)}));actions.appendChild(document.createTextNode(' '));actions.appendChild(actionButton('分享','ghost',function(){shareNode(n.node_id)}));actions.appendChild(document.createTextNode(' '));actions.appendChild(actionButton('删除','danger',function(){deleteNode(n.node_id)}));tr.appendChild(actions);nodeRows.appendChild(tr)})}
+function bytesText(v){v=Number(v)||0;const units=['B','KB','MB','GB','TB'];let i=0;while(v>=1024&&i<units.length-1){v/=1024;i++}return (i?v.toFixed(1):v)+' '+units[i]}
+function agentBlocks(m){const a=m.agent;if(!a)return [detailBlock('Agent',m.agent_version?[['版本',m.agent_version]]:[])];const rows=[['版本',m.agent_version||'未知'],['运行时长',durationText(a.uptime_sec)],['采集 / 发送',a.collect_ms.toFixed(1)+' ms / '+a.send_ms.toFixed(1)+' ms'],['内存 / 协程',bytesText(a.memory_bytes)+' / '+a.goroutines],['采集 / 上报错误',(a.collect_errors||0)+' / '+(a.report_errors||0)]];if(a.last_error)rows.push(['最近错误',new Date(a.last_error_at*1000).toLocaleString(),a.last_error]);return [detailBlock('Agent',rows)]}
+function durationText(sec){sec=Number(sec)||0;const d=Math.floor(sec/86400),h=Math.floor(sec%86400/3600),m=Math.floor(sec%3600/60);return d?d+' 天 '+h+' 小时':h?h+' 小时 '+m+' 分':m+' 分'}
+async function loadFleet(){try{const f=await api('/api/admin/fleet');fleetSummary.textContent='中心端内嵌 Agent 版本 '+f.current+'。过时的 Agent 会在下次上报时自动升级，AUTO_UPDATE=false 和容器模式除外。';fleetRows.replaceChildren();f.versions.forEach(function(v){const tr=document.createElement('tr');tr.appendChild(cell((v.version||'未知')+(v.outdated?' · 过时':''),v.outdated?'off':'ok'));tr.appendChild(cell(String(v.nodes.length)));tr.appendChild(cell(v.nodes.join(', ')));fleetRows.appendChild(tr)})}catch(e){}}
+function detailBlock(title,rows){const block=document.createElement('div');block.className='detail-block';const h=document.createElement('h4');h.textContent=title;block.appendChild(h);if(!rows.length){const empty=document.createElement('div');empty.className='muted';empty.textContent='暂无数据';block.appendChild(empty)}rows.forEach(function(r){const kv=document.createElement('div');kv.className='kv';const k=document.createElement('span');k.textContent=r[0];const v=document.createElement('span');v.textContent=r[1];if(r[2])kv.title=r[2];kv.appendChild(k);kv.appendChild(v);block.appendChild(kv)});return block}
+function connectionBlocks(conns){conns=conns||{};const states=Object.keys(conns.tcp_states||{}).sort().map(function(k){return [k,String(conns.tcp_states[k])]});const listening=(conns.listening||[]).map(function(p){return [p.proto+' '+(p.address.indexOf(':')>=0?'['+p.address+']':p.address)+':'+p.port,'LISTEN']});const peers=(conns.top_peers||[]).map(function(p){return [p.address,String(p.count)]});return [detailBlock('TCP 状态',states),detailBlock('监听端口',listening),detailBlock('连接最多的对端',peers)]}
+function processBlocks(top){top=top||{};const row=function(p){return [p.pid+' '+p.name+(p.user?' ('+p.user+')':''),(p.cpu_percent||0).toFixed(1)+'% · '+bytesText(p.rss)+' · '+p.threads+' 线程',p.command||p.name]};return [detailBlock('CPU 占用最高进程',(top.by_cpu||[]).map(row)),detailBlock('内存占用最高进程',(top.by_memory||[]).map(row))]}
+function containerBlocks(list){list=list||[];if(!list.length)return [];return [detailBlock('容器',list.map(function(c){return [c.name,(c.cpu_percent||0).toFixed(1)+'% · '+bytesText(c.memory_used)+(c.memory_limit?' / '+bytesText(c.memory_limit):'')+' · '+c.pids+' PIDs · IO '+bytesText(c.read_rate)+'/s ↓ '+bytesText(c.write_rate)+'/s ↑',c.runtime+' '+c.id+(c.image?' · '+c.image:'')]}))]}
+function serviceBlocks(list){list=list||[];if(!list.length)return [];return [detailBlock('服务',list.map(function(svc){return [svc.name,svc.active_state+'/'+svc.sub_state+' · 重启 '+(svc.restarts||0)+' 次'+(svc.memory?' · '+bytesText(svc.memory):''),'load: '+svc.load_state]}))]}
+function diskHealthBlocks(raid,smart){const out=[];if(raid&&raid.length)out.push(detailBlock('软 RAID',raid.map(function(a){return [a.name+' '+(a.level||''),a.state+' · '+a.active_devices+'/'+a.devices+(a.degraded?' · 降级':'')+(a.sync_action?' · '+a.sync_action+' '+a.sync_percent.toFixed(1)+'%':''),(a.failed||[]).length?'故障成员: '+a.failed.join(', '):'']})));if(smart&&smart.length)out.push(detailBlock('SMART',smart.map(function(d){return [d.device,(d.passed?'PASSED':'FAILED')+' · 重映射 '+d.reallocated_sectors+' · 待映射 '+d.pending_sectors+(d.wear_percent?' · 磨损 '+d.wear_percent.toFixed(0)+'%':'')+(d.temperature?' · '+d.temperature+'°C':''),[d.model,d.serial].filter(Boolean).join(' ')]})));return out}
+function probeBlocks(probes){if(!probes||!probes.length)return [];return [detailBlock('探测',probes.map(function(p){let v=p.error&&p.lost===p.sent?'失败':p.latency_ms.toFixed(1)+' ms · 丢包 '+p.loss_percent.toFixed(0)+'%';if(p.status_code)v+=' · HTTP '+p.status_code;if(p.jitter_ms)v+=' · 抖动 '+p.jitter_ms.toFixed(1)+' ms';return [(p.name||p.target)+' ('+p.type+')',v,[p.target,p.dns_ms?'DNS '+p.dns_ms+' ms':'',p.connect_ms?'连接 '+p.connect_ms+' ms':'',p.tls_ms?'TLS '+p.tls_ms+' ms':'',p.first_byte_ms?'首字节 '+p.first_byte_ms+' ms':'',p.error||''].filter(Boolean).join(' · ')]}))]}
+async function showNodeDetail(id){try{const m=await api('/api/admin/node?node_id='+encodeURIComponent(id));nodeDetailTitle.textContent='节点详情 · '+id;nodeDetailBody.replaceChildren.apply(nodeDetailBody,serviceBlocks(m.services).concat(connectionBlocks(m.connections),processBlocks(m.top_processes),containerBlocks(m.containers),diskHealthBlocks(m.raid,m.smart),probeBlocks(m.probes),agentBlocks(m)));nodeDetail.classList.remove('hidden');nodeDetail.scrollIntoView({behavior:'smooth',block:'start'})}catch(e){toast(e.message)}}
+function hideNodeDetail(){nodeDetail.classList.add('hidden')}
+function editNode(id){const n=(window.nodeCache||[]).find(function(x){return x.node_id===id})||{};const info=n.info||{};editNodeName.value=id;editDisplayName.value=info.display_name||'';editSeller.value=info.seller||'';editPrice.value=info.price||'';editCycle.value=info.cycle||'';editBandwidth.value=info.bandwidth||'';editTraffic.value=info.traffic||'';editTrafficResetDay.value=normalizeResetDay(info.traffic_reset_day);editDueTime.value=dateValue(info.due_time);editBuyUrl.value=info.buy_url||'';editShowPurchase.checked=!!info.show_purchase_info;editGroup.value=info.group||'';editTags.value=(info.tags||[]).join(', ');editVisibility.value=info.visibility||'';editPublicName.value=info.public_name||'';editRedactHost.checked=!!info.redact_host;editInfo.classList.remove('hidden');editInfo.scrollIntoView({behavior:'smooth',block:'start'})}
+async function bulkTags(){const nodes=(window.nodeCache||[]).map(function(n){return n.node_id});if(!nodes.length){toast('列表中没有节点');return}const req={nodes:nodes,add:splitList(bulkAdd.value),remove:splitList(bulkRemove.value)};if(bulkGroup.value.trim())req.group=bulkGroup.value.trim();if(!confirm('修改列表中 '+nodes.length+' 个节点的分组和标签？'))return;try{const res=await api('/api/admin/nodes/tags',{method:'POST',headers:{'Content-Type':'application/json'},body:JSON.stringify(req)});bulkGroup.value='';bulkAdd.value='';bulkRemove.value='';await loadNodes();toast('已修改 '+res.updated+' 个节点')}catch(e){toast(e.message)}}
+async function runBulk(){const ids=bulkNodes.value.split(/[\s,]+/).filter(Boolean);if(!ids.length){toast('请输入 Node ID');return}const op=bulkOp.value;let info=null;if(op==='update'){info={};if(bulkSeller.value.trim())info.seller=bulkSeller.value.trim();if(bulkPrice.value.trim())info.price=bulkPrice.value.trim();if(bulkDueTime.value){if(!validDueDate(bulkDueTime.value)){toast('到期时间年份只能是 4 位');return}info.due_time=new Date(bulkDueTime.value+'T00:00:00').getTime()}if(bulkResetDay.value)info.traffic_reset_day=normalizeResetDay(bulkResetDay.value);if(!Object.keys(info).length){toast('请填写要修改的字段');return}}if(op==='delete'&&!confirm('确定删除 '+ids.length+' 个节点?'))return;const ops=ids.map(function(id){const o={op:op,node_id:id};if(info)o.info=info;return o});try{const res=await api('/api/admin/nodes/bulk',{method:'POST',headers:{'Content-Type':'application/json'},body:JSON.stringify({atomic:bulkAtomic.checked,ops:ops})});renderBulkResults(res.results||[]);await loadNodes()}catch(e){toast(e.message)}}
+function renderBulkResults(results){bulkResults.replaceChildren();const failed=results.filter(function(r){return !r.ok});const summary=document.createElement('p');summary.className='muted';summary.textContent='成功 '+(results.length-failed.length)+' 个，失败 '+failed.length+' 个';bulkResults.appendChild(summary);failed.forEach(function(r){const p=document.createElement('p');p.className='off';p.textContent=r.node_id+': '+r.error;bulkResults.appendChild(p)});const commands=results.filter(function(r){return r.commands}).map(function(r){return '# '+r.node_id+'\n'+r.commands.linux+'\n'+r.commands.windows});const note=results.find(function(r){return r.commands&&r.commands.note});bulkCommands.value=(note?'# '+note.commands.note+'\n\n':'')+commands.join('\n\n');bulkCommands.classList.toggle('hidden',!commands.length)}
 function hideEditInfo(){editInfo.classList.add('hidden')}
-async function saveNodeInfo(){if(!validDueDate(editDueTime.value)){toast('到期时间年份只能是 4 位');return}try{const due=editDueTime.value?new Date(editDueTime.value+'T00:00:00').getTime():0;await api('/info',{method:'POST',headers:{'Content-Type':'application/json'},body:JSON.stringify({name:editNodeName.value,seller:editSeller.value,price:editPrice.value,cycle:editCycle.value,bandwidth:editBandwidth.value,traffic:editTraffic.value,traffic_reset_day:normalizeResetDay(editTrafficResetDay.value),buy_url:editBuyUrl.value,due_time:due,show_purchase_info:editShowPurchase.checked})});hideEditInfo();await loadNodes();toast('主机信息已保存')}catch(e){toast(e.message)}}
+async function saveNodeInfo(){if(!validDueDate(editDueTime.value)){toast('到期时间年份只能是 4 位');return}try{const due=editDueTime.value?new Date(editDueTime.value+'T00:00:00').getTime():0;await api('/info',{method:'POST',headers:{'Content-Type':'application/json'},body:JSON.stringify({name:editNodeName.value,display_name:editDisplayName.value.trim(),seller:editSeller.value,price:editPrice.value,cycle:editCycle.value,bandwidth:editBandwidth.value,traffic:editTraffic.value,traffic_reset_day:normalizeResetDay(editTrafficResetDay.value),buy_url:editBuyUrl.value,due_time:due,show_purchase_info:editShowPurchase.checked,group:editGroup.value.trim(),tags:splitList(editTags.value),visibility:editVisibility.value,public_name:editPublicName.value.trim(),redact_host:editRedactHost.checked})});hideEditInfo();await loadNodes();toast('主机信息已保存')}catch(e){toast(e.message)}}
+async function renameNode(id){const next=(prompt('新的节点 ID（旧 ID '+id+' 会保留为别名）',id)||'').trim();if(!next||next===id)return;try{await api('/api/admin/nodes/rename',{method:'POST',headers:{'Content-Type':'application/json'},body:JSON.stringify({node_id:id,new_node_id:next})});await loadNodes();toast('节点已改名为 '+next)}catch(e){toast(e.message)}}
+async function shareNode(id){const hours=(prompt('分享 '+id+' 的只读链接，有效小时数（最长 8760）','168')||'').trim();if(!hours)return;try{const r=await api('/api/admin/nodes/share',{method:'POST',headers:{'Content-Type':'application/json'},body:JSON.stringify({node_id:id,hours:parseInt(hours,10)||0})});prompt('分享链接，有效至 '+new Date(r.expires_at*1000).toLocaleString(),r.url)}catch(e){toast(e.message)}}
+async function resetShares(){if(!confirm('撤销已发出的全部分享链接？'))return;try{await api('/api/admin/nodes/share/reset',{method:'POST'});toast('分享链接已全部失效')}catch(e){toast(e.message)}}
 async function deleteNode(id){if(!confirm('确定删除 '+id+' ?'))return;try{await api('/delete',{method:'POST',headers:{'Content-Type':'application/json'},body:JSON.stringify({name:id})});await loadNodes();toast('节点已删除')}catch(e){toast(e.message)}}
 async function copyText(id){const el=document.getElementById(id);await navigator.clipboard.writeText(el.value);toast('已复制')}
 check();
@@ -84,18 +131,20 @@ set -eu
 SERVER=""
 TOKEN=""
 NODE_ID=""
+RELEASE_KEY=""
 
 while [ "$#" -gt 0 ]; do
   case "$1" in
     --server) SERVER="$2"; shift 2 ;;
     --token) TOKEN="$2"; shift 2 ;;
     --node-id) NODE_ID="$2"; shift 2 ;;
+    --release-key) RELEASE_KEY="$2"; shift 2 ;;
     *) echo "unknown option: $1" >&2; exit 2 ;;
   esac
 done
 
 if [ -z "$SERVER" ] || [ -z "$TOKEN" ] || [ -z "$NODE_ID" ]; then
-  echo "usage: install-agent-linux.sh --server URL --token TOKEN --node-id NODE" >&2
+  echo "usage: install-agent-linux.sh --server URL --token TOKEN --node-id NODE [--release-key KEY]" >&2
   exit 2
 fi
 
@@ -107,12 +156,42 @@ case "$(uname -m)" in
   *) echo "unsupported arch: $(uname -m)" >&2; exit 1 ;;
 esac
 
+DOWNLOAD="%s/download"
+BIN="vps-agent-linux-$ARCH"
+
+# verify_release checks the downloaded binary against the signed release
+# manifest and stops the install unless both the signature and the checksum
+# match. The signature needs OpenSSL 3 (pkeyutl -rawin).
+verify_release() {
+  if ! openssl pkeyutl -help 2>&1 | grep -q -- -rawin; then
+    echo "OpenSSL 3 is required to verify the release signature; install it or rerun without --release-key" >&2
+    exit 1
+  fi
+  curl -fsSL "$DOWNLOAD/manifest.json" -o "$TMP/manifest.json"
+  curl -fsSL "$DOWNLOAD/manifest.json.sig" -o "$TMP/manifest.json.sig"
+  # DER SubjectPublicKeyInfo for ed25519: a fixed prefix and the raw key.
+  { printf '\060\052\060\005\006\003\053\145\160\003\041\000'; printf '%%s' "$RELEASE_KEY" | base64 -d; } >"$TMP/release.der"
+  base64 -d "$TMP/manifest.json.sig" >"$TMP/manifest.sig"
+  if ! openssl pkeyutl -verify -pubin -keyform DER -inkey "$TMP/release.der" -rawin -in "$TMP/manifest.json" -sigfile "$TMP/manifest.sig" >/dev/null 2>&1; then
+    echo "release manifest signature does not match the release key" >&2
+    exit 1
+  fi
+  SUM="$(sha256sum "$TMP/vps-agent" | cut -d' ' -f1)"
+  if ! grep -A1 "\"name\": \"$BIN\"" "$TMP/manifest.json" | grep -q "\"sha256\": \"$SUM\""; then
+    echo "$BIN does not match the release manifest" >&2
+    exit 1
+  fi
+}
+
 install -d /etc/vps-agent /usr/local/bin
 umask 077
-TMP="$(mktemp)"
-curl -fsSL "%s/download/vps-agent-linux-$ARCH" -o "$TMP"
-install -m 0755 "$TMP" /usr/local/bin/vps-agent
-rm -f "$TMP"
+TMP="$(mktemp -d)"
+trap 'rm -rf "$TMP"' EXIT
+curl -fsSL "$DOWNLOAD/$BIN" -o "$TMP/vps-agent"
+if [ -n "$RELEASE_KEY" ]; then
+  verify_release
+fi
+install -m 0755 "$TMP/vps-agent" /usr/local/bin/vps-agent
 
 cat >/etc/vps-agent/config.env <<EOF
 SERVER=$SERVER
@@ -125,6 +204,10 @@ MOUNTS=auto
 NETWORK_EXCLUDE=lo,docker*,veth*,br-*
 DISK_EXCLUDE_FS=tmpfs,devtmpfs,overlay,squashfs,proc,sysfs,cgroup,cgroup2
 EOF
+if [ -n "$RELEASE_KEY" ]; then
+  echo "RELEASE_KEY=$RELEASE_KEY" >>/etc/vps-agent/config.env
+  echo "release key pinned on first use: later updates must be signed with it"
+fi
 chmod 600 /etc/vps-agent/config.env
 
 cat >/etc/systemd/system/vps-agent.service <<'EOF'
@@ -155,7 +238,8 @@ function Install-VpsAgent {
   param(
     [Parameter(Mandatory=$true)][string]$Server,
     [Parameter(Mandatory=$true)][string]$Token,
-    [Parameter(Mandatory=$true)][string]$NodeId
+    [Parameter(Mandatory=$true)][string]$NodeId,
+    [string]$ReleaseKey = ""
   )
 
   $identity = [Security.Principal.WindowsIdentity]::GetCurrent()
@@ -177,8 +261,18 @@ function Install-VpsAgent {
   New-Item -ItemType Directory -Force -Path $configDir | Out-Null
   icacls $configDir /inheritance:r /grant:r "Administrators:(OI)(CI)F" "SYSTEM:(OI)(CI)F" | Out-Null
 
+  $download = "%s/download"
+  $bin = "vps-agent-windows-$arch.exe"
   $tmp = Join-Path $env:TEMP "vps-agent.exe"
-  Invoke-WebRequest "%s/download/vps-agent-windows-$arch.exe" -OutFile $tmp -UseBasicParsing
+  Invoke-WebRequest "$download/$bin" -OutFile $tmp -UseBasicParsing
+  if ($ReleaseKey) {
+    try {
+      Test-VpsAgentRelease -Download $download -Bin $bin -Path $tmp -ReleaseKey $ReleaseKey
+    } catch {
+      Remove-Item $tmp -Force
+      throw
+    }
+  }
   Copy-Item $tmp "$installDir\\vps-agent.exe" -Force
   Remove-Item $tmp -Force
 
@@ -191,6 +285,10 @@ DISK_INTERVAL=30s
 CONNECTION_INTERVAL=60s
 MOUNTS=auto
 "@
+  if ($ReleaseKey) {
+    $configText += [Environment]::NewLine + "RELEASE_KEY=$ReleaseKey"
+    Write-Host "release key pinned on first use: later updates must be signed with it"
+  }
   [System.IO.File]::WriteAllText("$configDir\config.env", $configText, (New-Object System.Text.UTF8Encoding($false)))
   icacls "$configDir\config.env" /inheritance:r /grant:r "Administrators:F" "SYSTEM:F" | Out-Null
 
@@ -207,9 +305,43 @@ MOUNTS=auto
   $quote = [char]34
   $binPath = $quote + $installDir + "\vps-agent.exe" + $quote + " run --config " + $quote + $configDir + "\config.env" + $quote
   New-Service -Name "vps-agent" -BinaryPathName $binPath -DisplayName "VPS Monitor Agent" -StartupType Automatic | Out-Null
+  sc.exe failure vps-agent reset= 86400 actions= restart/5000/restart/5000/restart/5000 | Out-Null
+  sc.exe failureflag vps-agent 1 | Out-Null
   Start-Service vps-agent
   Write-Host "vps-agent installed: $NodeId -> $Server"
 }
+
+# Test-VpsAgentRelease checks a downloaded binary against the signed release
+# manifest and throws unless both the signature and the checksum match.
+# Windows PowerShell has no ed25519, so the signature is verified with an
+# OpenSSL 3 found on PATH.
+function Test-VpsAgentRelease {
+  param([string]$Download, [string]$Bin, [string]$Path, [string]$ReleaseKey)
+
+  $dir = Join-Path $env:TEMP ("vps-agent-release-" + [guid]::NewGuid())
+  New-Item -ItemType Directory -Force -Path $dir | Out-Null
+  try {
+    $manifestPath = Join-Path $dir "manifest.json"
+    $signaturePath = Join-Path $dir "manifest.json.sig"
+    Invoke-WebRequest "$Download/manifest.json" -OutFile $manifestPath -UseBasicParsing
+    Invoke-WebRequest "$Download/manifest.json.sig" -OutFile $signaturePath -UseBasicParsing
+    $help = cmd /c "openssl pkeyutl -help 2>&1"
+    if (-not (($help | Out-String) -match "-rawin")) {
+      throw "OpenSSL 3 is required on PATH to verify the release signature; install it or rerun without -ReleaseKey"
+    }
+    $prefix = [byte[]](0x30, 0x2a, 0x30, 0x05, 0x06, 0x03, 0x2b, 0x65, 0x70, 0x03, 0x21, 0x00)
+    [System.IO.File]::WriteAllBytes("$dir\\release.der", [byte[]]($prefix + [Convert]::FromBase64String($ReleaseKey.Trim())))
+    [System.IO.File]::WriteAllBytes("$dir\\manifest.sig", [Convert]::FromBase64String((Get-Content $signaturePath -Raw).Trim()))
+    cmd /c "openssl pkeyutl -verify -pubin -keyform DER -inkey ""$dir\\release.der"" -rawin -in ""$manifestPath"" -sigfile ""$dir\\manifest.sig"" >nul 2>&1"
+    if ($LASTEXITCODE -ne 0) { throw "release manifest signature does not match the release key" }
+    $manifest = Get-Content $manifestPath -Raw | ConvertFrom-Json
+    $entry = $manifest.files | Where-Object { $_.name -eq $Bin }
+    $sum = (Get-FileHash $Path -Algorithm SHA256).Hash.ToLower()
+    if (-not $entry -or $entry.sha256 -ne $sum) { throw "$Bin does not match the release manifest" }
+  } finally {
+    Remove-Item $dir -Recurse -Force -ErrorAction SilentlyContinue
+  }
+}
 `
 
 const linuxUninstallTemplate = `#!/usr/bin/env sh
@@ -221,7 +353,7 @@ if [ "$(id -u)" -ne 0 ]; then
 fi
 
 systemctl disable --now vps-agent 2>/dev/null || true
-rm -f /etc/systemd/system/vps-agent.service /usr/local/bin/vps-agent
+rm -f /etc/systemd/system/vps-agent.service /usr/local/bin/vps-agent /usr/local/bin/vps-agent.old /usr/local/bin/vps-agent.update.json
 rm -rf /etc/vps-agent
 systemctl daemon-reload 2>/dev/null || true
 echo "vps-agent uninstalled"
@@ -2,11 +2,16 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"time"
+
+	"vps-agent/internal/config"
+	serverapp "vps-agent/internal/server/application"
+	serverdomain "vps-agent/internal/server/domain"
 )
 
 func (s *Server) handleAdminLogin(w http.ResponseWriter, r *http.Request) {
@@ -76,7 +81,22 @@ func (s *Server) handleAdminNodes(w http.ResponseWriter, r *http.Request) {
 	}
 	switch r.Method {
 	case http.MethodGet:
-		writeJSON(w, s.store.AdminNodes(s.cfg.OfflineWait))
+		tag, err := serverdomain.NormalizeTag(r.URL.Query().Get("tag"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		nodes := s.store.AdminNodes(s.cfg.OfflineWait)
+		if tag != "" {
+			matched := nodes[:0]
+			for _, node := range nodes {
+				if node.Info.HasLabel(tag) {
+					matched = append(matched, node)
+				}
+			}
+			nodes = matched
+		}
+		writeJSON(w, nodes)
 	case http.MethodPost:
 		var req struct {
 			NodeID string `json:"node_id"`
@@ -101,6 +121,41 @@ func (s *Server) handleAdminNodes(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *Server) handleAdminNode(w http.ResponseWriter, r *http.Request) {
+	if !s.adminAuthorized(r) {
+		http.Error(w, "admin login required", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+	nodeID := strings.TrimSpace(r.URL.Query().Get("node_id"))
+	if !validNodeID(nodeID) {
+		http.Error(w, "invalid node_id", http.StatusBadRequest)
+		return
+	}
+	metrics, ok := s.store.Report(nodeID)
+	if !ok {
+		http.Error(w, "node has not reported yet", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, metrics)
+}
+
+// handleAdminFleet groups nodes by the agent version they last reported.
+func (s *Server) handleAdminFleet(w http.ResponseWriter, r *http.Request) {
+	if !s.adminAuthorized(r) {
+		http.Error(w, "admin login required", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+	writeJSON(w, serverapp.FleetVersions(s.store.AdminNodes(s.cfg.OfflineWait)))
+}
+
 func (s *Server) handleAdminNodesExport(w http.ResponseWriter, r *http.Request) {
 	if !s.adminAuthorized(r) {
 		http.Error(w, "admin login required", http.StatusUnauthorized)
@@ -110,8 +165,23 @@ func (s *Server) handleAdminNodesExport(w http.ResponseWriter, r *http.Request)
 		methodNotAllowed(w)
 		return
 	}
+	tag, err := serverdomain.NormalizeTag(r.URL.Query().Get("tag"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	backup := s.store.ExportNodes()
+	if tag != "" {
+		records := make([]NodeBackupRecord, 0, len(backup.Nodes))
+		for _, record := range backup.Nodes {
+			if record.Info.HasLabel(tag) {
+				records = append(records, record)
+			}
+		}
+		backup.Nodes = records
+	}
 	w.Header().Set("Content-Disposition", "attachment; filename=monitor-nodes.json")
-	writeJSON(w, s.store.ExportNodes())
+	writeJSON(w, backup)
 }
 
 func (s *Server) handleAdminNodesImport(w http.ResponseWriter, r *http.Request) {
@@ -133,6 +203,12 @@ func (s *Server) handleAdminNodesImport(w http.ResponseWriter, r *http.Request)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	for i := range backup.Nodes {
+		if err := backup.Nodes[i].Info.Normalize(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 	imported, err := s.store.ImportNodes(backup, s.cfg.MaxNodes)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -142,6 +218,225 @@ func (s *Server) handleAdminNodesImport(w http.ResponseWriter, r *http.Request)
 	writeJSON(w, map[string]int{"imported": imported})
 }
 
+// handleAdminNodeTags edits the group and tags of several nodes at once. The
+// nodes are picked by ID or by a tag they already carry; Group, when set,
+// replaces the group, and Add and Remove change the tags. The edits go
+// through BulkNodes as one atomic batch.
+func (s *Server) handleAdminNodeTags(w http.ResponseWriter, r *http.Request) {
+	if !s.adminAuthorized(r) {
+		http.Error(w, "admin login required", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+	if !s.validAdminOrigin(r) {
+		http.Error(w, "invalid request origin", http.StatusForbidden)
+		return
+	}
+	var req struct {
+		Nodes  []string `json:"nodes"`
+		Tag    string   `json:"tag"`
+		Group  *string  `json:"group"`
+		Add    []string `json:"add"`
+		Remove []string `json:"remove"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tag, err := serverdomain.NormalizeTag(req.Tag)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if (len(req.Nodes) == 0) == (tag == "") {
+		http.Error(w, "pick nodes either by id or by tag", http.StatusBadRequest)
+		return
+	}
+	add, err := serverdomain.NormalizeTags(req.Add)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	remove, err := serverdomain.NormalizeTags(req.Remove)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	picked := map[string]bool{}
+	for _, nodeID := range req.Nodes {
+		picked[strings.TrimSpace(nodeID)] = true
+	}
+	var ops []BulkOp
+	for _, node := range s.store.AdminNodes(s.cfg.OfflineWait) {
+		if !picked[node.NodeID] && (tag == "" || !node.Info.HasLabel(tag)) {
+			continue
+		}
+		tags := make([]string, 0, len(node.Info.Tags)+len(add))
+		for _, have := range node.Info.Tags {
+			if !(HostInfo{Tags: remove}).HasLabel(have) {
+				tags = append(tags, have)
+			}
+		}
+		tags = append(tags, add...)
+		op := BulkOp{Op: serverdomain.BulkUpdate, NodeID: node.NodeID, Info: &serverdomain.HostInfoPatch{Group: req.Group, Tags: &tags}}
+		if err := op.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("%s: %v", node.NodeID, err), http.StatusBadRequest)
+			return
+		}
+		ops = append(ops, op)
+	}
+	updated := 0
+	if len(ops) > 0 {
+		results, err := s.store.BulkNodes(ops, s.cfg.MaxNodes, true)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, result := range results {
+			if result.OK {
+				updated++
+			}
+		}
+	}
+	s.cache.MarkDirty()
+	writeJSON(w, map[string]int{"updated": updated})
+}
+
+// handleAdminNodeRename moves a node to a new node ID. The agent keeps
+// working under the old one, which becomes an alias, and switches over the
+// next time it fetches its config.
+func (s *Server) handleAdminNodeRename(w http.ResponseWriter, r *http.Request) {
+	if !s.adminAuthorized(r) {
+		http.Error(w, "admin login required", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+	if !s.validAdminOrigin(r) {
+		http.Error(w, "invalid request origin", http.StatusForbidden)
+		return
+	}
+	var req struct {
+		NodeID    string `json:"node_id"`
+		NewNodeID string `json:"new_node_id"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	oldID, newID := strings.TrimSpace(req.NodeID), strings.TrimSpace(req.NewNodeID)
+	if !validNodeID(oldID) || !validNodeID(newID) {
+		http.Error(w, "invalid node_id", http.StatusBadRequest)
+		return
+	}
+	if oldID == newID {
+		http.Error(w, "new node_id is the same as the old one", http.StatusBadRequest)
+		return
+	}
+	err := s.store.RenameNode(oldID, newID)
+	switch {
+	case errors.Is(err, serverdomain.ErrUnknownNode):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, serverdomain.ErrNodeExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.cache.MarkDirty()
+	writeJSON(w, map[string]string{"node_id": newID})
+}
+
+// handleAdminNodeShare signs a read-only link to one node. Links name the
+// node's internal ID, so they survive a rename; deleting the node or
+// resetting the share key revokes them.
+func (s *Server) handleAdminNodeShare(w http.ResponseWriter, r *http.Request) {
+	if !s.adminAuthorized(r) {
+		http.Error(w, "admin login required", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+	if !s.validAdminOrigin(r) {
+		http.Error(w, "invalid request origin", http.StatusForbidden)
+		return
+	}
+	var req struct {
+		NodeID string `json:"node_id"`
+		Hours  int    `json:"hours"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	nodeID := strings.TrimSpace(req.NodeID)
+	if !validNodeID(nodeID) {
+		http.Error(w, "invalid node_id", http.StatusBadRequest)
+		return
+	}
+	ttl := time.Duration(req.Hours) * time.Hour
+	if req.Hours == 0 {
+		ttl = serverdomain.DefaultShareTTL
+	}
+	if ttl <= 0 || ttl > serverdomain.MaxShareTTL {
+		http.Error(w, fmt.Sprintf("hours must be between 1 and %d", int(serverdomain.MaxShareTTL.Hours())), http.StatusBadRequest)
+		return
+	}
+	nodeID = s.store.ResolveNodeID(nodeID)
+	key := ""
+	for _, node := range s.store.AdminNodes(s.cfg.OfflineWait) {
+		if node.NodeID == nodeID {
+			key = node.ID
+		}
+	}
+	if key == "" {
+		http.Error(w, serverdomain.ErrUnknownNode.Error(), http.StatusNotFound)
+		return
+	}
+	secret, err := s.store.ShareSecret()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	expires := time.Now().Add(ttl)
+	writeJSON(w, map[string]any{
+		"node_id":    nodeID,
+		"url":        s.externalBase(r) + "/s/" + serverdomain.SignShare(secret, key, expires),
+		"expires_at": expires.Unix(),
+	})
+}
+
+// handleAdminNodeShareReset replaces the share key, revoking every share
+// link handed out so far.
+func (s *Server) handleAdminNodeShareReset(w http.ResponseWriter, r *http.Request) {
+	if !s.adminAuthorized(r) {
+		http.Error(w, "admin login required", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+	if !s.validAdminOrigin(r) {
+		http.Error(w, "invalid request origin", http.StatusForbidden)
+		return
+	}
+	if err := s.store.ResetShareSecret(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]bool{"ok": true})
+}
+
 func (s *Server) handleAdminInstallCommand(w http.ResponseWriter, r *http.Request) {
 	if !s.adminAuthorized(r) {
 		http.Error(w, "admin login required", http.StatusUnauthorized)
@@ -171,16 +466,16 @@ func (s *Server) handleAdminInstallCommand(w http.ResponseWriter, r *http.Reques
 		return
 	}
 	base := s.externalBase(r)
-	linux := fmt.Sprintf("curl -fsSL %s/install/agent-linux.sh | sudo sh -s -- --server %s --token %s --node-id %s", base, base, shellQuote(token), shellQuote(nodeID))
-	windows := fmt.Sprintf("powershell -ExecutionPolicy Bypass -Command \"[Net.ServicePointManager]::SecurityProtocol = [Net.SecurityProtocolType]::Tls12; iwr %s/install/agent-windows.ps1 -UseBasicParsing | iex; Install-VpsAgent -Server '%s' -Token '%s' -NodeId '%s'\"", base, base, psQuote(token), psQuote(nodeID))
+	linux, windows := installCommands(base, nodeID, token)
 	linuxUninstall := fmt.Sprintf("curl -fsSL %s/uninstall/agent-linux.sh | sudo sh", base)
 	windowsUninstall := fmt.Sprintf("powershell -ExecutionPolicy Bypass -Command \"[Net.ServicePointManager]::SecurityProtocol = [Net.SecurityProtocolType]::Tls12; iwr %s/uninstall/agent-windows.ps1 -UseBasicParsing | iex\"", base)
+	note := installNote()
 	if platform == "linux" {
-		writeJSON(w, map[string]string{"command": linux})
+		writeJSON(w, map[string]string{"command": linux, "note": note})
 		return
 	}
 	if platform == "windows" {
-		writeJSON(w, map[string]string{"command": windows})
+		writeJSON(w, map[string]string{"command": windows, "note": note})
 		return
 	}
 	if platform == "linux-uninstall" {
@@ -191,7 +486,104 @@ func (s *Server) handleAdminInstallCommand(w http.ResponseWriter, r *http.Reques
 		writeJSON(w, map[string]string{"command": windowsUninstall})
 		return
 	}
-	writeJSON(w, map[string]string{"linux": linux, "windows": windows, "linux_uninstall": linuxUninstall, "windows_uninstall": windowsUninstall})
+	writeJSON(w, map[string]string{"linux": linux, "windows": windows, "linux_uninstall": linuxUninstall, "windows_uninstall": windowsUninstall, "note": note})
+}
+
+// installNote tells the admin what the release key in the install commands
+// means: the installer trusts whatever key this server hands it, and the
+// agent then refuses updates not signed with that key. It is empty when the
+// release carries no key.
+func installNote() string {
+	if releasePublicKey() == "" {
+		return ""
+	}
+	return "安装命令带有发布公钥，安装时固定（首次使用即信任）：安装时没有 OpenSSL 3 或签名校验失败会中止安装，之后的升级必须用同一把密钥签名。"
+}
+
+// installCommands returns the Linux and Windows one-line installers that
+// register nodeID with token against base.
+func installCommands(base, nodeID, token string) (linux, windows string) {
+	linuxKey, windowsKey := "", ""
+	if key := releasePublicKey(); key != "" {
+		linuxKey = " --release-key " + shellQuote(key)
+		windowsKey = " -ReleaseKey '" + psQuote(key) + "'"
+	}
+	linux = fmt.Sprintf("curl -fsSL %s/install/agent-linux.sh | sudo sh -s -- --server %s --token %s --node-id %s%s", base, base, shellQuote(token), shellQuote(nodeID), linuxKey)
+	windows = fmt.Sprintf("powershell -ExecutionPolicy Bypass -Command \"[Net.ServicePointManager]::SecurityProtocol = [Net.SecurityProtocolType]::Tls12; iwr %s/install/agent-windows.ps1 -UseBasicParsing | iex; Install-VpsAgent -Server '%s' -Token '%s' -NodeId '%s'%s\"", base, base, psQuote(token), psQuote(nodeID), windowsKey)
+	return linux, windows
+}
+
+// maxBulkOps bounds one bulk request; a fleet larger than this is edited in
+// several requests.
+const maxBulkOps = 2000
+
+// handleAdminNodesBulk runs several node operations in one request and
+// reports the outcome of each. Malformed items reject the whole request
+// before anything is written; items that fail against the stored state
+// (an unknown node, the node limit) are reported and, unless atomic is set,
+// do not stop the others. Token items come back with fresh install commands.
+func (s *Server) handleAdminNodesBulk(w http.ResponseWriter, r *http.Request) {
+	if !s.adminAuthorized(r) {
+		http.Error(w, "admin login required", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+	if !s.validAdminOrigin(r) {
+		http.Error(w, "invalid request origin", http.StatusForbidden)
+		return
+	}
+	var req struct {
+		Atomic bool     `json:"atomic"`
+		Ops    []BulkOp `json:"ops"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4<<20)).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Ops) == 0 || len(req.Ops) > maxBulkOps {
+		http.Error(w, fmt.Sprintf("ops must list 1 to %d items", maxBulkOps), http.StatusBadRequest)
+		return
+	}
+	tokens := make([]string, len(req.Ops))
+	for i := range req.Ops {
+		op := &req.Ops[i]
+		op.Op = strings.TrimSpace(op.Op)
+		op.NodeID = strings.TrimSpace(op.NodeID)
+		if !validNodeID(op.NodeID) {
+			http.Error(w, fmt.Sprintf("ops[%d]: invalid node_id", i), http.StatusBadRequest)
+			return
+		}
+		if err := op.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("ops[%d]: %v", i, err), http.StatusBadRequest)
+			return
+		}
+		if op.Op == serverdomain.BulkToken {
+			token, err := newAgentToken()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			tokens[i] = token
+			op.TokenHash = hashToken(token)
+		}
+	}
+	results, err := s.store.BulkNodes(req.Ops, s.cfg.MaxNodes, req.Atomic)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	base := s.externalBase(r)
+	for i := range results {
+		if results[i].OK && tokens[i] != "" {
+			linux, windows := installCommands(base, results[i].NodeID, tokens[i])
+			results[i].Commands = map[string]string{"linux": linux, "windows": windows, "note": installNote()}
+		}
+	}
+	s.cache.MarkDirty()
+	writeJSON(w, map[string][]BulkResult{"results": results})
 }
 
 func (s *Server) handleAdminSettings(w http.ResponseWriter, r *http.Request) {
@@ -226,3 +618,286 @@ func (s *Server) handleAdminSettings(w http.ResponseWriter, r *http.Request) {
 		methodNotAllowed(w)
 	}
 }
+
+// maxProbeTargets keeps the list an agent probes every round small enough
+// to finish well inside the probe interval.
+const maxProbeTargets = 50
+
+// handleAdminProbes reads or replaces the probe targets handed out to agents.
+func (s *Server) handleAdminProbes(w http.ResponseWriter, r *http.Request) {
+	if !s.adminAuthorized(r) {
+		http.Error(w, "admin login required", http.StatusUnauthorized)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		targets := s.store.ProbeTargets()
+		if targets == nil {
+			targets = []ProbeTarget{}
+		}
+		writeJSON(w, targets)
+	case http.MethodPost:
+		if !s.validAdminOrigin(r) {
+			http.Error(w, "invalid request origin", http.StatusForbidden)
+			return
+		}
+		var req []ProbeTarget
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		targets, err := normalizeProbeTargets(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.store.SetProbeTargets(targets); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]bool{"ok": true})
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func normalizeProbeTargets(req []ProbeTarget) ([]ProbeTarget, error) {
+	if len(req) > maxProbeTargets {
+		return nil, fmt.Errorf("too many probe targets, limit is %d", maxProbeTargets)
+	}
+	names := make(map[string]bool, len(req))
+	targets := make([]ProbeTarget, 0, len(req))
+	for _, target := range req {
+		target.Name = strings.TrimSpace(target.Name)
+		target.Type = strings.TrimSpace(target.Type)
+		target.Target = strings.TrimSpace(target.Target)
+		if target.Name == "" || len(target.Name) > 64 || names[target.Name] {
+			return nil, fmt.Errorf("invalid probe name %q", target.Name)
+		}
+		probe := config.Probe{Name: target.Name, Type: target.Type, Target: target.Target, Warn: time.Duration(target.WarnMs) * time.Millisecond}
+		if err := probe.Validate(); err != nil {
+			return nil, err
+		}
+		nodes := target.Nodes[:0]
+		for _, nodeID := range target.Nodes {
+			nodeID = strings.TrimSpace(nodeID)
+			if nodeID == "" {
+				continue
+			}
+			if !validNodeID(nodeID) {
+				return nil, fmt.Errorf("invalid node_id %q", nodeID)
+			}
+			nodes = append(nodes, nodeID)
+		}
+		target.Nodes = nodes
+		if len(target.Nodes) == 0 {
+			target.Nodes = nil
+		}
+		tags, err := serverdomain.NormalizeTags(target.Tags)
+		if err != nil {
+			return nil, err
+		}
+		target.Tags = tags
+		names[target.Name] = true
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// adminDashboard is a dashboard as the admin console reads and writes it.
+// The password hash stays on the server: reads report HasPassword, and a
+// write sets Password when given, keeps the stored one when HasPassword is
+// still set and removes it otherwise.
+type adminDashboard struct {
+	Slug        string   `json:"slug"`
+	SiteName    string   `json:"site_name"`
+	Nodes       []string `json:"nodes,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Theme       string   `json:"theme,omitempty"`
+	HasPassword bool     `json:"has_password"`
+	Password    string   `json:"password,omitempty"`
+}
+
+// maxDashboardPassword bounds the input hashed on every dashboard login.
+const maxDashboardPassword = 128
+
+// handleAdminDashboards reads or replaces the dashboards served at /d/{slug}.
+func (s *Server) handleAdminDashboards(w http.ResponseWriter, r *http.Request) {
+	if !s.adminAuthorized(r) {
+		http.Error(w, "admin login required", http.StatusUnauthorized)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		dashboards := s.store.Dashboards()
+		out := make([]adminDashboard, len(dashboards))
+		for i, d := range dashboards {
+			out[i] = adminDashboard{Slug: d.Slug, SiteName: d.SiteName, Nodes: d.Nodes, Tags: d.Tags, Theme: d.Theme, HasPassword: d.PasswordHash != ""}
+		}
+		writeJSON(w, out)
+	case http.MethodPost:
+		if !s.validAdminOrigin(r) {
+			http.Error(w, "invalid request origin", http.StatusForbidden)
+			return
+		}
+		var req []adminDashboard
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		dashboards, err := s.normalizeDashboards(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.store.SetDashboards(dashboards); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.cache.MarkDirty()
+		writeJSON(w, map[string]bool{"ok": true})
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (s *Server) normalizeDashboards(req []adminDashboard) ([]Dashboard, error) {
+	if len(req) > serverdomain.MaxDashboards {
+		return nil, fmt.Errorf("too many dashboards, limit is %d", serverdomain.MaxDashboards)
+	}
+	stored := make(map[string]string)
+	for _, d := range s.store.Dashboards() {
+		stored[d.Slug] = d.PasswordHash
+	}
+	slugs := make(map[string]bool, len(req))
+	dashboards := make([]Dashboard, 0, len(req))
+	for _, item := range req {
+		d := Dashboard{Slug: item.Slug, SiteName: item.SiteName, Nodes: item.Nodes, Tags: item.Tags, Theme: item.Theme}
+		if err := d.Normalize(); err != nil {
+			return nil, err
+		}
+		if slugs[d.Slug] {
+			return nil, fmt.Errorf("duplicate dashboard slug %q", d.Slug)
+		}
+		for _, nodeID := range d.Nodes {
+			if !validNodeID(nodeID) {
+				return nil, fmt.Errorf("invalid node_id %q", nodeID)
+			}
+		}
+		switch {
+		case len(item.Password) > maxDashboardPassword:
+			return nil, fmt.Errorf("dashboard password is longer than %d bytes", maxDashboardPassword)
+		case item.Password != "":
+			hash, err := serverdomain.HashDashboardPassword(item.Password)
+			if err != nil {
+				return nil, err
+			}
+			d.PasswordHash = hash
+		case item.HasPassword:
+			// A renamed dashboard has no stored password to keep; failing
+			// here stops it from quietly turning public.
+			if d.PasswordHash = stored[d.Slug]; d.PasswordHash == "" {
+				return nil, fmt.Errorf("dashboard %q needs its password entered again", d.Slug)
+			}
+		}
+		slugs[d.Slug] = true
+		dashboards = append(dashboards, d)
+	}
+	return dashboards, nil
+}
+
+// handleAdminAgentConfig reads or replaces the config.env overrides pushed to
+// agents, globally and per node.
+func (s *Server) handleAdminAgentConfig(w http.ResponseWriter, r *http.Request) {
+	if !s.adminAuthorized(r) {
+		http.Error(w, "admin login required", http.StatusUnauthorized)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, s.store.AgentOverrides())
+	case http.MethodPost:
+		if !s.validAdminOrigin(r) {
+			http.Error(w, "invalid request origin", http.StatusForbidden)
+			return
+		}
+		var req AgentOverrides
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		overrides, err := normalizeAgentOverrides(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.store.SetAgentOverrides(overrides); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]bool{"ok": true})
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+// normalizeAgentOverrides upper-cases keys and checks every node's merged
+// settings the same way the agent will, so a bad value is refused here
+// rather than ignored on 200 agents.
+func normalizeAgentOverrides(req AgentOverrides) (AgentOverrides, error) {
+	out := AgentOverrides{Global: normalizeOverrideKeys(req.Global)}
+	if err := checkOverrides(out.Global); err != nil {
+		return AgentOverrides{}, err
+	}
+	for nodeID, values := range req.Nodes {
+		nodeID = strings.TrimSpace(nodeID)
+		if !validNodeID(nodeID) {
+			return AgentOverrides{}, fmt.Errorf("invalid node_id %q", nodeID)
+		}
+		values = normalizeOverrideKeys(values)
+		if len(values) == 0 {
+			continue
+		}
+		merged := map[string]string{}
+		for key, value := range out.Global {
+			merged[key] = value
+		}
+		for key, value := range values {
+			merged[key] = value
+		}
+		if err := checkOverrides(merged); err != nil {
+			return AgentOverrides{}, fmt.Errorf("node %s: %w", nodeID, err)
+		}
+		if out.Nodes == nil {
+			out.Nodes = map[string]map[string]string{}
+		}
+		out.Nodes[nodeID] = values
+	}
+	return out, nil
+}
+
+func normalizeOverrideKeys(values map[string]string) map[string]string {
+	var out map[string]string
+	for key, value := range values {
+		key = strings.ToUpper(strings.TrimSpace(key))
+		if key == "" {
+			continue
+		}
+		if out == nil {
+			out = map[string]string{}
+		}
+		out[key] = strings.TrimSpace(value)
+	}
+	return out
+}
+
+func checkOverrides(values map[string]string) error {
+	cfg, err := config.Default().WithOverrides(values)
+	if err != nil {
+		return err
+	}
+	if cfg.BasicInterval < time.Second {
+		return errors.New("BASIC_INTERVAL must be >= 1s")
+	}
+	return nil
+}
@@ -1,11 +1,19 @@
 package server
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
+
+	"vps-agent/internal/agent"
+	"vps-agent/internal/buildinfo"
+	serverapp "vps-agent/internal/server/application"
 )
 
 func TestAdminInstallCommandAuthAndPlatformResponse(t *testing.T) {
@@ -43,3 +51,537 @@ func TestAdminInstallCommandAuthAndPlatformResponse(t *testing.T) {
 		t.Fatalf("linux command missing node id: %s", body.Command)
 	}
 }
+
+func TestInstallCommandsPinEmbeddedReleaseKey(t *testing.T) {
+	s := newTestServer(t)
+	const key = "11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="
+	manifest := []byte(`{"version":"0.1.0","files":[]}`)
+	previous := agentBinaries
+	agentBinaries = fstest.MapFS{
+		"agent_bins/release.pub":           {Data: []byte(key + "\n")},
+		"agent_bins/manifest.json":         {Data: manifest},
+		"agent_bins/vps-agent-linux-amd64": {Data: []byte("agent build")},
+	}
+	t.Cleanup(func() { agentBinaries = previous })
+
+	token, err := s.sessions.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for platform, want := range map[string]string{
+		"linux":   "--release-key '" + key + "'",
+		"windows": "-ReleaseKey '" + key + "'",
+	} {
+		req := authedAdminRequest(http.MethodPost, "https://monitor.example.com/api/admin/install-command?node_id=node-1&platform="+platform, token)
+		resp := httptest.NewRecorder()
+		s.handleAdminInstallCommand(resp, req)
+		var body struct {
+			Command string `json:"command"`
+			Note    string `json:"note"`
+		}
+		decodeJSONResponse(t, resp, &body)
+		if !strings.Contains(body.Command, want) {
+			t.Fatalf("%s command does not pin the release key: %s", platform, body.Command)
+		}
+		if !strings.Contains(body.Note, "首次使用") {
+			t.Fatalf("%s note does not say the key is pinned on first use: %q", platform, body.Note)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "https://monitor.example.com/download/manifest.json", nil)
+	resp := httptest.NewRecorder()
+	s.handleDownload(resp, req)
+	if resp.Code != http.StatusOK || resp.Body.String() != string(manifest) {
+		t.Fatalf("manifest status = %d body = %s", resp.Code, resp.Body.String())
+	}
+	if got := resp.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("manifest content type = %q", got)
+	}
+	if got := resp.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Fatalf("manifest cache control = %q", got)
+	}
+}
+
+func TestAdminFleetFlagsOutdatedAgents(t *testing.T) {
+	s := newTestServer(t)
+	current := sampleMetrics("node-new", 100, 200)
+	current.AgentVersion = buildinfo.Version
+	current.Agent = &agent.AgentStats{UptimeSec: 60, CollectMs: 3, SendMs: 20}
+	old := sampleMetrics("node-old", 100, 200)
+	old.AgentVersion = "0.0.1"
+	for _, metrics := range []agent.Metrics{current, old} {
+		if err := s.store.UpsertReport(metrics, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	token, err := s.sessions.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := httptest.NewRecorder()
+	s.handleAdminNodes(resp, authedAdminRequest(http.MethodGet, "https://monitor.example.com/api/admin/nodes", token))
+	var nodes []AdminNode
+	decodeJSONResponse(t, resp, &nodes)
+	flags := map[string][]string{}
+	for _, node := range nodes {
+		flags[node.NodeID] = node.AgentFlags
+	}
+	if len(flags["node-new"]) != 0 || len(flags["node-old"]) != 1 || flags["node-old"][0] != "agent 0.0.1 outdated" {
+		t.Fatalf("agent flags = %#v", flags)
+	}
+
+	resp = httptest.NewRecorder()
+	s.handleAdminFleet(resp, authedAdminRequest(http.MethodGet, "https://monitor.example.com/api/admin/fleet", token))
+	var fleet serverapp.Fleet
+	decodeJSONResponse(t, resp, &fleet)
+	if fleet.Current != buildinfo.Version || len(fleet.Versions) != 2 || fleet.Versions[0].Outdated || !fleet.Versions[1].Outdated || fleet.Versions[1].Nodes[0] != "node-old" {
+		t.Fatalf("fleet = %#v", fleet)
+	}
+}
+
+func TestAdminNodeReturnsLatestReport(t *testing.T) {
+	s := newTestServer(t)
+	metrics := sampleMetrics("node-1", 100, 200)
+	metrics.Conns = &agent.Connections{
+		TCP:       3,
+		TCPStates: map[string]int{"LISTEN": 1, "ESTABLISHED": 2},
+		Listening: []agent.ListenPort{{Proto: "tcp", Address: "0.0.0.0", Port: 22}},
+		TopPeers:  []agent.PeerCount{{Address: "203.0.113.7", Count: 2}},
+	}
+	if err := s.store.UpsertReport(metrics, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := httptest.NewRecorder()
+	s.handleAdminNode(resp, httptest.NewRequest(http.MethodGet, "/api/admin/node?node_id=node-1", nil))
+	if resp.Code != http.StatusUnauthorized {
+		t.Fatalf("unauthorized node status = %d", resp.Code)
+	}
+
+	token, err := s.sessions.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = httptest.NewRecorder()
+	s.handleAdminNode(resp, authedAdminRequest(http.MethodGet, "/api/admin/node?node_id=missing", token))
+	if resp.Code != http.StatusNotFound {
+		t.Fatalf("missing node status = %d", resp.Code)
+	}
+
+	resp = httptest.NewRecorder()
+	s.handleAdminNode(resp, authedAdminRequest(http.MethodGet, "/api/admin/node?node_id=node-1", token))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("node status = %d body = %s", resp.Code, resp.Body.String())
+	}
+	var got agent.Metrics
+	decodeJSONResponse(t, resp, &got)
+	if got.Conns == nil || len(got.Conns.Listening) != 1 || got.Conns.TopPeers[0].Address != "203.0.113.7" {
+		t.Fatalf("connections = %#v", got.Conns)
+	}
+
+	resp = httptest.NewRecorder()
+	s.handleAdminNode(resp, authedAdminRequest(http.MethodPost, "/api/admin/node?node_id=node-1", token))
+	if resp.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("post node status = %d", resp.Code)
+	}
+}
+
+func TestAdminProbesValidateAndServeAgentConfig(t *testing.T) {
+	s := newTestServer(t)
+	const nodeID = "US-node-001"
+	const agentToken = "agent-token"
+	if err := s.store.SetNodeToken(nodeID, hashToken(agentToken), 10); err != nil {
+		t.Fatal(err)
+	}
+	token, err := s.sessions.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := httptest.NewRecorder()
+	s.handleAdminProbes(resp, adminRequestWithBody(http.MethodPost, "/api/admin/probes", token, `[{"name":"bad","type":"tcp","target":"origin.example.com"}]`))
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("invalid probe status = %d body = %s", resp.Code, resp.Body.String())
+	}
+
+	body := `[{"name":" CT ","type":"icmp","target":"202.96.209.133","warn_ms":300},{"name":"origin","type":"tcp","target":"origin.example.com:443","nodes":["JP-node-001"]}]`
+	resp = httptest.NewRecorder()
+	s.handleAdminProbes(resp, adminRequestWithBody(http.MethodPost, "/api/admin/probes", token, body))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("save probes status = %d body = %s", resp.Code, resp.Body.String())
+	}
+
+	agentRequest := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/agent/config", nil)
+		req.Header.Set("X-Node-ID", nodeID)
+		req.Header.Set("Authorization", "Bearer "+agentToken)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp := httptest.NewRecorder()
+		s.handleAgentConfig(resp, req)
+		return resp
+	}
+	resp = agentRequest("")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("agent config status = %d body = %s", resp.Code, resp.Body.String())
+	}
+	etag := resp.Header().Get("ETag")
+	var cfg agent.RemoteConfig
+	decodeJSONResponse(t, resp, &cfg)
+	if len(cfg.Probes) != 1 || cfg.Probes[0].Name != "CT" || cfg.Probes[0].WarnMs != 300 || etag != `"`+cfg.Version+`"` {
+		t.Fatalf("agent config = %#v etag = %q", cfg, etag)
+	}
+	if resp := agentRequest(etag); resp.Code != http.StatusNotModified {
+		t.Fatalf("unchanged config status = %d", resp.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/agent/config", nil)
+	req.Header.Set("X-Node-ID", nodeID)
+	resp = httptest.NewRecorder()
+	s.handleAgentConfig(resp, req)
+	if resp.Code != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated agent config status = %d", resp.Code)
+	}
+}
+
+func TestAdminAgentConfigValidatesOverrides(t *testing.T) {
+	s := newTestServer(t)
+	token, err := s.sessions.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, body := range []string{
+		`{"global":{"server":"https://evil.example.com"}}`,
+		`{"global":{"BASIC_INTERVAL":"100ms"}}`,
+		`{"nodes":{"US-node-001":{"DISK_INTERVAL":"soon"}}}`,
+		`{"nodes":{"bad/id":{"MOUNTS":"/"}}}`,
+	} {
+		resp := httptest.NewRecorder()
+		s.handleAdminAgentConfig(resp, adminRequestWithBody(http.MethodPost, "/api/admin/agent-config", token, body))
+		if resp.Code != http.StatusBadRequest {
+			t.Fatalf("%s status = %d body = %s", body, resp.Code, resp.Body.String())
+		}
+	}
+
+	resp := httptest.NewRecorder()
+	s.handleAdminAgentConfig(resp, adminRequestWithBody(http.MethodPost, "/api/admin/agent-config", token, `{"global":{" basic_interval ":"5s"},"nodes":{"US-node-001":{"mounts":"/,/data"},"JP-node-001":{}}}`))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("save overrides status = %d body = %s", resp.Code, resp.Body.String())
+	}
+	got := s.store.AgentOverrides()
+	if got.Global["BASIC_INTERVAL"] != "5s" || got.Nodes["US-node-001"]["MOUNTS"] != "/,/data" || len(got.Nodes) != 1 {
+		t.Fatalf("stored overrides = %#v", got)
+	}
+}
+
+func TestAgentReportOffersNewerEmbeddedBuild(t *testing.T) {
+	s := newTestServer(t)
+	const nodeID = "US-node-001"
+	const token = "agent-token"
+	if err := s.store.SetNodeToken(nodeID, hashToken(token), 10); err != nil {
+		t.Fatal(err)
+	}
+	binary := []byte("agent build")
+	previous := agentBinaries
+	agentBinaries = fstest.MapFS{"agent_bins/vps-agent-linux-armv7": {Data: binary}}
+	t.Cleanup(func() { agentBinaries = previous })
+
+	report := func(body string) agent.ReportResponse {
+		req := httptest.NewRequest(http.MethodPost, "/api/agent/report", strings.NewReader(body))
+		req.Header.Set("X-Node-ID", nodeID)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp := httptest.NewRecorder()
+		s.handleAgentReport(resp, req)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("report status = %d body = %s", resp.Code, resp.Body.String())
+		}
+		var out agent.ReportResponse
+		decodeJSONResponse(t, resp, &out)
+		return out
+	}
+
+	sum := sha256.Sum256(binary)
+	got := report(`{"os":"linux","arch":"arm","agent_version":"0.0.9"}`)
+	if got.OK != "true" || got.Update == nil || got.Update.Version != buildinfo.Version || got.Update.URL != "/download/vps-agent-linux-armv7" || got.Update.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Fatalf("update offer = %#v", got)
+	}
+	for _, body := range []string{
+		`{"os":"linux","arch":"arm","agent_version":"` + buildinfo.Version + `"}`,
+		`{"os":"linux","arch":"arm"}`,
+		`{"os":"linux","arch":"mips","agent_version":"0.0.9"}`,
+	} {
+		if got := report(body); got.Update != nil {
+			t.Fatalf("%s offered %#v", body, got.Update)
+		}
+	}
+}
+
+func TestNodeTagsFilterViewsAndBulkEdit(t *testing.T) {
+	s := newTestServer(t)
+	for _, nodeID := range []string{"US-node-001", "JP-node-001"} {
+		if err := s.store.UpsertReport(sampleMetrics(nodeID, 100, 200), 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	token, err := s.sessions.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := httptest.NewRecorder()
+	s.handleInfo(resp, adminRequestWithBody(http.MethodPost, "/info", token, `{"name":"US-node-001","group":"US","tags":["a,b"]}`))
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("invalid tag status = %d body = %s", resp.Code, resp.Body.String())
+	}
+	resp = httptest.NewRecorder()
+	s.handleInfo(resp, adminRequestWithBody(http.MethodPost, "/info", token, `{"name":"US-node-001","group":" US ","tags":["aws","AWS"]}`))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("save info status = %d body = %s", resp.Code, resp.Body.String())
+	}
+
+	resp = httptest.NewRecorder()
+	s.handleAdminNodeTags(resp, adminRequestWithBody(http.MethodPost, "/api/admin/nodes/tags", token, `{"tag":"us","add":["edge"]}`))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("bulk by tag status = %d body = %s", resp.Code, resp.Body.String())
+	}
+	resp = httptest.NewRecorder()
+	s.handleAdminNodeTags(resp, adminRequestWithBody(http.MethodPost, "/api/admin/nodes/tags", token, `{"nodes":["JP-node-001"],"group":"JP","add":["edge"]}`))
+	var result map[string]int
+	decodeJSONResponse(t, resp, &result)
+	if result["updated"] != 1 {
+		t.Fatalf("bulk by node result = %#v", result)
+	}
+	resp = httptest.NewRecorder()
+	s.handleAdminNodeTags(resp, adminRequestWithBody(http.MethodPost, "/api/admin/nodes/tags", token, `{"add":["edge"]}`))
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("bulk without selection status = %d", resp.Code)
+	}
+
+	publicNames := func(target string) []string {
+		t.Helper()
+		resp := httptest.NewRecorder()
+		s.handleNodes(resp, httptest.NewRequest(http.MethodGet, target, nil))
+		var hosts []AkileHost
+		decodeJSONResponse(t, resp, &hosts)
+		names := make([]string, len(hosts))
+		for i, host := range hosts {
+			names[i] = host.Host.Name
+		}
+		return names
+	}
+	if names := publicNames("/api/nodes?tag=edge"); len(names) != 2 {
+		t.Fatalf("edge nodes = %q", names)
+	}
+	if s.cache.entries["hosts?tag=edge"] == nil {
+		t.Fatal("tag-filtered host list was not cached")
+	}
+	if names := publicNames("/api/nodes?tag=AWS"); len(names) != 1 || names[0] != "US-node-001" {
+		t.Fatalf("aws nodes = %q", names)
+	}
+	resp = httptest.NewRecorder()
+	s.handleNodes(resp, httptest.NewRequest(http.MethodGet, "/api/nodes?tag=a%2Cb", nil))
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("invalid filter status = %d", resp.Code)
+	}
+
+	resp = httptest.NewRecorder()
+	s.handleAdminNodes(resp, authedAdminRequest(http.MethodGet, "https://monitor.example.com/api/admin/nodes?tag=jp", token))
+	var nodes []AdminNode
+	decodeJSONResponse(t, resp, &nodes)
+	if len(nodes) != 1 || nodes[0].NodeID != "JP-node-001" || nodes[0].Info.Group != "JP" || len(nodes[0].Info.Tags) != 1 {
+		t.Fatalf("admin nodes = %#v", nodes)
+	}
+
+	resp = httptest.NewRecorder()
+	s.handleAdminNodesExport(resp, authedAdminRequest(http.MethodGet, "https://monitor.example.com/api/admin/nodes/export?tag=US", token))
+	var backup NodeBackup
+	decodeJSONResponse(t, resp, &backup)
+	if len(backup.Nodes) != 1 || backup.Nodes[0].NodeID != "US-node-001" || len(backup.Nodes[0].Info.Tags) != 2 {
+		t.Fatalf("export = %#v", backup.Nodes)
+	}
+}
+
+func TestAdminNodesBulkValidatesAndMintsCommands(t *testing.T) {
+	s := newTestServer(t)
+	token, err := s.sessions.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, body := range []string{
+		`{"ops":[]}`,
+		`{"ops":[{"op":"add","node_id":"US-node-001"},{"op":"rename","node_id":"US-node-002"}]}`,
+		`{"ops":[{"op":"add","node_id":"bad;id"}]}`,
+		`{"ops":[{"op":"update","node_id":"US-node-001"}]}`,
+		`{"ops":[{"op":"update","node_id":"US-node-001","info":{"tags":["a,b"]}}]}`,
+	} {
+		resp := httptest.NewRecorder()
+		s.handleAdminNodesBulk(resp, adminRequestWithBody(http.MethodPost, "/api/admin/nodes/bulk", token, body))
+		if resp.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status = %d body = %s", body, resp.Code, resp.Body.String())
+		}
+	}
+	if nodes := s.store.AdminNodes(time.Minute); len(nodes) != 0 {
+		t.Fatalf("rejected request wrote nodes: %#v", nodes)
+	}
+
+	body := `{"ops":[{"op":"add","node_id":"US-node-001"},{"op":"update","node_id":"US-node-001","info":{"seller":"Acme","due_time":1767225600000,"traffic_reset_day":15}},{"op":"token","node_id":"US-node-001"},{"op":"delete","node_id":"JP-node-001"}]}`
+	resp := httptest.NewRecorder()
+	s.handleAdminNodesBulk(resp, adminRequestWithBody(http.MethodPost, "/api/admin/nodes/bulk", token, body))
+	var out struct {
+		Results []BulkResult `json:"results"`
+	}
+	decodeJSONResponse(t, resp, &out)
+	if len(out.Results) != 4 || !out.Results[0].OK || !out.Results[1].OK || !out.Results[2].OK || out.Results[3].Error != "unknown node" {
+		t.Fatalf("results = %#v", out.Results)
+	}
+	linux := out.Results[2].Commands["linux"]
+	if !strings.Contains(linux, "--node-id 'US-node-001'") || out.Results[2].Commands["windows"] == "" {
+		t.Fatalf("commands = %#v", out.Results[2].Commands)
+	}
+	agentToken := strings.Trim(strings.Fields(strings.SplitAfter(linux, "--token ")[1])[0], "'")
+	if !s.store.ValidNodeToken("US-node-001", hashToken(agentToken)) {
+		t.Fatal("minted token is not valid for the node")
+	}
+	nodes := s.store.AdminNodes(time.Minute)
+	if len(nodes) != 1 || nodes[0].Info.Seller != "Acme" || nodes[0].Info.TrafficResetDay != 15 || nodes[0].Info.DueTime != 1767225600000 {
+		t.Fatalf("nodes = %#v", nodes)
+	}
+}
+
+func TestAdminNodeRenameKeepsAgentReporting(t *testing.T) {
+	s := newTestServer(t)
+	admin, err := s.sessions.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const token = "agent-token"
+	if err := s.store.SetNodeToken("HK-old", hashToken(token), 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.store.UpsertReport(sampleMetrics("US-taken", 0, 0), 10); err != nil {
+		t.Fatal(err)
+	}
+	agentRequest := func(method, target, nodeID, body string) *http.Request {
+		req := httptest.NewRequest(method, "https://monitor.example.com"+target, strings.NewReader(body))
+		req.Header.Set("X-Node-ID", nodeID)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return req
+	}
+
+	for body, status := range map[string]int{
+		`{"node_id":"HK-old","new_node_id":"HK-old"}`:   http.StatusBadRequest,
+		`{"node_id":"HK-old","new_node_id":"bad;id"}`:   http.StatusBadRequest,
+		`{"node_id":"HK-old","new_node_id":"US-taken"}`: http.StatusConflict,
+		`{"node_id":"HK-ghost","new_node_id":"HK-new"}`: http.StatusNotFound,
+	} {
+		resp := httptest.NewRecorder()
+		s.handleAdminNodeRename(resp, adminRequestWithBody(http.MethodPost, "/api/admin/nodes/rename", admin, body))
+		if resp.Code != status {
+			t.Fatalf("%s: status = %d body = %s, want %d", body, resp.Code, resp.Body.String(), status)
+		}
+	}
+
+	resp := httptest.NewRecorder()
+	s.handleAgentConfig(resp, agentRequest(http.MethodGet, "/api/agent/config", "HK-old", ""))
+	var before agent.RemoteConfig
+	decodeJSONResponse(t, resp, &before)
+
+	resp = httptest.NewRecorder()
+	s.handleAdminNodeRename(resp, adminRequestWithBody(http.MethodPost, "/api/admin/nodes/rename", admin, `{"node_id":"HK-old","new_node_id":"HK-new"}`))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("rename status = %d body = %s", resp.Code, resp.Body.String())
+	}
+
+	resp = httptest.NewRecorder()
+	s.handleAgentReport(resp, agentRequest(http.MethodPost, "/api/agent/report", "HK-old", `{}`))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("report under the old id: status = %d body = %s", resp.Code, resp.Body.String())
+	}
+	if _, ok := s.store.Report("HK-new"); !ok {
+		t.Fatal("report under the old id was not stored for the renamed node")
+	}
+	if _, ok := s.store.Report("HK-old"); ok {
+		t.Fatal("report under the old id created a second node")
+	}
+
+	req := agentRequest(http.MethodGet, "/api/agent/config", "HK-old", "")
+	req.Header.Set("If-None-Match", `"`+before.Version+`"`)
+	resp = httptest.NewRecorder()
+	s.handleAgentConfig(resp, req)
+	var after agent.RemoteConfig
+	decodeJSONResponse(t, resp, &after)
+	if after.NodeID != "HK-new" || after.Version == before.Version {
+		t.Fatalf("config after rename = %#v, before %#v", after, before)
+	}
+
+	resp = httptest.NewRecorder()
+	s.handleAgentPing(resp, agentRequest(http.MethodGet, "/api/agent/ping", "HK-new", ""))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("ping under the new id: status = %d", resp.Code)
+	}
+}
+
+func TestStatusPageHonorsNodeVisibility(t *testing.T) {
+	s := newTestServer(t)
+	for _, nodeID := range []string{"US-node-001", "JP-10.0.0.1", "HK-node-001", "SG-node-001"} {
+		if err := s.store.UpsertReport(sampleMetrics(nodeID, 100, 200), 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	token, err := s.sessions.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, body := range []string{
+		`{"name":"JP-10.0.0.1","redact_host":true}`,
+		`{"name":"HK-node-001","visibility":"private","public_name":"Hong Kong"}`,
+		`{"name":"SG-node-001","visibility":"hidden"}`,
+	} {
+		resp := httptest.NewRecorder()
+		s.handleInfo(resp, adminRequestWithBody(http.MethodPost, "/info", token, body))
+		if resp.Code != http.StatusOK {
+			t.Fatalf("save info %s: status = %d body = %s", body, resp.Code, resp.Body.String())
+		}
+	}
+	resp := httptest.NewRecorder()
+	s.handleInfo(resp, adminRequestWithBody(http.MethodPost, "/info", token, `{"name":"US-node-001","visibility":"secret"}`))
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("invalid visibility status = %d", resp.Code)
+	}
+
+	hostNames := func(req *http.Request) []string {
+		t.Helper()
+		resp := httptest.NewRecorder()
+		s.handleNodes(resp, req)
+		var hosts []AkileHost
+		decodeJSONResponse(t, resp, &hosts)
+		names := make([]string, len(hosts))
+		for i, host := range hosts {
+			names[i] = host.Host.Name
+		}
+		return names
+	}
+	if got := hostNames(httptest.NewRequest(http.MethodGet, "/api/nodes", nil)); strings.Join(got, ",") != "JP-***,US-node-001" {
+		t.Fatalf("public nodes = %q", got)
+	}
+	if got := hostNames(authedAdminRequest(http.MethodGet, "/api/nodes", token)); strings.Join(got, ",") != "Hong Kong,JP-***,US-node-001" {
+		t.Fatalf("admin nodes = %q", got)
+	}
+	crossOrigin := authedAdminRequest(http.MethodGet, "/api/nodes", token)
+	crossOrigin.Header.Set("Origin", "https://other.example.com")
+	if got := hostNames(crossOrigin); strings.Join(got, ",") != "JP-***,US-node-001" {
+		t.Fatalf("cross-origin admin nodes = %q", got)
+	}
+
+	resp = httptest.NewRecorder()
+	s.handleInfo(resp, httptest.NewRequest(http.MethodGet, "/info", nil))
+	var infos []HostInfo
+	decodeJSONResponse(t, resp, &infos)
+	if len(infos) != 1 || infos[0].Name != "JP-***" || infos[0].RedactHost {
+		t.Fatalf("public infos = %#v", infos)
+	}
+}
@@ -0,0 +1,57 @@
+package application
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+
+	"vps-agent/internal/agent"
+	"vps-agent/internal/server/domain"
+)
+
+// AgentConfigFor picks the probe targets and settings overrides that apply to
+// nodeID, whose group and tags come from info. The version is a hash of the
+// content, node ID included, so it changes when the node's settings do and
+// when the node is renamed.
+func AgentConfigFor(nodeID string, info domain.HostInfo, targets []domain.ProbeTarget, overrides domain.AgentOverrides) agent.RemoteConfig {
+	cfg := agent.RemoteConfig{NodeID: nodeID, Probes: []agent.RemoteProbe{}}
+	for _, target := range targets {
+		if !targetApplies(target, nodeID, info) {
+			continue
+		}
+		cfg.Probes = append(cfg.Probes, agent.RemoteProbe{
+			Name:   target.Name,
+			Type:   target.Type,
+			Target: target.Target,
+			WarnMs: target.WarnMs,
+		})
+	}
+	for _, values := range []map[string]string{overrides.Global, overrides.Nodes[nodeID]} {
+		for key, value := range values {
+			if cfg.Settings == nil {
+				cfg.Settings = map[string]string{}
+			}
+			cfg.Settings[key] = value
+		}
+	}
+	data, _ := json.Marshal(cfg)
+	sum := sha256.Sum256(data)
+	cfg.Version = hex.EncodeToString(sum[:8])
+	return cfg
+}
+
+func targetApplies(target domain.ProbeTarget, nodeID string, info domain.HostInfo) bool {
+	if len(target.Nodes) == 0 && len(target.Tags) == 0 {
+		return true
+	}
+	if slices.Contains(target.Nodes, nodeID) {
+		return true
+	}
+	for _, tag := range target.Tags {
+		if info.HasLabel(tag) {
+			return true
+		}
+	}
+	return false
+}
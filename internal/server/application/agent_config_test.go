@@ -0,0 +1,70 @@
+package application
+
+import (
+	"testing"
+
+	"vps-agent/internal/server/domain"
+)
+
+func TestAgentConfigForScopesTargetsAndVersions(t *testing.T) {
+	targets := []domain.ProbeTarget{
+		{Name: "CT", Type: "icmp", Target: "202.96.209.133"},
+		{Name: "origin", Type: "tcp", Target: "origin.example.com:443", WarnMs: 200, Nodes: []string{"US-node-001"}},
+	}
+
+	us := AgentConfigFor("US-node-001", domain.HostInfo{}, targets, domain.AgentOverrides{})
+	jp := AgentConfigFor("JP-node-001", domain.HostInfo{}, targets, domain.AgentOverrides{})
+	if len(us.Probes) != 2 || us.Probes[1].WarnMs != 200 {
+		t.Fatalf("US probes = %#v", us.Probes)
+	}
+	if len(jp.Probes) != 1 || jp.Probes[0].Name != "CT" {
+		t.Fatalf("JP probes = %#v", jp.Probes)
+	}
+	if us.Version == "" || us.Version == jp.Version {
+		t.Fatalf("versions = %q %q", us.Version, jp.Version)
+	}
+	if again := AgentConfigFor("US-node-001", domain.HostInfo{}, targets, domain.AgentOverrides{}); again.Version != us.Version {
+		t.Fatalf("version not stable: %q != %q", again.Version, us.Version)
+	}
+	if empty := AgentConfigFor("US-node-001", domain.HostInfo{}, nil, domain.AgentOverrides{}); empty.Probes == nil || empty.Version == "" {
+		t.Fatalf("empty config = %#v", empty)
+	}
+}
+
+func TestAgentConfigForMatchesTargetTags(t *testing.T) {
+	targets := []domain.ProbeTarget{
+		{Name: "CT", Type: "icmp", Target: "202.96.209.133", Tags: []string{"cn-route"}},
+		{Name: "origin", Type: "tcp", Target: "origin.example.com:443", Nodes: []string{"JP-node-001"}, Tags: []string{"US"}},
+	}
+
+	tagged := AgentConfigFor("US-node-001", domain.HostInfo{Group: "us", Tags: []string{"CN-Route"}}, targets, domain.AgentOverrides{})
+	if len(tagged.Probes) != 2 {
+		t.Fatalf("tagged probes = %#v", tagged.Probes)
+	}
+	listed := AgentConfigFor("JP-node-001", domain.HostInfo{}, targets, domain.AgentOverrides{})
+	if len(listed.Probes) != 1 || listed.Probes[0].Name != "origin" {
+		t.Fatalf("listed probes = %#v", listed.Probes)
+	}
+	if none := AgentConfigFor("SG-node-001", domain.HostInfo{Tags: []string{"other"}}, targets, domain.AgentOverrides{}); len(none.Probes) != 0 {
+		t.Fatalf("untagged probes = %#v", none.Probes)
+	}
+}
+
+func TestAgentConfigForMergesOverrides(t *testing.T) {
+	overrides := domain.AgentOverrides{
+		Global: map[string]string{"BASIC_INTERVAL": "5s", "MOUNTS": "auto"},
+		Nodes:  map[string]map[string]string{"US-node-001": {"MOUNTS": "/,/data"}},
+	}
+
+	us := AgentConfigFor("US-node-001", domain.HostInfo{}, nil, overrides)
+	jp := AgentConfigFor("JP-node-001", domain.HostInfo{}, nil, overrides)
+	if us.Settings["BASIC_INTERVAL"] != "5s" || us.Settings["MOUNTS"] != "/,/data" {
+		t.Fatalf("US settings = %#v", us.Settings)
+	}
+	if jp.Settings["MOUNTS"] != "auto" {
+		t.Fatalf("JP settings = %#v", jp.Settings)
+	}
+	if none := AgentConfigFor("JP-node-001", domain.HostInfo{}, nil, domain.AgentOverrides{}); none.Settings != nil || none.Version == jp.Version {
+		t.Fatalf("config without overrides = %#v", none)
+	}
+}
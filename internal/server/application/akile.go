@@ -1,11 +1,16 @@
 package application
 
 import (
+	"fmt"
+
 	"vps-agent/internal/agent"
 	"vps-agent/internal/server/domain"
 )
 
-func ToAkileHost(metrics agent.Metrics, traffic domain.TrafficStat) AkileHost {
+// ToAkileHost builds the public view of a node from its latest report. From
+// info it takes the public names and labels, and drops the hostname of a
+// redacted host; the purchase details are served separately by /api/info.
+func ToAkileHost(metrics agent.Metrics, traffic domain.TrafficStat, info domain.HostInfo) AkileHost {
 	diskUsed := uint64(0)
 	diskTotal := uint64(0)
 	for _, disk := range metrics.Disks {
@@ -23,26 +28,40 @@ func ToAkileHost(metrics agent.Metrics, traffic domain.TrafficStat) AkileHost {
 	if metrics.Conns != nil {
 		conns = *metrics.Conns
 	}
+	hostname := metrics.Hostname
+	if info.RedactHost {
+		hostname = ""
+	}
 	return AkileHost{
 		Host: AkileHostMeta{
-			Name:            metrics.NodeID,
-			Hostname:        metrics.Hostname,
+			Name:            info.PublicHostName(metrics.NodeID),
+			Hostname:        hostname,
 			Platform:        platform,
 			PlatformVersion: metrics.Kernel,
 			Kernel:          metrics.Kernel,
 			Arch:            metrics.Arch,
 			Virtualization:  metrics.Virtualization,
-			CPU:             make([]int, metrics.CPU.Cores),
+			CPU:             perCoreUsage(metrics.CPU),
 			CPUModel:        metrics.CPU.ModelName,
 			PhysicalCores:   metrics.CPU.PhysicalCores,
 			LogicalCores:    metrics.CPU.Cores,
 			MemTotal:        metrics.Memory.Total,
 			SwapTotal:       metrics.Swap.Total,
+			DisplayName:     info.PublicDisplayName(),
+			Group:           info.Group,
+			Tags:            info.Tags,
 		},
 		State: AkileHostState{
 			CPU:                 metrics.CPU.UsagePercent,
+			CPUUser:             metrics.CPU.UserPercent,
+			CPUSystem:           metrics.CPU.SystemPercent,
+			CPUIOWait:           metrics.CPU.IOWaitPercent,
+			CPUSteal:            metrics.CPU.StealPercent,
+			CPUIRQ:              metrics.CPU.IRQPercent,
 			MemUsed:             metrics.Memory.Used,
 			SwapUsed:            metrics.Swap.Used,
+			MemoryDetail:        metrics.MemoryDetail,
+			Pressure:            metrics.Pressure,
 			DiskUsed:            diskUsed,
 			DiskTotal:           diskTotal,
 			Disks:               metrics.Disks,
@@ -52,8 +71,12 @@ func ToAkileHost(metrics agent.Metrics, traffic domain.TrafficStat) AkileHost {
 			NetOutSpeed:         metrics.Network.TxRate,
 			DiskReadSpeed:       metrics.DiskIO.ReadRate,
 			DiskWriteSpeed:      metrics.DiskIO.WriteRate,
+			DiskDevices:         metrics.DiskDevices,
+			Sensors:             metrics.Sensors,
+			Probes:              publicProbes(metrics.Probes, info.RedactHost),
 			TCP:                 conns.TCP,
 			UDP:                 conns.UDP,
+			TCPStates:           conns.TCPStates,
 			Processes:           metrics.Processes,
 			Load1:               metrics.Load.Load1,
 			Load5:               metrics.Load.Load5,
@@ -69,9 +92,49 @@ func ToAkileHost(metrics agent.Metrics, traffic domain.TrafficStat) AkileHost {
 	}
 }
 
-func OfflineAkileHost(name string) AkileHost {
+// publicProbes drops probe targets from the public view. Unnamed probes are
+// labelled by type and position, and names go through RedactAddresses when
+// the node hides its host details.
+func publicProbes(probes []agent.ProbeResult, redact bool) []AkileProbe {
+	if len(probes) == 0 {
+		return nil
+	}
+	out := make([]AkileProbe, len(probes))
+	for i, probe := range probes {
+		name := probe.Name
+		if name == "" {
+			name = fmt.Sprintf("%s #%d", probe.Type, i+1)
+		} else if redact {
+			name = domain.RedactAddresses(name)
+		}
+		out[i] = AkileProbe{
+			Name:        name,
+			Type:        probe.Type,
+			Timestamp:   probe.Timestamp,
+			LatencyMs:   probe.LatencyMs,
+			LossPercent: probe.LossPercent,
+			StatusCode:  probe.StatusCode,
+		}
+	}
+	return out
+}
+
+// perCoreUsage rounds per-core usage to whole percents. Agents that do not
+// report per-core data still get one zero slot per logical core.
+func perCoreUsage(cpu agent.CPU) []int {
+	if len(cpu.PerCoreUsage) == 0 {
+		return make([]int, cpu.Cores)
+	}
+	out := make([]int, len(cpu.PerCoreUsage))
+	for i, usage := range cpu.PerCoreUsage {
+		out[i] = int(usage + 0.5)
+	}
+	return out
+}
+
+func OfflineAkileHost(name string, info domain.HostInfo) AkileHost {
 	return AkileHost{
-		Host:      AkileHostMeta{Name: name, Platform: "pending", PlatformVersion: "", CPU: []int{}, MemTotal: 1},
+		Host:      AkileHostMeta{Name: info.PublicHostName(name), Platform: "pending", PlatformVersion: "", CPU: []int{}, MemTotal: 1, DisplayName: info.PublicDisplayName(), Group: info.Group, Tags: info.Tags},
 		State:     AkileHostState{},
 		TimeStamp: 0,
 	}
@@ -27,21 +27,38 @@ func TestToAkileHostMapsMetricsAndTraffic(t *testing.T) {
 			{Mount: "/", Used: 10, Total: 100},
 			{Mount: "/data", Used: 20, Total: 200},
 		},
-		Network:   agent.Network{RxBytes: 1000, TxBytes: 2000, RxRate: 10, TxRate: 20},
-		DiskIO:    agent.DiskIO{ReadRate: 30, WriteRate: 40},
+		Network: agent.Network{RxBytes: 1000, TxBytes: 2000, RxRate: 10, TxRate: 20},
+		DiskIO:  agent.DiskIO{ReadRate: 30, WriteRate: 40},
+		DiskDevices: []agent.DiskDevice{
+			{Name: "nvme0n1", ReadRate: 30, WriteRate: 40, UtilPercent: 12.5},
+		},
+		Sensors:   []agent.Sensor{{Chip: "coretemp", Label: "Package id 0", Kind: "temperature", Value: 45, Critical: 100}},
+		Probes:    []agent.ProbeResult{{Name: "CT", Type: "icmp", Target: "10.0.0.1", Timestamp: 1200, Sent: 5, Lost: 1, LossPercent: 20, LatencyMs: 42.5}},
 		Conns:     conns,
 		Processes: 7,
-	}, domain.TrafficStat{ResetDay: 40, PeriodStart: 111, NextReset: 222, RxTotal: 333, TxTotal: 444})
+	}, domain.TrafficStat{ResetDay: 40, PeriodStart: 111, NextReset: 222, RxTotal: 333, TxTotal: 444}, domain.HostInfo{Group: "US", Tags: []string{"aws"}, Seller: "hidden"})
 
 	if host.Host.Name != "node-1" || host.Host.Platform != "Ubuntu" || host.Host.LogicalCores != 4 {
 		t.Fatalf("host meta = %#v", host.Host)
 	}
+	if host.Host.Group != "US" || len(host.Host.Tags) != 1 || host.Host.Tags[0] != "aws" {
+		t.Fatalf("labels = %q %q", host.Host.Group, host.Host.Tags)
+	}
 	if len(host.Host.CPU) != 4 {
 		t.Fatalf("cpu slots = %d", len(host.Host.CPU))
 	}
 	if host.State.DiskUsed != 30 || host.State.DiskTotal != 300 {
 		t.Fatalf("disk totals = used %d total %d", host.State.DiskUsed, host.State.DiskTotal)
 	}
+	if len(host.State.DiskDevices) != 1 || host.State.DiskDevices[0].Name != "nvme0n1" || host.State.DiskDevices[0].UtilPercent != 12.5 {
+		t.Fatalf("disk devices = %#v", host.State.DiskDevices)
+	}
+	if len(host.State.Sensors) != 1 || host.State.Sensors[0].Value != 45 {
+		t.Fatalf("sensors = %#v", host.State.Sensors)
+	}
+	if want := (AkileProbe{Name: "CT", Type: "icmp", Timestamp: 1200, LatencyMs: 42.5, LossPercent: 20}); len(host.State.Probes) != 1 || host.State.Probes[0] != want {
+		t.Fatalf("probes = %#v", host.State.Probes)
+	}
 	if host.State.TCP != 3 || host.State.UDP != 4 {
 		t.Fatalf("connections = tcp %d udp %d", host.State.TCP, host.State.UDP)
 	}
@@ -50,8 +67,29 @@ func TestToAkileHostMapsMetricsAndTraffic(t *testing.T) {
 	}
 }
 
+func TestToAkileHostMapsCPUMemoryAndPressureDetails(t *testing.T) {
+	host := ToAkileHost(agent.Metrics{
+		NodeID:       "node-3",
+		MemoryDetail: &agent.MemoryDetail{Available: 256, Cached: 128},
+		Pressure:     &agent.Pressure{Memory: &agent.PressureStat{SomeAvg10: 1.5}},
+		CPU:          agent.CPU{UsagePercent: 60, UserPercent: 30, SystemPercent: 10, IOWaitPercent: 5, StealPercent: 12.5, IRQPercent: 2.5, PerCoreUsage: []float64{10.4, 99.6}, Cores: 2},
+	}, domain.TrafficStat{}, domain.HostInfo{})
+	if len(host.Host.CPU) != 2 || host.Host.CPU[0] != 10 || host.Host.CPU[1] != 100 {
+		t.Fatalf("per-core cpu = %#v", host.Host.CPU)
+	}
+	if host.State.CPUUser != 30 || host.State.CPUSystem != 10 || host.State.CPUIOWait != 5 || host.State.CPUSteal != 12.5 || host.State.CPUIRQ != 2.5 {
+		t.Fatalf("cpu breakdown = %#v", host.State)
+	}
+	if host.State.MemoryDetail == nil || host.State.MemoryDetail.Cached != 128 {
+		t.Fatalf("memory detail = %#v", host.State.MemoryDetail)
+	}
+	if host.State.Pressure == nil || host.State.Pressure.Memory.SomeAvg10 != 1.5 || host.State.Pressure.IO != nil {
+		t.Fatalf("pressure = %#v", host.State.Pressure)
+	}
+}
+
 func TestToAkileHostDefaultsPlatformAndConnections(t *testing.T) {
-	host := ToAkileHost(agent.Metrics{NodeID: "node-2"}, domain.TrafficStat{})
+	host := ToAkileHost(agent.Metrics{NodeID: "node-2"}, domain.TrafficStat{}, domain.HostInfo{})
 	if host.Host.Platform != "unknown" {
 		t.Fatalf("platform = %q", host.Host.Platform)
 	}
@@ -64,11 +102,46 @@ func TestToAkileHostDefaultsPlatformAndConnections(t *testing.T) {
 }
 
 func TestOfflineAkileHost(t *testing.T) {
-	host := OfflineAkileHost("pending-node")
-	if host.Host.Name != "pending-node" || host.Host.Platform != "pending" || host.Host.MemTotal != 1 {
+	host := OfflineAkileHost("pending-node", domain.HostInfo{Group: "JP"})
+	if host.Host.Name != "pending-node" || host.Host.Platform != "pending" || host.Host.MemTotal != 1 || host.Host.Group != "JP" {
 		t.Fatalf("offline host = %#v", host)
 	}
 	if len(host.Host.CPU) != 0 || host.TimeStamp != 0 {
 		t.Fatalf("offline host cpu/time = %#v timestamp=%d", host.Host.CPU, host.TimeStamp)
 	}
 }
+
+func TestAkileHostsDoNotLeakDisplayName(t *testing.T) {
+	const display = "Tokyo 203.0.113.7"
+	redacted := domain.HostInfo{DisplayName: display, RedactHost: true}
+	if host := ToAkileHost(agent.Metrics{NodeID: "node-1"}, domain.TrafficStat{}, redacted); host.Host.DisplayName != "Tokyo ***" {
+		t.Fatalf("redacted display name = %q", host.Host.DisplayName)
+	}
+	if host := OfflineAkileHost("node-1", redacted); host.Host.DisplayName != "Tokyo ***" {
+		t.Fatalf("offline redacted display name = %q", host.Host.DisplayName)
+	}
+	named := domain.HostInfo{DisplayName: display, PublicName: "Tokyo", RedactHost: true}
+	for _, host := range []AkileHost{
+		ToAkileHost(agent.Metrics{NodeID: "node-1"}, domain.TrafficStat{}, named),
+		OfflineAkileHost("node-1", named),
+	} {
+		if host.Host.Name != "Tokyo" || host.Host.DisplayName != "" {
+			t.Fatalf("public name host = %q display %q", host.Host.Name, host.Host.DisplayName)
+		}
+	}
+}
+
+func TestPublicProbesHideTargets(t *testing.T) {
+	metrics := agent.Metrics{NodeID: "node-1", Probes: []agent.ProbeResult{
+		{Name: "CT 202.96.209.133", Type: "icmp", Target: "202.96.209.133"},
+		{Type: "tcp", Target: "origin.example.com:443"},
+	}}
+	host := ToAkileHost(metrics, domain.TrafficStat{}, domain.HostInfo{})
+	if len(host.State.Probes) != 2 || host.State.Probes[0].Name != "CT 202.96.209.133" || host.State.Probes[1].Name != "tcp #2" {
+		t.Fatalf("probes = %#v", host.State.Probes)
+	}
+	host = ToAkileHost(metrics, domain.TrafficStat{}, domain.HostInfo{RedactHost: true})
+	if host.State.Probes[0].Name != "CT ***" || host.State.Probes[1].Name != "tcp #2" {
+		t.Fatalf("redacted probes = %#v", host.State.Probes)
+	}
+}
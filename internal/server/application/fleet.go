@@ -0,0 +1,51 @@
+package application
+
+import (
+	"sort"
+
+	"vps-agent/internal/buildinfo"
+	"vps-agent/internal/server/domain"
+)
+
+// Fleet is the spread of agent versions across nodes that have reported.
+// Current is the version the server embeds and offers for self-update.
+type Fleet struct {
+	Current  string         `json:"current"`
+	Versions []FleetVersion `json:"versions"`
+}
+
+// FleetVersion lists the nodes that last reported one agent version. An
+// empty version is an agent that predates version reporting.
+type FleetVersion struct {
+	Version  string   `json:"version"`
+	Outdated bool     `json:"outdated"`
+	Nodes    []string `json:"nodes"`
+}
+
+// FleetVersions groups nodes by agent version, newest first.
+func FleetVersions(nodes []domain.AdminNode) Fleet {
+	byVersion := map[string][]string{}
+	for _, node := range nodes {
+		if node.LastSeen == 0 {
+			continue
+		}
+		byVersion[node.AgentVersion] = append(byVersion[node.AgentVersion], node.NodeID)
+	}
+	fleet := Fleet{Current: buildinfo.Version, Versions: []FleetVersion{}}
+	for version, ids := range byVersion {
+		sort.Strings(ids)
+		fleet.Versions = append(fleet.Versions, FleetVersion{
+			Version:  version,
+			Outdated: version == "" || buildinfo.Newer(buildinfo.Version, version),
+			Nodes:    ids,
+		})
+	}
+	sort.Slice(fleet.Versions, func(i, j int) bool {
+		a, b := fleet.Versions[i].Version, fleet.Versions[j].Version
+		if b == "" || a == "" {
+			return b == ""
+		}
+		return buildinfo.Newer(a, b) || !buildinfo.Newer(b, a) && a > b
+	})
+	return fleet
+}
@@ -0,0 +1,29 @@
+package application
+
+import (
+	"reflect"
+	"testing"
+
+	"vps-agent/internal/buildinfo"
+	"vps-agent/internal/server/domain"
+)
+
+func TestFleetVersionsGroupsReportedNodesNewestFirst(t *testing.T) {
+	fleet := FleetVersions([]domain.AdminNode{
+		{NodeID: "b", LastSeen: 1, AgentVersion: buildinfo.Version},
+		{NodeID: "c", LastSeen: 1, AgentVersion: "0.0.9"},
+		{NodeID: "a", LastSeen: 1, AgentVersion: buildinfo.Version},
+		{NodeID: "d", LastSeen: 1},
+		{NodeID: "pending"},
+		{NodeID: "e", LastSeen: 1, AgentVersion: "0.0.10"},
+	})
+	want := Fleet{Current: buildinfo.Version, Versions: []FleetVersion{
+		{Version: buildinfo.Version, Nodes: []string{"a", "b"}},
+		{Version: "0.0.10", Outdated: true, Nodes: []string{"e"}},
+		{Version: "0.0.9", Outdated: true, Nodes: []string{"c"}},
+		{Version: "", Outdated: true, Nodes: []string{"d"}},
+	}}
+	if !reflect.DeepEqual(fleet, want) {
+		t.Fatalf("fleet = %#v", fleet)
+	}
+}
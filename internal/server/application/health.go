@@ -0,0 +1,124 @@
+package application
+
+import (
+	"fmt"
+	"time"
+
+	"vps-agent/internal/agent"
+	"vps-agent/internal/buildinfo"
+)
+
+const (
+	// InodeWarnPercent is the inode usage at which a mount is flagged.
+	InodeWarnPercent = 90
+	// WearWarnPercent is the share of SSD endurance used at which a drive is
+	// flagged.
+	WearWarnPercent = 90
+	// ProbeLossWarnPercent is the packet or connection loss at which a probe
+	// is flagged.
+	ProbeLossWarnPercent = 20
+	// AgentErrorWindow is how long a collect or report error keeps an agent
+	// flagged.
+	AgentErrorWindow = 10 * time.Minute
+	// AgentSlowCollectMs flags an agent whose collect takes about as long as
+	// the default report interval.
+	AgentSlowCollectMs = 2000
+	// AgentMemoryWarnBytes flags an agent that holds more memory than a
+	// monitoring agent should.
+	AgentMemoryWarnBytes = 256 << 20
+)
+
+// readOnlyFSTypes are filesystems that can only be mounted read-only, so a
+// read-only mount of one is expected rather than a sign of trouble.
+var readOnlyFSTypes = map[string]bool{
+	"squashfs": true,
+	"erofs":    true,
+	"cramfs":   true,
+	"iso9660":  true,
+	"udf":      true,
+}
+
+// HealthFlags lists hardware and filesystem problems in a report that an
+// operator should look at, in a short human readable form.
+func HealthFlags(metrics agent.Metrics) []string {
+	var out []string
+	for _, disk := range metrics.Disks {
+		if disk.ReadOnly && !readOnlyFSTypes[disk.FSType] {
+			out = append(out, fmt.Sprintf("%s read-only", disk.Mount))
+		}
+		if disk.InodesTotal > 0 && disk.InodesUsedPercent >= InodeWarnPercent {
+			out = append(out, fmt.Sprintf("%s inodes %.0f%%", disk.Mount, disk.InodesUsedPercent))
+		}
+	}
+	for _, array := range metrics.RAID {
+		if array.Degraded {
+			out = append(out, fmt.Sprintf("%s degraded %d/%d", array.Name, array.ActiveDevices, array.Devices))
+		}
+		if array.SyncAction != "" {
+			out = append(out, fmt.Sprintf("%s %s %.1f%%", array.Name, array.SyncAction, array.SyncPercent))
+		}
+	}
+	for _, disk := range metrics.SMART {
+		if !disk.Passed {
+			out = append(out, fmt.Sprintf("%s SMART failed", disk.Device))
+		}
+		if disk.ReallocatedSectors > 0 {
+			out = append(out, fmt.Sprintf("%s reallocated %d", disk.Device, disk.ReallocatedSectors))
+		}
+		if disk.PendingSectors > 0 {
+			out = append(out, fmt.Sprintf("%s pending %d", disk.Device, disk.PendingSectors))
+		}
+		if disk.MediaErrors > 0 {
+			out = append(out, fmt.Sprintf("%s media errors %d", disk.Device, disk.MediaErrors))
+		}
+		if disk.WearPercent >= WearWarnPercent {
+			out = append(out, fmt.Sprintf("%s wear %.0f%%", disk.Device, disk.WearPercent))
+		}
+	}
+	for _, probe := range metrics.Probes {
+		name := probe.Name
+		if name == "" {
+			name = probe.Target
+		}
+		if probe.Sent > 0 && probe.Lost == probe.Sent {
+			out = append(out, fmt.Sprintf("probe %s down", name))
+			continue
+		}
+		if probe.LossPercent >= ProbeLossWarnPercent {
+			out = append(out, fmt.Sprintf("probe %s loss %.0f%%", name, probe.LossPercent))
+		}
+		if probe.StatusCode >= 400 {
+			out = append(out, fmt.Sprintf("probe %s HTTP %d", name, probe.StatusCode))
+		}
+		if probe.WarnMs > 0 && probe.LatencyMs >= probe.WarnMs {
+			out = append(out, fmt.Sprintf("probe %s %.0fms", name, probe.LatencyMs))
+		}
+	}
+	return out
+}
+
+// AgentFlags lists problems with the agent itself: a build older than the one
+// the server embeds, recent errors, slow collects and high memory use.
+func AgentFlags(metrics agent.Metrics) []string {
+	var out []string
+	switch {
+	case metrics.AgentVersion == "":
+		out = append(out, "agent version unknown")
+	case buildinfo.Newer(buildinfo.Version, metrics.AgentVersion):
+		out = append(out, fmt.Sprintf("agent %s outdated", metrics.AgentVersion))
+	}
+	stats := metrics.Agent
+	if stats == nil {
+		return out
+	}
+	if stats.LastErrorAt > 0 && metrics.Timestamp-stats.LastErrorAt < int64(AgentErrorWindow/time.Second) {
+		out = append(out, fmt.Sprintf("agent errors %d", stats.CollectErrors+stats.ReportErrors))
+	}
+	if stats.CollectMs >= AgentSlowCollectMs {
+		out = append(out, fmt.Sprintf("agent collect %.0f ms", stats.CollectMs))
+	}
+	if stats.MemoryBytes >= AgentMemoryWarnBytes {
+		out = append(out, fmt.Sprintf("agent memory %d MB", stats.MemoryBytes>>20))
+	}
+	return out
+}
@@ -0,0 +1,97 @@
+package application
+
+import (
+	"reflect"
+	"testing"
+
+	"vps-agent/internal/agent"
+	"vps-agent/internal/buildinfo"
+)
+
+func TestHealthFlagsReportsReadOnlyAndInodeExhaustion(t *testing.T) {
+	flags := HealthFlags(agent.Metrics{Disks: []agent.Disk{
+		{Mount: "/", InodesTotal: 100, InodesUsed: 96, InodesUsedPercent: 96},
+		{Mount: "/data", ReadOnly: true, InodesTotal: 100, InodesUsed: 10, InodesUsedPercent: 10},
+		{Mount: "/tank"},
+		{Mount: "/snap/core/1", FSType: "squashfs", ReadOnly: true},
+	}})
+	if len(flags) != 2 || flags[0] != "/ inodes 96%" || flags[1] != "/data read-only" {
+		t.Fatalf("flags = %#v", flags)
+	}
+	if got := HealthFlags(agent.Metrics{}); len(got) != 0 {
+		t.Fatalf("empty flags = %#v", got)
+	}
+}
+
+func TestHealthFlagsReportsRAIDAndSMART(t *testing.T) {
+	flags := HealthFlags(agent.Metrics{
+		RAID: []agent.RAIDArray{
+			{Name: "md0", Devices: 2, ActiveDevices: 2},
+			{Name: "md1", Devices: 2, ActiveDevices: 1, Degraded: true, SyncAction: "recovery", SyncPercent: 8.5},
+		},
+		SMART: []agent.SMARTDisk{
+			{Device: "/dev/sda", Passed: true},
+			{Device: "/dev/sdb", Passed: false, ReallocatedSectors: 12},
+			{Device: "/dev/nvme0", Passed: true, WearPercent: 91},
+		},
+	})
+	want := []string{"md1 degraded 1/2", "md1 recovery 8.5%", "/dev/sdb SMART failed", "/dev/sdb reallocated 12", "/dev/nvme0 wear 91%"}
+	if len(flags) != len(want) {
+		t.Fatalf("flags = %#v", flags)
+	}
+	for i := range want {
+		if flags[i] != want[i] {
+			t.Fatalf("flags[%d] = %q, want %q", i, flags[i], want[i])
+		}
+	}
+}
+
+func TestHealthFlagsReportsProbeLossAndLatency(t *testing.T) {
+	flags := HealthFlags(agent.Metrics{Probes: []agent.ProbeResult{
+		{Name: "CT", Type: "icmp", Sent: 5, Lost: 0, LatencyMs: 180, WarnMs: 300},
+		{Name: "CU", Type: "icmp", Sent: 5, Lost: 2, LossPercent: 40, LatencyMs: 320, WarnMs: 300},
+		{Name: "CM", Type: "icmp", Sent: 5, Lost: 5, LossPercent: 100},
+		{Name: "origin", Type: "http", Sent: 1, StatusCode: 502, LatencyMs: 90},
+	}})
+	want := []string{"probe CU loss 40%", "probe CU 320ms", "probe CM down", "probe origin HTTP 502"}
+	if len(flags) != len(want) {
+		t.Fatalf("flags = %#v", flags)
+	}
+	for i := range want {
+		if flags[i] != want[i] {
+			t.Fatalf("flags[%d] = %q, want %q", i, flags[i], want[i])
+		}
+	}
+}
+
+func TestAgentFlagsReportsOutdatedAndUnhealthyAgents(t *testing.T) {
+	current := agent.Metrics{Timestamp: 1000, AgentVersion: buildinfo.Version, Agent: &agent.AgentStats{CollectMs: 12, MemoryBytes: 20 << 20}}
+	if got := AgentFlags(current); len(got) != 0 {
+		t.Fatalf("healthy agent flags = %#v", got)
+	}
+	if got := AgentFlags(agent.Metrics{}); !reflect.DeepEqual(got, []string{"agent version unknown"}) {
+		t.Fatalf("unversioned agent flags = %#v", got)
+	}
+
+	flags := AgentFlags(agent.Metrics{
+		Timestamp:    1000,
+		AgentVersion: "0.0.9",
+		Agent: &agent.AgentStats{
+			CollectMs:     2500,
+			ReportErrors:  3,
+			CollectErrors: 1,
+			LastErrorAt:   900,
+			MemoryBytes:   300 << 20,
+		},
+	})
+	want := []string{"agent 0.0.9 outdated", "agent errors 4", "agent collect 2500 ms", "agent memory 300 MB"}
+	if !reflect.DeepEqual(flags, want) {
+		t.Fatalf("flags = %#v", flags)
+	}
+
+	old := current
+	old.Agent = &agent.AgentStats{ReportErrors: 3, LastErrorAt: 1000 - int64(AgentErrorWindow.Seconds())}
+	if got := AgentFlags(old); len(got) != 0 {
+		t.Fatalf("old errors flagged: %#v", got)
+	}
+}
@@ -0,0 +1,40 @@
+package application
+
+import "vps-agent/internal/agent"
+
+// ServiceTransition is a watched unit whose active state changed between two
+// reports from the same node.
+type ServiceTransition struct {
+	Name string
+	From string
+	To   string
+}
+
+// FailedServices lists the watched units that systemd reports as failed or
+// that do not exist on the node.
+func FailedServices(metrics agent.Metrics) []string {
+	var out []string
+	for _, service := range metrics.Services {
+		if service.ActiveState == "failed" || service.LoadState == "not-found" {
+			out = append(out, service.Name)
+		}
+	}
+	return out
+}
+
+// ServiceTransitions compares the watched units of two reports. Units that
+// appear for the first time are not transitions.
+func ServiceTransitions(prev, next agent.Metrics) []ServiceTransition {
+	before := make(map[string]string, len(prev.Services))
+	for _, service := range prev.Services {
+		before[service.Name] = service.ActiveState
+	}
+	var out []ServiceTransition
+	for _, service := range next.Services {
+		from, ok := before[service.Name]
+		if ok && from != service.ActiveState {
+			out = append(out, ServiceTransition{Name: service.Name, From: from, To: service.ActiveState})
+		}
+	}
+	return out
+}
@@ -0,0 +1,32 @@
+package application
+
+import (
+	"testing"
+
+	"vps-agent/internal/agent"
+)
+
+func TestFailedServicesAndTransitions(t *testing.T) {
+	prev := agent.Metrics{Services: []agent.Service{
+		{Name: "nginx", LoadState: "loaded", ActiveState: "active"},
+		{Name: "postgresql", LoadState: "loaded", ActiveState: "active"},
+	}}
+	next := agent.Metrics{Services: []agent.Service{
+		{Name: "nginx", LoadState: "loaded", ActiveState: "active"},
+		{Name: "postgresql", LoadState: "loaded", ActiveState: "failed"},
+		{Name: "redis", LoadState: "not-found", ActiveState: "inactive"},
+	}}
+
+	failed := FailedServices(next)
+	if len(failed) != 2 || failed[0] != "postgresql" || failed[1] != "redis" {
+		t.Fatalf("failed = %#v", failed)
+	}
+	if got := FailedServices(prev); len(got) != 0 {
+		t.Fatalf("healthy failed = %#v", got)
+	}
+
+	transitions := ServiceTransitions(prev, next)
+	if len(transitions) != 1 || transitions[0] != (ServiceTransition{Name: "postgresql", From: "active", To: "failed"}) {
+		t.Fatalf("transitions = %#v", transitions)
+	}
+}
@@ -11,17 +11,34 @@ type Store interface {
 	SiteName() string
 	GetSettings() domain.Settings
 	UpdateSettings(domain.Settings) error
+	ProbeTargets() []domain.ProbeTarget
+	SetProbeTargets([]domain.ProbeTarget) error
+	AgentOverrides() domain.AgentOverrides
+	SetAgentOverrides(domain.AgentOverrides) error
+	Dashboards() []domain.Dashboard
+	SetDashboards([]domain.Dashboard) error
+	ShareSecret() (string, error)
+	SavedShareSecret() (string, bool)
+	ResetShareSecret() error
 	UpsertReport(agent.Metrics, int) error
+	RecordOutage(string, domain.Outage) error
+	UptimeLogs() map[string]domain.UptimeLog
 	AddPlannedNode(string, int) error
 	SetNodeToken(string, string, int) error
 	ValidNodeToken(string, string) bool
+	ResolveNodeID(string) string
+	NodeByKey(string) (string, bool)
+	RenameNode(string, string) error
 	UpsertInfo(domain.HostInfo) error
 	Delete(string) error
 	InfoList() []domain.HostInfo
-	AkileHosts() []AkileHost
+	Info(string) (domain.HostInfo, bool)
+	AkileHosts(domain.HostFilter) []AkileHost
 	AdminNodes(time.Duration) []domain.AdminNode
+	Report(string) (agent.Metrics, bool)
 	ExportNodes() domain.NodeBackup
 	ImportNodes(domain.NodeBackup, int) (int, error)
+	BulkNodes([]domain.BulkOp, int, bool) ([]domain.BulkResult, error)
 }
 
 type AkileHost struct {
@@ -31,44 +48,69 @@ type AkileHost struct {
 }
 
 type AkileHostMeta struct {
-	Name            string `json:"Name"`
-	Hostname        string `json:"Hostname"`
-	Platform        string `json:"Platform"`
-	PlatformVersion string `json:"PlatformVersion"`
-	Kernel          string `json:"Kernel"`
-	Arch            string `json:"Arch"`
-	Virtualization  string `json:"Virtualization"`
-	CPU             []int  `json:"CPU"`
-	CPUModel        string `json:"CPUModel"`
-	PhysicalCores   int    `json:"PhysicalCores"`
-	LogicalCores    int    `json:"LogicalCores"`
-	MemTotal        uint64 `json:"MemTotal"`
-	SwapTotal       uint64 `json:"SwapTotal"`
+	Name            string   `json:"Name"`
+	DisplayName     string   `json:"DisplayName,omitempty"`
+	Hostname        string   `json:"Hostname"`
+	Platform        string   `json:"Platform"`
+	PlatformVersion string   `json:"PlatformVersion"`
+	Kernel          string   `json:"Kernel"`
+	Arch            string   `json:"Arch"`
+	Virtualization  string   `json:"Virtualization"`
+	CPU             []int    `json:"CPU"`
+	CPUModel        string   `json:"CPUModel"`
+	PhysicalCores   int      `json:"PhysicalCores"`
+	LogicalCores    int      `json:"LogicalCores"`
+	MemTotal        uint64   `json:"MemTotal"`
+	SwapTotal       uint64   `json:"SwapTotal"`
+	Group           string   `json:"Group,omitempty"`
+	Tags            []string `json:"Tags,omitempty"`
 }
 
 type AkileHostState struct {
-	CPU                 float64      `json:"CPU"`
-	MemUsed             uint64       `json:"MemUsed"`
-	SwapUsed            uint64       `json:"SwapUsed"`
-	DiskUsed            uint64       `json:"DiskUsed"`
-	DiskTotal           uint64       `json:"DiskTotal"`
-	Disks               []agent.Disk `json:"Disks"`
-	NetInTransfer       uint64       `json:"NetInTransfer"`
-	NetOutTransfer      uint64       `json:"NetOutTransfer"`
-	NetInSpeed          uint64       `json:"NetInSpeed"`
-	NetOutSpeed         uint64       `json:"NetOutSpeed"`
-	DiskReadSpeed       uint64       `json:"DiskReadSpeed"`
-	DiskWriteSpeed      uint64       `json:"DiskWriteSpeed"`
-	TCP                 int          `json:"TCP"`
-	UDP                 int          `json:"UDP"`
-	Processes           int          `json:"Processes"`
-	Load1               float64      `json:"Load1"`
-	Load5               float64      `json:"Load5"`
-	Load15              float64      `json:"Load15"`
-	Uptime              uint64       `json:"Uptime"`
-	CycleNetInTransfer  uint64       `json:"CycleNetInTransfer"`
-	CycleNetOutTransfer uint64       `json:"CycleNetOutTransfer"`
-	TrafficResetDay     int          `json:"TrafficResetDay"`
-	TrafficPeriodStart  int64        `json:"TrafficPeriodStart"`
-	TrafficNextReset    int64        `json:"TrafficNextReset"`
+	CPU                 float64             `json:"CPU"`
+	CPUUser             float64             `json:"CPUUser"`
+	CPUSystem           float64             `json:"CPUSystem"`
+	CPUIOWait           float64             `json:"CPUIOWait"`
+	CPUSteal            float64             `json:"CPUSteal"`
+	CPUIRQ              float64             `json:"CPUIRQ"`
+	MemUsed             uint64              `json:"MemUsed"`
+	SwapUsed            uint64              `json:"SwapUsed"`
+	MemoryDetail        *agent.MemoryDetail `json:"MemoryDetail,omitempty"`
+	Pressure            *agent.Pressure     `json:"Pressure,omitempty"`
+	DiskUsed            uint64              `json:"DiskUsed"`
+	DiskTotal           uint64              `json:"DiskTotal"`
+	Disks               []agent.Disk        `json:"Disks"`
+	NetInTransfer       uint64              `json:"NetInTransfer"`
+	NetOutTransfer      uint64              `json:"NetOutTransfer"`
+	NetInSpeed          uint64              `json:"NetInSpeed"`
+	NetOutSpeed         uint64              `json:"NetOutSpeed"`
+	DiskReadSpeed       uint64              `json:"DiskReadSpeed"`
+	DiskWriteSpeed      uint64              `json:"DiskWriteSpeed"`
+	DiskDevices         []agent.DiskDevice  `json:"DiskDevices"`
+	Sensors             []agent.Sensor      `json:"Sensors"`
+	Probes              []AkileProbe        `json:"Probes"`
+	TCP                 int                 `json:"TCP"`
+	UDP                 int                 `json:"UDP"`
+	TCPStates           map[string]int      `json:"TCPStates,omitempty"`
+	Processes           int                 `json:"Processes"`
+	Load1               float64             `json:"Load1"`
+	Load5               float64             `json:"Load5"`
+	Load15              float64             `json:"Load15"`
+	Uptime              uint64              `json:"Uptime"`
+	CycleNetInTransfer  uint64              `json:"CycleNetInTransfer"`
+	CycleNetOutTransfer uint64              `json:"CycleNetOutTransfer"`
+	TrafficResetDay     int                 `json:"TrafficResetDay"`
+	TrafficPeriodStart  int64               `json:"TrafficPeriodStart"`
+	TrafficNextReset    int64               `json:"TrafficNextReset"`
+}
+
+// AkileProbe is the public view of a probe result. Targets are left out
+// because they often name origin servers.
+type AkileProbe struct {
+	Name        string  `json:"name"`
+	Type        string  `json:"type"`
+	Timestamp   int64   `json:"ts"`
+	LatencyMs   float64 `json:"latency_ms"`
+	LossPercent float64 `json:"loss_percent"`
+	StatusCode  int     `json:"status_code,omitempty"`
 }
@@ -0,0 +1,85 @@
+package application
+
+import (
+	"slices"
+	"time"
+
+	"vps-agent/internal/server/domain"
+)
+
+// UptimeReport is the availability of the tracked nodes for one calendar
+// month, in the server's time zone.
+type UptimeReport struct {
+	Month       string       `json:"month"`
+	GeneratedAt int64        `json:"generated_at"`
+	Nodes       []NodeUptime `json:"nodes"`
+}
+
+// NodeUptime is one node's availability today, in the report's month, per
+// day of that month and in its current billing period, with the outages
+// that fall in the month.
+type NodeUptime struct {
+	NodeID      string              `json:"node_id"`
+	DisplayName string              `json:"display_name,omitempty"`
+	Seller      string              `json:"seller,omitempty"`
+	Cycle       string              `json:"cycle,omitempty"`
+	Online      bool                `json:"online"`
+	Since       int64               `json:"since"`
+	Today       domain.UptimeStat   `json:"today"`
+	Month       domain.UptimeStat   `json:"month"`
+	Billing     domain.UptimeStat   `json:"billing"`
+	Days        []domain.UptimeStat `json:"days"`
+	Outages     []OutageEntry       `json:"outages"`
+}
+
+// OutageEntry is an outage as reported, with its length worked out.
+type OutageEntry struct {
+	Start    int64 `json:"start"`
+	End      int64 `json:"end,omitempty"`
+	Duration int64 `json:"duration_seconds"`
+	Ongoing  bool  `json:"ongoing,omitempty"`
+}
+
+// Uptime builds the report for the month that starts at month. Nodes the
+// server has not seen report since it started tracking uptime are left out.
+// A node that is offline now has an outage running since its last report.
+func Uptime(nodes []domain.AdminNode, logs map[string]domain.UptimeLog, month, now time.Time) UptimeReport {
+	monthEnd := month.AddDate(0, 1, 0)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	report := UptimeReport{Month: month.Format("2006-01"), GeneratedAt: now.Unix(), Nodes: []NodeUptime{}}
+	for _, node := range nodes {
+		uptime, ok := logs[node.NodeID]
+		if !ok || uptime.Since == 0 {
+			continue
+		}
+		if !node.Online && node.LastSeen > 0 {
+			uptime.Outages = append(slices.Clone(uptime.Outages), domain.Outage{Start: node.LastSeen})
+		}
+		billingStart, billingEnd := domain.BillingPeriod(now, node.Info.DueTime, node.Info.Cycle, node.Info.TrafficResetDay)
+		entry := NodeUptime{
+			NodeID:      node.NodeID,
+			DisplayName: node.Info.DisplayName,
+			Seller:      node.Info.Seller,
+			Cycle:       node.Info.Cycle,
+			Online:      node.Online,
+			Since:       uptime.Since,
+			Today:       uptime.Stat(today, today.AddDate(0, 0, 1), now),
+			Month:       uptime.Stat(month, monthEnd, now),
+			Billing:     uptime.Stat(billingStart, billingEnd, now),
+			Outages:     []OutageEntry{},
+		}
+		for day := month; day.Before(monthEnd); day = day.AddDate(0, 0, 1) {
+			entry.Days = append(entry.Days, uptime.Stat(day, day.AddDate(0, 0, 1), now))
+		}
+		for _, outage := range uptime.OutagesIn(month, monthEnd) {
+			entry.Outages = append(entry.Outages, OutageEntry{
+				Start:    outage.Start,
+				End:      outage.End,
+				Duration: int64(outage.Duration(now).Seconds()),
+				Ongoing:  outage.Ongoing(),
+			})
+		}
+		report.Nodes = append(report.Nodes, entry)
+	}
+	return report
+}
@@ -0,0 +1,50 @@
+package application
+
+import (
+	"testing"
+	"time"
+
+	"vps-agent/internal/server/domain"
+)
+
+func TestUptimeReportsMonthDaysAndOngoingOutage(t *testing.T) {
+	loc := time.FixedZone("test", 8*60*60)
+	month := time.Date(2026, time.July, 1, 0, 0, 0, 0, loc)
+	now := time.Date(2026, time.July, 3, 12, 0, 0, 0, loc)
+	since := month.Unix()
+	lastSeen := now.Add(-time.Hour).Unix()
+	nodes := []domain.AdminNode{
+		{NodeID: "up", Online: true, LastSeen: now.Unix(), Info: domain.HostInfo{Seller: "seller", Cycle: "月", DueTime: time.Date(2026, time.August, 2, 0, 0, 0, 0, loc).UnixMilli()}},
+		{NodeID: "down", LastSeen: lastSeen},
+		{NodeID: "pending"},
+	}
+	logs := map[string]domain.UptimeLog{
+		"up":   {Since: since, Outages: []domain.Outage{{Start: month.Add(23 * time.Hour).Unix(), End: month.Add(25 * time.Hour).Unix()}}},
+		"down": {Since: since},
+	}
+
+	report := Uptime(nodes, logs, month, now)
+	if report.Month != "2026-07" || len(report.Nodes) != 2 {
+		t.Fatalf("report = %#v", report)
+	}
+	up := report.Nodes[0]
+	if len(up.Days) != 31 || up.Days[0].Downtime != 3600 || up.Days[1].Downtime != 3600 || up.Days[3].Monitored != 0 {
+		t.Fatalf("days = %#v", up.Days[:4])
+	}
+	if up.Month.Downtime != 7200 || up.Month.Monitored != now.Unix()-since || up.Today.Downtime != 0 || up.Today.Percent != 100 {
+		t.Fatalf("up = %#v", up)
+	}
+	if up.Billing.Start != time.Date(2026, time.July, 2, 0, 0, 0, 0, loc).Unix() || up.Billing.Downtime != 3600 {
+		t.Fatalf("billing = %#v", up.Billing)
+	}
+	if len(up.Outages) != 1 || up.Outages[0].Duration != 7200 || up.Outages[0].Ongoing {
+		t.Fatalf("outages = %#v", up.Outages)
+	}
+	down := report.Nodes[1]
+	if len(down.Outages) != 1 || !down.Outages[0].Ongoing || down.Outages[0].Duration != 3600 || down.Today.Downtime != 3600 {
+		t.Fatalf("down = %#v", down)
+	}
+	if len(logs["down"].Outages) != 0 {
+		t.Fatalf("report changed the stored log: %#v", logs["down"])
+	}
+}
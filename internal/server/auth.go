@@ -10,13 +10,20 @@ import (
 	"time"
 )
 
-func (s *Server) agentAuthorized(r *http.Request) bool {
+// agentNode checks the agent's node ID and token and returns the node it
+// reports for. An agent still using an ID the node was renamed from is
+// resolved to the current one.
+func (s *Server) agentNode(r *http.Request) (string, bool) {
 	nodeID := strings.TrimSpace(r.Header.Get("X-Node-ID"))
 	if !validNodeID(nodeID) {
-		return false
+		return "", false
 	}
 	token := bearerToken(r.Header.Get("Authorization"))
-	return token != "" && s.store.ValidNodeToken(nodeID, hashToken(token))
+	if token == "" {
+		return "", false
+	}
+	nodeID = s.store.ResolveNodeID(nodeID)
+	return nodeID, s.store.ValidNodeToken(nodeID, hashToken(token))
 }
 
 func bearerToken(header string) string {
@@ -35,6 +42,39 @@ func (s *Server) adminAuthorized(r *http.Request) bool {
 	return s.sessions.Valid(cookie.Value)
 }
 
+// adminViewer reports whether a status page request comes from a logged-in
+// admin on this server's own origin. Private nodes are never handed to other
+// origins, even ones CORS_ORIGINS lets read the public data.
+func (s *Server) adminViewer(r *http.Request) bool {
+	return requestOriginSameHost(r, strings.TrimSpace(r.Header.Get("Origin"))) && s.adminAuthorized(r)
+}
+
+// dashboardAccess reports whether the request may see a dashboard: it has
+// no password, the visitor entered it, or an admin is looking.
+func (s *Server) dashboardAccess(r *http.Request, dashboard Dashboard) bool {
+	if dashboard.PasswordHash == "" || s.adminViewer(r) {
+		return true
+	}
+	cookie, err := r.Cookie("monitor_dashboard")
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	return s.sessions.ValidGrant(cookie.Value, dashboardScope(dashboard))
+}
+
+// dashboardScope ties a dashboard cookie to the slug and password it was
+// issued for, so changing the password logs every visitor out.
+func dashboardScope(dashboard Dashboard) string {
+	return "dashboard\n" + dashboard.Slug + "\n" + dashboard.PasswordHash
+}
+
+func dashboardCookie(r *http.Request, slug, value string, maxAge time.Duration) *http.Cookie {
+	cookie := adminCookie(r, value, maxAge)
+	cookie.Name = "monitor_dashboard"
+	cookie.Path = "/d/" + slug
+	return cookie
+}
+
 func adminCookie(r *http.Request, value string, maxAge time.Duration) *http.Cookie {
 	secure := r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
 	return &http.Cookie{
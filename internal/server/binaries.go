@@ -1,6 +1,82 @@
 package server
 
-import "embed"
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"strings"
+	"sync"
+
+	"vps-agent/internal/agent"
+	"vps-agent/internal/buildinfo"
+	"vps-agent/internal/release"
+)
 
 //go:embed agent_bins/*
-var agentBinaries embed.FS
+var embeddedAgentBinaries embed.FS
+
+// agentBinaries is a variable so tests can serve binaries without a release
+// build.
+var agentBinaries fs.FS = embeddedAgentBinaries
+
+// agentBinarySums caches the SHA-256 of each embedded binary; a missing
+// binary is cached as "".
+var agentBinarySums sync.Map
+
+// agentBinaryName is the release file name for a platform. 32-bit ARM is
+// only built for GOARM=7.
+func agentBinaryName(goos, goarch string) string {
+	if goarch == "arm" {
+		goarch = "armv7"
+	}
+	name := "vps-agent-" + goos + "-" + goarch
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+func agentBinarySHA256(name string) (string, bool) {
+	if sum, ok := agentBinarySums.Load(name); ok {
+		return sum.(string), sum != ""
+	}
+	sum := ""
+	if data, err := fs.ReadFile(agentBinaries, "agent_bins/"+name); err == nil {
+		digest := sha256.Sum256(data)
+		sum = hex.EncodeToString(digest[:])
+	}
+	agentBinarySums.Store(name, sum)
+	return sum, sum != ""
+}
+
+// agentUpdate offers the embedded build for the agent's platform when it is
+// newer than the version the agent reported.
+func agentUpdate(metrics agent.Metrics) *agent.UpdateOffer {
+	if !buildinfo.Newer(buildinfo.Version, metrics.AgentVersion) {
+		return nil
+	}
+	name := agentBinaryName(metrics.OS, metrics.Arch)
+	if !validDownloadName(name) {
+		return nil
+	}
+	sum, ok := agentBinarySHA256(name)
+	if !ok {
+		return nil
+	}
+	return &agent.UpdateOffer{Version: buildinfo.Version, URL: "/download/" + name, SHA256: sum}
+}
+
+// releasePublicKey is the key the embedded manifest was signed with, or ""
+// for an unsigned build. Install commands pin it on new agents.
+func releasePublicKey() string {
+	data, err := fs.ReadFile(agentBinaries, "agent_bins/"+release.PublicKeyName)
+	if err != nil {
+		return ""
+	}
+	key := strings.TrimSpace(string(data))
+	if _, err := release.ParsePublicKey(key); err != nil {
+		return ""
+	}
+	return key
+}
@@ -5,32 +5,61 @@ import (
 	"time"
 )
 
+// maxCacheEntries is how many keys ResponseCache holds. When it is full the
+// expired keys are dropped, and if none are, a new key is built without being
+// cached. Keys can come from request parameters such as ?tag=, so the map must
+// not grow with every distinct value a client sends.
+const maxCacheEntries = 256
+
+// ResponseCache keeps built responses for up to a second, one per key, so a
+// burst of clients shares one build. MarkDirty drops all of them at once.
 type ResponseCache struct {
 	mu      sync.Mutex
+	entries map[string]*cachedResponse
+}
+
+type cachedResponse struct {
 	dirty   bool
 	expires time.Time
 	data    []byte
 }
 
 func NewResponseCache() *ResponseCache {
-	return &ResponseCache{dirty: true}
+	return &ResponseCache{entries: map[string]*cachedResponse{}}
 }
 
 func (c *ResponseCache) MarkDirty() {
 	c.mu.Lock()
-	c.dirty = true
+	for _, entry := range c.entries {
+		entry.dirty = true
+	}
 	c.mu.Unlock()
 }
 
-func (c *ResponseCache) Get(build func() []byte) []byte {
+func (c *ResponseCache) Get(key string, build func() []byte) []byte {
 	now := time.Now()
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if !c.dirty && now.Before(c.expires) && c.data != nil {
-		return c.data
+	entry := c.entries[key]
+	if entry == nil {
+		if len(c.entries) >= maxCacheEntries {
+			for k, old := range c.entries {
+				if old.dirty || !now.Before(old.expires) {
+					delete(c.entries, k)
+				}
+			}
+			if len(c.entries) >= maxCacheEntries {
+				return build()
+			}
+		}
+		entry = &cachedResponse{dirty: true}
+		c.entries[key] = entry
+	}
+	if !entry.dirty && now.Before(entry.expires) && entry.data != nil {
+		return entry.data
 	}
-	c.data = build()
-	c.expires = now.Add(time.Second)
-	c.dirty = false
-	return c.data
+	entry.data = build()
+	entry.expires = now.Add(time.Second)
+	entry.dirty = false
+	return entry.data
 }
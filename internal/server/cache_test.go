@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -12,11 +13,11 @@ func TestResponseCacheReusesValueUntilDirty(t *testing.T) {
 	cache := NewResponseCache()
 	builds := 0
 
-	first := cache.Get(func() []byte {
+	first := cache.Get("hosts", func() []byte {
 		builds++
 		return []byte("first")
 	})
-	second := cache.Get(func() []byte {
+	second := cache.Get("hosts", func() []byte {
 		builds++
 		return []byte("second")
 	})
@@ -29,7 +30,7 @@ func TestResponseCacheReusesValueUntilDirty(t *testing.T) {
 	}
 
 	cache.MarkDirty()
-	third := cache.Get(func() []byte {
+	third := cache.Get("hosts", func() []byte {
 		builds++
 		return []byte("third")
 	})
@@ -45,9 +46,9 @@ func TestResponseCacheReusesValueUntilDirty(t *testing.T) {
 func TestResponseCacheRefreshesExpiredValue(t *testing.T) {
 	cache := NewResponseCache()
 
-	first := cache.Get(func() []byte { return []byte("first") })
-	cache.expires = time.Now().Add(-time.Second)
-	second := cache.Get(func() []byte { return []byte("second") })
+	first := cache.Get("hosts", func() []byte { return []byte("first") })
+	cache.entries["hosts"].expires = time.Now().Add(-time.Second)
+	second := cache.Get("hosts", func() []byte { return []byte("second") })
 
 	if !bytes.Equal(first, []byte("first")) {
 		t.Fatalf("first value = %q", first)
@@ -69,7 +70,7 @@ func TestResponseCacheConcurrentGetBuildsOnce(t *testing.T) {
 		go func() {
 			defer wg.Done()
 			<-start
-			results <- cache.Get(func() []byte {
+			results <- cache.Get("hosts", func() []byte {
 				atomic.AddInt32(&builds, 1)
 				return []byte("shared")
 			})
@@ -89,3 +90,52 @@ func TestResponseCacheConcurrentGetBuildsOnce(t *testing.T) {
 		}
 	}
 }
+
+func TestResponseCacheKeepsKeysApart(t *testing.T) {
+	cache := NewResponseCache()
+	public := cache.Get("public", func() []byte { return []byte("public") })
+	private := cache.Get("private", func() []byte { return []byte("private") })
+	if !bytes.Equal(public, []byte("public")) || !bytes.Equal(private, []byte("private")) {
+		t.Fatalf("values = %q/%q", public, private)
+	}
+
+	cache.MarkDirty()
+	if got := cache.Get("private", func() []byte { return []byte("rebuilt") }); !bytes.Equal(got, []byte("rebuilt")) {
+		t.Fatalf("value after dirty = %q", got)
+	}
+}
+
+func TestResponseCacheDropsExpiredEntriesWhenFull(t *testing.T) {
+	cache := NewResponseCache()
+	for i := range maxCacheEntries - 1 {
+		key := fmt.Sprintf("hosts?tag=t%d", i)
+		cache.Get(key, func() []byte { return []byte(key) })
+	}
+	cache.Get("hosts", func() []byte { return []byte("live") })
+	for key, entry := range cache.entries {
+		if key != "hosts" {
+			entry.expires = time.Now().Add(-time.Second)
+		}
+	}
+	cache.Get("hosts?tag=new", func() []byte { return []byte("new") })
+	if len(cache.entries) != 2 || cache.entries["hosts"] == nil {
+		t.Fatalf("entries = %d, live entry kept = %t", len(cache.entries), cache.entries["hosts"] != nil)
+	}
+}
+
+func TestResponseCacheDoesNotGrowPastLimit(t *testing.T) {
+	cache := NewResponseCache()
+	for i := range maxCacheEntries {
+		key := fmt.Sprintf("hosts?tag=t%d", i)
+		cache.Get(key, func() []byte { return []byte(key) })
+	}
+	builds := 0
+	for range 2 {
+		if got := cache.Get("hosts?tag=extra", func() []byte { builds++; return []byte("extra") }); !bytes.Equal(got, []byte("extra")) {
+			t.Fatalf("value = %q", got)
+		}
+	}
+	if len(cache.entries) != maxCacheEntries || cache.entries["hosts?tag=extra"] != nil || builds != 2 {
+		t.Fatalf("entries = %d, builds = %d", len(cache.entries), builds)
+	}
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	serverdomain "vps-agent/internal/server/domain"
+)
+
+// dashboardAccessTTL is how long a visitor stays let in after entering a
+// dashboard password.
+const dashboardAccessTTL = 7 * 24 * time.Hour
+
+// handleDashboard serves the dashboards under /d/{slug}: the status page and
+// its own config.json, WebSocket, node list and /info, each limited to the
+// nodes the dashboard selects.
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	slug, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/d/"), "/")
+	dashboard, ok := s.findDashboard(slug)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if rest == "login" {
+		s.handleDashboardLogin(w, r, dashboard)
+		return
+	}
+	if rest != "" && !s.dashboardAccess(r, dashboard) {
+		http.Error(w, "dashboard password required", http.StatusUnauthorized)
+		return
+	}
+	page := statusPage{
+		base:     "/d/" + slug,
+		siteName: dashboard.SiteName,
+		theme:    dashboard.Theme,
+		view:     func() (hostView, bool) { return s.dashboardView(r, slug) },
+	}
+	s.serveStatusPage(w, r, rest, page)
+}
+
+// handleDashboardLogin checks a dashboard password and lets the visitor in
+// with a cookie scoped to the dashboard's path.
+func (s *Server) handleDashboardLogin(w http.ResponseWriter, r *http.Request, dashboard Dashboard) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+	if !s.validAdminOrigin(r) {
+		http.Error(w, "invalid request origin", http.StatusForbidden)
+		return
+	}
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !dashboard.CheckPassword(req.Password) {
+		time.Sleep(300 * time.Millisecond)
+		http.Error(w, "invalid dashboard password", http.StatusUnauthorized)
+		return
+	}
+	grant := s.sessions.Grant(dashboardScope(dashboard), dashboardAccessTTL)
+	http.SetCookie(w, dashboardCookie(r, dashboard.Slug, grant, dashboardAccessTTL))
+	writeJSON(w, map[string]bool{"ok": true})
+}
+
+func (s *Server) findDashboard(slug string) (Dashboard, bool) {
+	if !serverdomain.ValidDashboardSlug(slug) {
+		return Dashboard{}, false
+	}
+	for _, dashboard := range s.store.Dashboards() {
+		if dashboard.Slug == slug {
+			return dashboard, true
+		}
+	}
+	return Dashboard{}, false
+}
+
+// dashboardView looks the dashboard up again and reports whether the
+// request may still see it, so a stream ends once the dashboard is deleted
+// or its password changes.
+func (s *Server) dashboardView(r *http.Request, slug string) (hostView, bool) {
+	dashboard, ok := s.findDashboard(slug)
+	if !ok || !s.dashboardAccess(r, dashboard) {
+		return hostView{}, false
+	}
+	admin := s.adminViewer(r)
+	key := "dashboard/" + slug
+	if admin {
+		key += "-private"
+	}
+	return hostView{key: key, filter: dashboard.Filter(admin)}, true
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDashboardsServeOwnNodesAndConfig(t *testing.T) {
+	s := newTestServer(t)
+	for _, nodeID := range []string{"US-node-001", "JP-node-001"} {
+		if err := s.store.UpsertReport(sampleMetrics(nodeID, 100, 200), 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.store.UpsertInfo(HostInfo{Name: "US-node-001", Group: "US"}); err != nil {
+		t.Fatal(err)
+	}
+	token, err := s.sessions.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, body := range []string{
+		`[{"slug":"Bad Slug","site_name":"x","tags":["us"]}]`,
+		`[{"slug":"us","site_name":"x","tags":["us"]},{"slug":"US","site_name":"y","tags":["us"]}]`,
+		`[{"slug":"us","site_name":"x","nodes":["bad/id"]}]`,
+		`[{"slug":"us","site_name":"x","tags":["us"],"has_password":true}]`,
+		`[{"slug":"all","site_name":"Everything"}]`,
+	} {
+		resp := httptest.NewRecorder()
+		s.handleAdminDashboards(resp, adminRequestWithBody(http.MethodPost, "/api/admin/dashboards", token, body))
+		if resp.Code != http.StatusBadRequest {
+			t.Fatalf("save %s: status = %d", body, resp.Code)
+		}
+	}
+	resp := httptest.NewRecorder()
+	s.handleAdminDashboards(resp, adminRequestWithBody(http.MethodPost, "/api/admin/dashboards", "", `[]`))
+	if resp.Code != http.StatusUnauthorized {
+		t.Fatalf("save without login: status = %d", resp.Code)
+	}
+	resp = httptest.NewRecorder()
+	s.handleAdminDashboards(resp, adminRequestWithBody(http.MethodPost, "/api/admin/dashboards", token, `[{"slug":"us","site_name":"US Customers","tags":["us"],"theme":"dark"},{"slug":"jp","site_name":"Japan","nodes":["JP-node-001"]}]`))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("save dashboards: status = %d body = %s", resp.Code, resp.Body.String())
+	}
+
+	get := func(target string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Host = "monitor.example.com"
+		resp := httptest.NewRecorder()
+		s.handleDashboard(resp, req)
+		return resp
+	}
+	for target, want := range map[string]string{"/d/us/api/nodes": "US-node-001", "/d/jp/api/nodes": "JP-node-001"} {
+		var hosts []AkileHost
+		decodeJSONResponse(t, get(target), &hosts)
+		if len(hosts) != 1 || hosts[0].Host.Name != want {
+			t.Fatalf("%s hosts = %#v", target, hosts)
+		}
+	}
+	var cfg map[string]string
+	decodeJSONResponse(t, get("/d/us/config.json"), &cfg)
+	if cfg["siteName"] != "US Customers" || cfg["theme"] != "dark" || cfg["socket"] != "ws://monitor.example.com/d/us/ws" || cfg["apiURL"] != "http://monitor.example.com/d/us" {
+		t.Fatalf("dashboard config = %#v", cfg)
+	}
+	var infos []HostInfo
+	decodeJSONResponse(t, get("/d/jp/info"), &infos)
+	if len(infos) != 0 {
+		t.Fatalf("jp infos = %#v", infos)
+	}
+	if resp := get("/d/us"); resp.Code != http.StatusOK || !strings.Contains(resp.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("dashboard page: status = %d type = %q", resp.Code, resp.Header().Get("Content-Type"))
+	}
+	for _, target := range []string{"/d/missing", "/d/us/other", "/d/"} {
+		if resp := get(target); resp.Code != http.StatusNotFound {
+			t.Fatalf("%s: status = %d", target, resp.Code)
+		}
+	}
+}
+
+func TestDashboardPasswordGatesDataUntilChanged(t *testing.T) {
+	s := newTestServer(t)
+	if err := s.store.UpsertReport(sampleMetrics("JP-node-001", 100, 200), 10); err != nil {
+		t.Fatal(err)
+	}
+	token, err := s.sessions.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveDashboards := func(body string) {
+		t.Helper()
+		resp := httptest.NewRecorder()
+		s.handleAdminDashboards(resp, adminRequestWithBody(http.MethodPost, "/api/admin/dashboards", token, body))
+		if resp.Code != http.StatusOK {
+			t.Fatalf("save dashboards: status = %d body = %s", resp.Code, resp.Body.String())
+		}
+	}
+	saveDashboards(`[{"slug":"jp","site_name":"Japan","nodes":["JP-node-001"],"password":"open sesame"}]`)
+
+	resp := httptest.NewRecorder()
+	s.handleAdminDashboards(resp, authedAdminRequest(http.MethodGet, "/api/admin/dashboards", token))
+	if body := resp.Body.String(); !strings.Contains(body, `"has_password":true`) || strings.Contains(body, "pbkdf2") {
+		t.Fatalf("admin dashboards = %s", body)
+	}
+
+	visit := func(target string, cookie *http.Cookie) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Host = "monitor.example.com"
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		resp := httptest.NewRecorder()
+		s.handleDashboard(resp, req)
+		return resp.Code
+	}
+	if code := visit("/d/jp/api/nodes", nil); code != http.StatusUnauthorized {
+		t.Fatalf("nodes without password: status = %d", code)
+	}
+	if code := visit("/d/jp/config.json", nil); code != http.StatusUnauthorized {
+		t.Fatalf("config without password: status = %d", code)
+	}
+	if code := visit("/d/jp", nil); code != http.StatusOK {
+		t.Fatalf("page without password: status = %d", code)
+	}
+	if code := visit("/d/jp/api/nodes", &http.Cookie{Name: "monitor_admin", Value: token}); code != http.StatusOK {
+		t.Fatalf("nodes as admin: status = %d", code)
+	}
+
+	login := func(password string) *httptest.ResponseRecorder {
+		t.Helper()
+		resp := httptest.NewRecorder()
+		s.handleDashboard(resp, adminRequestWithBody(http.MethodPost, "/d/jp/login", "", `{"password":"`+password+`"}`))
+		return resp
+	}
+	if resp := login("wrong"); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong password: status = %d", resp.Code)
+	}
+	resp = login("open sesame")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("login: status = %d body = %s", resp.Code, resp.Body.String())
+	}
+	cookies := resp.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "monitor_dashboard" || cookies[0].Path != "/d/jp" || !cookies[0].HttpOnly {
+		t.Fatalf("login cookies = %#v", cookies)
+	}
+	if code := visit("/d/jp/api/nodes", cookies[0]); code != http.StatusOK {
+		t.Fatalf("nodes after login: status = %d", code)
+	}
+
+	saveDashboards(`[{"slug":"jp","site_name":"Japan","nodes":["JP-node-001"],"has_password":true}]`)
+	if code := visit("/d/jp/api/nodes", cookies[0]); code != http.StatusOK {
+		t.Fatalf("nodes after keeping the password: status = %d", code)
+	}
+	saveDashboards(`[{"slug":"jp","site_name":"Japan","nodes":["JP-node-001"],"password":"new secret"}]`)
+	if code := visit("/d/jp/api/nodes", cookies[0]); code != http.StatusUnauthorized {
+		t.Fatalf("nodes after changing the password: status = %d", code)
+	}
+	saveDashboards(`[{"slug":"jp","site_name":"Japan","nodes":["JP-node-001"]}]`)
+	if code := visit("/d/jp/api/nodes", nil); code != http.StatusOK {
+		t.Fatalf("nodes after removing the password: status = %d", code)
+	}
+}
@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	BulkAdd    = "add"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+	BulkToken  = "token"
+)
+
+var ErrUnknownNode = errors.New("unknown node")
+
+// BulkOp is one item of a bulk node request. Info carries the fields an
+// update changes; TokenHash is filled in by the server for a token item and
+// never read from the request.
+type BulkOp struct {
+	Op        string         `json:"op"`
+	NodeID    string         `json:"node_id"`
+	Info      *HostInfoPatch `json:"info,omitempty"`
+	TokenHash string         `json:"-"`
+}
+
+// BulkResult reports what happened to the BulkOp at the same index.
+type BulkResult struct {
+	Op       string            `json:"op"`
+	NodeID   string            `json:"node_id"`
+	OK       bool              `json:"ok"`
+	Error    string            `json:"error,omitempty"`
+	Commands map[string]string `json:"commands,omitempty"`
+}
+
+// HostInfoPatch lists the HostInfo fields a bulk update sets. Fields left
+// out of the request keep their current value.
+type HostInfoPatch struct {
+	DisplayName     *string   `json:"display_name"`
+	DueTime         *int64    `json:"due_time"`
+	BuyURL          *string   `json:"buy_url"`
+	Seller          *string   `json:"seller"`
+	Price           *string   `json:"price"`
+	Cycle           *string   `json:"cycle"`
+	Bandwidth       *string   `json:"bandwidth"`
+	Traffic         *string   `json:"traffic"`
+	TrafficResetDay *int      `json:"traffic_reset_day"`
+	Show            *bool     `json:"show_purchase_info"`
+	Group           *string   `json:"group"`
+	Tags            *[]string `json:"tags"`
+	Visibility      *string   `json:"visibility"`
+	RedactHost      *bool     `json:"redact_host"`
+	PublicName      *string   `json:"public_name"`
+}
+
+// Apply returns info with the patched fields replaced and its labels
+// normalized.
+func (p HostInfoPatch) Apply(info HostInfo) (HostInfo, error) {
+	setString := func(dst *string, src *string) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	if p.DueTime != nil {
+		info.DueTime = *p.DueTime
+	}
+	setString(&info.DisplayName, p.DisplayName)
+	setString(&info.BuyURL, p.BuyURL)
+	setString(&info.Seller, p.Seller)
+	setString(&info.Price, p.Price)
+	setString(&info.Cycle, p.Cycle)
+	setString(&info.Bandwidth, p.Bandwidth)
+	setString(&info.Traffic, p.Traffic)
+	setString(&info.Group, p.Group)
+	setString(&info.Visibility, p.Visibility)
+	setString(&info.PublicName, p.PublicName)
+	if p.TrafficResetDay != nil {
+		info.TrafficResetDay = *p.TrafficResetDay
+	}
+	if p.Show != nil {
+		info.Show = *p.Show
+	}
+	if p.RedactHost != nil {
+		info.RedactHost = *p.RedactHost
+	}
+	if p.Tags != nil {
+		info.Tags = *p.Tags
+	}
+	info.TrafficResetDay = NormalizeTrafficResetDay(info.TrafficResetDay)
+	if err := info.Normalize(); err != nil {
+		return HostInfo{}, err
+	}
+	return info, nil
+}
+
+// Validate checks the parts of an op that do not depend on stored state.
+func (op BulkOp) Validate() error {
+	switch op.Op {
+	case BulkAdd, BulkDelete, BulkToken:
+		if op.Info != nil {
+			return fmt.Errorf("%s does not take info", op.Op)
+		}
+	case BulkUpdate:
+		if op.Info == nil {
+			return fmt.Errorf("update needs info")
+		}
+		if _, err := op.Info.Apply(HostInfo{}); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown op %q", op.Op)
+	}
+	return nil
+}
+
+// RollBack marks the items that had succeeded as undone, for a batch that
+// was thrown away because another item failed.
+func RollBack(results []BulkResult) {
+	for i := range results {
+		if results[i].OK {
+			results[i].OK = false
+			results[i].Error = "rolled back"
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package domain
+
+import "testing"
+
+func TestHostInfoPatchKeepsFieldsLeftOut(t *testing.T) {
+	seller := "Acme"
+	day := 40
+	info, err := HostInfoPatch{Seller: &seller, TrafficResetDay: &day}.Apply(HostInfo{Name: "node-1", Price: "$5", Tags: []string{"edge"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Seller != "Acme" || info.Price != "$5" || info.TrafficResetDay != 31 || len(info.Tags) != 1 {
+		t.Fatalf("patched info = %#v", info)
+	}
+
+	empty := []string{}
+	if info, err := (HostInfoPatch{Tags: &empty}).Apply(info); err != nil || info.Tags != nil {
+		t.Fatalf("cleared tags = %q, %v", info.Tags, err)
+	}
+}
+
+func TestBulkOpValidate(t *testing.T) {
+	bad := "a,b"
+	for _, op := range []BulkOp{
+		{Op: "rename", NodeID: "node-1"},
+		{Op: BulkUpdate, NodeID: "node-1"},
+		{Op: BulkUpdate, NodeID: "node-1", Info: &HostInfoPatch{Group: &bad}},
+		{Op: BulkDelete, NodeID: "node-1", Info: &HostInfoPatch{}},
+	} {
+		if err := op.Validate(); err == nil {
+			t.Fatalf("Validate(%+v) succeeded", op)
+		}
+	}
+	if err := (BulkOp{Op: BulkToken, NodeID: "node-1"}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,170 @@
+package domain
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MaxDashboards          = 32
+	MaxDashboardSlugLength = 32
+	MaxSiteNameLength      = 64
+)
+
+// Dashboard themes. The empty theme leaves the choice to the visitor.
+const (
+	ThemeLight = "light"
+	ThemeDark  = "dark"
+)
+
+// Dashboard is a status page served at /d/{slug}. It lists the nodes in
+// Nodes or carrying one of the Tags; at least one of them is set.
+// PasswordHash is set when visitors must enter a password first.
+type Dashboard struct {
+	Slug         string   `json:"slug"`
+	SiteName     string   `json:"site_name"`
+	Nodes        []string `json:"nodes,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	Theme        string   `json:"theme,omitempty"`
+	PasswordHash string   `json:"password_hash,omitempty"`
+}
+
+// HostFilter picks the nodes a status page lists: the public ones, the
+// private ones too when Private is set, and of those only the ones in Nodes
+// or carrying one of the Tags when either is given. Hidden lists nodes
+// whatever their visibility; a share link uses it for the one node it names.
+type HostFilter struct {
+	Private bool
+	Hidden  bool
+	Nodes   []string
+	Tags    []string
+}
+
+// Shows reports whether the node belongs on the page.
+func (f HostFilter) Shows(nodeID string, info HostInfo) bool {
+	if !f.Hidden && !info.VisibleTo(f.Private) {
+		return false
+	}
+	if len(f.Nodes) == 0 && len(f.Tags) == 0 {
+		return true
+	}
+	if slices.Contains(f.Nodes, nodeID) {
+		return true
+	}
+	for _, tag := range f.Tags {
+		if info.HasLabel(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the HostFilter for the dashboard's node selection.
+func (d Dashboard) Filter(private bool) HostFilter {
+	return HostFilter{Private: private, Nodes: d.Nodes, Tags: d.Tags}
+}
+
+// ValidDashboardSlug reports whether slug can be used as the /d/{slug} path
+// segment: lower-case letters, digits and inner hyphens.
+func ValidDashboardSlug(slug string) bool {
+	if slug == "" || len(slug) > MaxDashboardSlugLength || slug[0] == '-' || slug[len(slug)-1] == '-' {
+		return false
+	}
+	for _, r := range slug {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// Normalize trims the dashboard's fields and checks the slug, site name,
+// theme and tags, and that some nodes or tags are selected: a page meant for
+// a few nodes must not show the whole fleet because its selection was left
+// empty. Node IDs are only trimmed; the caller validates them.
+func (d *Dashboard) Normalize() error {
+	d.Slug = strings.ToLower(strings.TrimSpace(d.Slug))
+	if !ValidDashboardSlug(d.Slug) {
+		return fmt.Errorf("invalid dashboard slug %q", d.Slug)
+	}
+	d.SiteName = strings.TrimSpace(d.SiteName)
+	if d.SiteName == "" || utf8.RuneCountInString(d.SiteName) > MaxSiteNameLength || strings.IndexFunc(d.SiteName, unicode.IsControl) >= 0 {
+		return fmt.Errorf("invalid site name %q", d.SiteName)
+	}
+	switch d.Theme = strings.ToLower(strings.TrimSpace(d.Theme)); d.Theme {
+	case "", ThemeLight, ThemeDark:
+	default:
+		return fmt.Errorf("invalid theme %q", d.Theme)
+	}
+	nodes := make([]string, 0, len(d.Nodes))
+	for _, nodeID := range d.Nodes {
+		if nodeID = strings.TrimSpace(nodeID); nodeID != "" && !slices.Contains(nodes, nodeID) {
+			nodes = append(nodes, nodeID)
+		}
+	}
+	d.Nodes = nil
+	if len(nodes) > 0 {
+		d.Nodes = nodes
+	}
+	tags, err := NormalizeTags(d.Tags)
+	if err != nil {
+		return err
+	}
+	d.Tags = tags
+	if len(d.Nodes) == 0 && len(d.Tags) == 0 {
+		return fmt.Errorf("dashboard %q selects no nodes or tags", d.Slug)
+	}
+	return nil
+}
+
+// passwordIterations is the PBKDF2 work factor for dashboard passwords.
+const passwordIterations = 100000
+
+// HashDashboardPassword returns the stored form of a dashboard password:
+// PBKDF2-SHA256 with a random salt, as "pbkdf2-sha256$iterations$salt$key".
+func HashDashboardPassword(password string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordIterations, 32)
+	if err != nil {
+		return "", err
+	}
+	return "pbkdf2-sha256$" + strconv.Itoa(passwordIterations) + "$" + hex.EncodeToString(salt) + "$" + hex.EncodeToString(key), nil
+}
+
+// CheckPassword reports whether password opens the dashboard. A dashboard
+// without a password accepts anything.
+func (d Dashboard) CheckPassword(password string) bool {
+	if d.PasswordHash == "" {
+		return true
+	}
+	parts := strings.Split(d.PasswordHash, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+	salt, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	got, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(want))
+	return err == nil && subtle.ConstantTimeCompare(got, want) == 1
+}
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidDashboardSlug(t *testing.T) {
+	for _, slug := range []string{"a", "customer-a", "team42"} {
+		if !ValidDashboardSlug(slug) {
+			t.Fatalf("ValidDashboardSlug(%q) = false", slug)
+		}
+	}
+	for _, slug := range []string{"", "-a", "a-", "Customer", "a/b", "a.b", strings.Repeat("a", MaxDashboardSlugLength+1)} {
+		if ValidDashboardSlug(slug) {
+			t.Fatalf("ValidDashboardSlug(%q) = true", slug)
+		}
+	}
+}
+
+func TestDashboardNormalize(t *testing.T) {
+	d := Dashboard{Slug: " Customer-A ", SiteName: " Customer A ", Nodes: []string{" US-1 ", "", "US-1"}, Tags: []string{"aws", "AWS"}, Theme: "Dark"}
+	if err := d.Normalize(); err != nil {
+		t.Fatal(err)
+	}
+	if d.Slug != "customer-a" || d.SiteName != "Customer A" || len(d.Nodes) != 1 || d.Nodes[0] != "US-1" || len(d.Tags) != 1 || d.Theme != ThemeDark {
+		t.Fatalf("normalized dashboard = %#v", d)
+	}
+	for _, bad := range []Dashboard{
+		{Slug: "a b", SiteName: "x", Tags: []string{"a"}},
+		{Slug: "a", SiteName: " ", Tags: []string{"a"}},
+		{Slug: "a", SiteName: "x", Tags: []string{"a"}, Theme: "blue"},
+		{Slug: "a", SiteName: "x", Tags: []string{"a,b"}},
+		{Slug: "a", SiteName: "x", Nodes: []string{" "}},
+	} {
+		if err := bad.Normalize(); err == nil {
+			t.Fatalf("Normalize(%#v) succeeded", bad)
+		}
+	}
+}
+
+func TestHostFilterShows(t *testing.T) {
+	tagged := HostInfo{Group: "US", Tags: []string{"aws"}}
+	private := HostInfo{Visibility: VisibilityPrivate}
+	if !(HostFilter{}).Shows("any", HostInfo{}) || (HostFilter{}).Shows("p", private) || !(HostFilter{Private: true}).Shows("p", private) {
+		t.Fatal("filter without a selection should show every visible node")
+	}
+	filter := Dashboard{Nodes: []string{"JP-1"}, Tags: []string{"aws"}}.Filter(false)
+	if !filter.Shows("JP-1", HostInfo{}) || !filter.Shows("US-1", tagged) || filter.Shows("HK-1", HostInfo{}) {
+		t.Fatal("dashboard filter should show listed and tagged nodes only")
+	}
+	if filter.Shows("JP-1", HostInfo{Visibility: VisibilityHidden}) {
+		t.Fatal("hidden node shown on a dashboard that lists it")
+	}
+}
+
+func TestDashboardPassword(t *testing.T) {
+	if !(Dashboard{}).CheckPassword("") {
+		t.Fatal("dashboard without a password should be open")
+	}
+	hash, err := HashDashboardPassword("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := HashDashboardPassword("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash == other {
+		t.Fatal("password hashes should be salted")
+	}
+	d := Dashboard{PasswordHash: hash}
+	if !d.CheckPassword("s3cret") || d.CheckPassword("wrong") || d.CheckPassword("") {
+		t.Fatal("CheckPassword did not match the hashed password")
+	}
+	if (Dashboard{PasswordHash: "plain"}).CheckPassword("plain") {
+		t.Fatal("malformed hash accepted")
+	}
+}
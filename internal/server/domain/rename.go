@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+)
+
+// MaxAliases bounds how many earlier node IDs a node answers to. Renaming a
+// node past the limit forgets its oldest alias.
+const MaxAliases = 8
+
+var ErrNodeExists = errors.New("node_id already exists")
+
+// NewNodeKey returns a random internal node ID. It is assigned when a node
+// is first planned and stays the same when the node is renamed.
+func NewNodeKey() string {
+	var buf [8]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf[:])
+}
+
+// AddAlias returns aliases with oldID appended and newID dropped, so renaming
+// a node back to an earlier ID does not leave it aliased to itself.
+func AddAlias(aliases []string, oldID, newID string) []string {
+	out := make([]string, 0, len(aliases)+1)
+	for _, alias := range aliases {
+		if alias != oldID && alias != newID {
+			out = append(out, alias)
+		}
+	}
+	out = append(out, oldID)
+	if len(out) > MaxAliases {
+		out = out[len(out)-MaxAliases:]
+	}
+	return out
+}
+
+// RenameTargets points probe targets that list oldID at newID instead. It
+// reports whether any target changed.
+func RenameTargets(targets []ProbeTarget, oldID, newID string) bool {
+	changed := false
+	for i := range targets {
+		if j := slices.Index(targets[i].Nodes, oldID); j >= 0 {
+			targets[i].Nodes = slices.Clone(targets[i].Nodes)
+			targets[i].Nodes[j] = newID
+			changed = true
+		}
+	}
+	return changed
+}
+
+// RenameOverrides moves the per-node overrides of oldID to newID. It reports
+// whether there were any.
+func (o *AgentOverrides) RenameOverrides(oldID, newID string) bool {
+	values, ok := o.Nodes[oldID]
+	if !ok {
+		return false
+	}
+	delete(o.Nodes, oldID)
+	o.Nodes[newID] = values
+	return true
+}
+
+// RenameDashboards points dashboards that list oldID at newID instead. It
+// reports whether any dashboard changed.
+func RenameDashboards(dashboards []Dashboard, oldID, newID string) bool {
+	changed := false
+	for i := range dashboards {
+		if j := slices.Index(dashboards[i].Nodes, oldID); j >= 0 {
+			dashboards[i].Nodes = slices.Clone(dashboards[i].Nodes)
+			dashboards[i].Nodes[j] = newID
+			changed = true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestAddAliasDropsNewIDAndKeepsNewest(t *testing.T) {
+	if got := AddAlias([]string{"a", "b"}, "c", "a"); !reflect.DeepEqual(got, []string{"b", "c"}) {
+		t.Fatalf("AddAlias = %q", got)
+	}
+	var aliases []string
+	for i := 0; i < MaxAliases+2; i++ {
+		aliases = AddAlias(aliases, fmt.Sprintf("n%d", i), "current")
+	}
+	if len(aliases) != MaxAliases || aliases[0] != "n2" || aliases[MaxAliases-1] != fmt.Sprintf("n%d", MaxAliases+1) {
+		t.Fatalf("aliases = %q", aliases)
+	}
+}
+
+func TestRenameTargetsAndOverrides(t *testing.T) {
+	shared := []string{"old", "other"}
+	targets := []ProbeTarget{{Name: "CT", Nodes: shared}, {Name: "CU", Tags: []string{"edge"}}}
+	if !RenameTargets(targets, "old", "new") || !reflect.DeepEqual(targets[0].Nodes, []string{"new", "other"}) {
+		t.Fatalf("targets = %#v", targets)
+	}
+	if shared[0] != "old" {
+		t.Fatal("RenameTargets wrote through to the caller's node list")
+	}
+	if RenameTargets(targets, "missing", "new") {
+		t.Fatal("RenameTargets reported a change for a node no target lists")
+	}
+
+	overrides := AgentOverrides{Nodes: map[string]map[string]string{"old": {"MOUNTS": "/"}}}
+	if !overrides.RenameOverrides("old", "new") || overrides.Nodes["new"]["MOUNTS"] != "/" || overrides.Nodes["old"] != nil {
+		t.Fatalf("overrides = %#v", overrides)
+	}
+	if (&AgentOverrides{}).RenameOverrides("old", "new") {
+		t.Fatal("RenameOverrides reported a change without overrides")
+	}
+}
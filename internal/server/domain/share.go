@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Share links last a week unless the admin picks otherwise, and at most a
+// year.
+const (
+	DefaultShareTTL = 7 * 24 * time.Hour
+	MaxShareTTL     = 365 * 24 * time.Hour
+)
+
+// NewShareSecret returns a random key for signing share links. Replacing
+// the stored key revokes every link signed with the old one.
+func NewShareSecret() string {
+	var buf [32]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf[:])
+}
+
+// SignShare returns a token for a read-only link to the node with internal
+// ID key, valid until expires. Tokens name the internal ID, so a link keeps
+// working when the node is renamed.
+func SignShare(secret, key string, expires time.Time) string {
+	unix := strconv.FormatInt(expires.Unix(), 10)
+	return key + "." + unix + "." + shareSignature(secret, key, unix)
+}
+
+// ParseShare checks a share token and returns the internal node ID it was
+// signed for and when it expires.
+func ParseShare(secret, token string, now time.Time) (string, time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if secret == "" || len(parts) != 3 || parts[0] == "" {
+		return "", time.Time{}, false
+	}
+	unix, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || now.Unix() > unix {
+		return "", time.Time{}, false
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(shareSignature(secret, parts[0], parts[1]))) {
+		return "", time.Time{}, false
+	}
+	return parts[0], time.Unix(unix, 0), true
+}
+
+func shareSignature(secret, key, expires string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("share\n" + key + "\n" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
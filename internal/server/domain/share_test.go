@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignAndParseShare(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	token := SignShare("secret", "0123abcd", now.Add(time.Hour))
+	key, expires, ok := ParseShare("secret", token, now)
+	if !ok || key != "0123abcd" || expires.Unix() != now.Add(time.Hour).Unix() {
+		t.Fatalf("ParseShare = %q, %v, %v", key, expires, ok)
+	}
+	if _, _, ok := ParseShare("secret", token, now.Add(2*time.Hour)); ok {
+		t.Fatal("expired token accepted")
+	}
+	if _, _, ok := ParseShare("other", token, now); ok {
+		t.Fatal("token accepted with another secret")
+	}
+	if _, _, ok := ParseShare("", token, now); ok {
+		t.Fatal("token accepted without a secret")
+	}
+	forged := SignShare("secret", "0123abcd", now.Add(time.Hour))
+	forged = "ffffffff" + forged[len("0123abcd"):]
+	for _, bad := range []string{"", "a.b", "a.b.c.d", forged, token + "x"} {
+		if _, _, ok := ParseShare("secret", bad, now); ok {
+			t.Fatalf("ParseShare(%q) succeeded", bad)
+		}
+	}
+	if NewShareSecret() == NewShareSecret() {
+		t.Fatal("share secrets should be random")
+	}
+}
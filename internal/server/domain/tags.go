@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MaxTags              = 16
+	MaxTagLength         = 32
+	MaxDisplayNameLength = 64
+)
+
+// NormalizeTag trims a tag or group name and checks that it can be used in
+// a query string and in the comma-separated inputs of the admin console.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.TrimSpace(tag)
+	if utf8.RuneCountInString(tag) > MaxTagLength || strings.ContainsAny(tag, ",&=?#/") {
+		return "", fmt.Errorf("invalid tag %q", tag)
+	}
+	for _, r := range tag {
+		if unicode.IsControl(r) {
+			return "", fmt.Errorf("invalid tag %q", tag)
+		}
+	}
+	return tag, nil
+}
+
+// NormalizeTags trims tags, drops empty ones and duplicates (compared without
+// case) and keeps the order they were given in.
+func NormalizeTags(tags []string) ([]string, error) {
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if tag == "" || containsTag(out, tag) {
+			continue
+		}
+		out = append(out, tag)
+	}
+	if len(out) > MaxTags {
+		return nil, fmt.Errorf("too many tags, limit is %d", MaxTags)
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+// Labels returns the group followed by the tags. Filters match any of them,
+// so a group can be used wherever a tag is accepted.
+func (info HostInfo) Labels() []string {
+	if info.Group == "" {
+		return info.Tags
+	}
+	return append([]string{info.Group}, info.Tags...)
+}
+
+// HasLabel reports whether tag is the group or one of the tags, ignoring case.
+func (info HostInfo) HasLabel(tag string) bool {
+	return containsTag(info.Labels(), tag)
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, have := range tags {
+		if strings.EqualFold(have, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize trims the display and public names, checks the visibility and
+// cleans the group and tags the way NormalizeTag and NormalizeTags do.
+func (info *HostInfo) Normalize() error {
+	displayName := strings.TrimSpace(info.DisplayName)
+	if utf8.RuneCountInString(displayName) > MaxDisplayNameLength || strings.IndexFunc(displayName, unicode.IsControl) >= 0 {
+		return fmt.Errorf("invalid display name %q", displayName)
+	}
+	info.DisplayName = displayName
+	publicName, err := normalizePublicName(info.PublicName)
+	if err != nil {
+		return err
+	}
+	info.PublicName = publicName
+	if info.Visibility, err = NormalizeVisibility(info.Visibility); err != nil {
+		return err
+	}
+	group, err := NormalizeTag(info.Group)
+	if err != nil {
+		return err
+	}
+	tags, err := NormalizeTags(info.Tags)
+	if err != nil {
+		return err
+	}
+	info.Group, info.Tags = group, tags
+	return nil
+}
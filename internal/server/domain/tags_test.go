@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTagsTrimsAndDeduplicates(t *testing.T) {
+	tags, err := NormalizeTags([]string{" aws ", "", "AWS", "edge"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 2 || tags[0] != "aws" || tags[1] != "edge" {
+		t.Fatalf("tags = %q", tags)
+	}
+	if tags, err := NormalizeTags([]string{" "}); err != nil || tags != nil {
+		t.Fatalf("blank tags = %q, %v", tags, err)
+	}
+
+	for _, bad := range []string{"a,b", "a&b", "line\nbreak", strings.Repeat("x", MaxTagLength+1)} {
+		if _, err := NormalizeTags([]string{bad}); err == nil {
+			t.Fatalf("NormalizeTags(%q) succeeded", bad)
+		}
+	}
+	many := make([]string, MaxTags+1)
+	for i := range many {
+		many[i] = strings.Repeat("t", i+1)
+	}
+	if _, err := NormalizeTags(many); err == nil {
+		t.Fatal("expected error for too many tags")
+	}
+}
+
+func TestHostInfoHasLabelMatchesGroupAndTags(t *testing.T) {
+	info := HostInfo{Group: "US", Tags: []string{"aws"}}
+	for _, tag := range []string{"us", "AWS"} {
+		if !info.HasLabel(tag) {
+			t.Fatalf("HasLabel(%q) = false", tag)
+		}
+	}
+	if info.HasLabel("edge") || (HostInfo{}).HasLabel("") {
+		t.Fatal("HasLabel matched a missing tag")
+	}
+}
+
+func TestNormalizeTrimsDisplayName(t *testing.T) {
+	info := HostInfo{DisplayName: "  Tokyo 1  ", Group: " JP "}
+	if err := info.Normalize(); err != nil || info.DisplayName != "Tokyo 1" || info.Group != "JP" {
+		t.Fatalf("normalized = %#v, %v", info, err)
+	}
+	for _, bad := range []string{"line\nbreak", strings.Repeat("x", MaxDisplayNameLength+1)} {
+		if err := (&HostInfo{DisplayName: bad}).Normalize(); err == nil {
+			t.Fatalf("display name %q accepted", bad)
+		}
+	}
+}
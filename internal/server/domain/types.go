@@ -4,18 +4,48 @@ type Settings struct {
 	SiteName string `json:"site_name"`
 }
 
-type PlannedNode struct {
-	NodeID    string `json:"node_id"`
-	CreatedAt int64  `json:"created_at"`
-	TokenHash string `json:"token_hash,omitempty"`
+// ProbeTarget is a probe defined in the admin console. It applies to every
+// node unless Nodes or Tags limit it; a node listed in Nodes or carrying one
+// of the Tags (or the group) gets it.
+type ProbeTarget struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Target string   `json:"target"`
+	WarnMs int      `json:"warn_ms,omitempty"`
+	Nodes  []string `json:"nodes,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
 }
 
-type AdminNode struct {
+// AgentOverrides are config.env settings pushed to agents, in KEY=value
+// form. A node's own entries win over the global ones.
+type AgentOverrides struct {
+	Global map[string]string            `json:"global,omitempty"`
+	Nodes  map[string]map[string]string `json:"nodes,omitempty"`
+}
+
+// PlannedNode is a node the admin has added. NodeID is what the agent sends
+// and can be renamed; ID is internal and never changes. Aliases are earlier
+// node IDs that agents not yet told about a rename still report under.
+type PlannedNode struct {
 	NodeID    string   `json:"node_id"`
-	Online    bool     `json:"online"`
-	LastSeen  int64    `json:"last_seen"`
+	ID        string   `json:"id,omitempty"`
 	CreatedAt int64    `json:"created_at"`
-	Info      HostInfo `json:"info"`
+	TokenHash string   `json:"token_hash,omitempty"`
+	Aliases   []string `json:"aliases,omitempty"`
+}
+
+type AdminNode struct {
+	NodeID         string   `json:"node_id"`
+	ID             string   `json:"id,omitempty"`
+	Aliases        []string `json:"aliases,omitempty"`
+	Online         bool     `json:"online"`
+	LastSeen       int64    `json:"last_seen"`
+	CreatedAt      int64    `json:"created_at"`
+	Info           HostInfo `json:"info"`
+	FailedServices []string `json:"failed_services,omitempty"`
+	Health         []string `json:"health,omitempty"`
+	AgentVersion   string   `json:"agent_version,omitempty"`
+	AgentFlags     []string `json:"agent_flags,omitempty"`
 }
 
 type NodeBackup struct {
@@ -26,23 +56,30 @@ type NodeBackup struct {
 
 type NodeBackupRecord struct {
 	NodeID    string   `json:"node_id"`
+	ID        string   `json:"id,omitempty"`
 	CreatedAt int64    `json:"created_at"`
 	TokenHash string   `json:"token_hash,omitempty"`
 	Info      HostInfo `json:"info"`
 }
 
 type HostInfo struct {
-	Name            string `json:"name"`
-	DueTime         int64  `json:"due_time"`
-	BuyURL          string `json:"buy_url"`
-	Seller          string `json:"seller"`
-	Price           string `json:"price"`
-	Cycle           string `json:"cycle"`
-	Bandwidth       string `json:"bandwidth"`
-	Traffic         string `json:"traffic"`
-	TrafficResetDay int    `json:"traffic_reset_day"`
-	Show            bool   `json:"show_purchase_info"`
-	AuthSecret      string `json:"auth_secret,omitempty"`
+	Name            string   `json:"name"`
+	DisplayName     string   `json:"display_name,omitempty"`
+	DueTime         int64    `json:"due_time"`
+	BuyURL          string   `json:"buy_url"`
+	Seller          string   `json:"seller"`
+	Price           string   `json:"price"`
+	Cycle           string   `json:"cycle"`
+	Bandwidth       string   `json:"bandwidth"`
+	Traffic         string   `json:"traffic"`
+	TrafficResetDay int      `json:"traffic_reset_day"`
+	Show            bool     `json:"show_purchase_info"`
+	Group           string   `json:"group,omitempty"`
+	Tags            []string `json:"tags,omitempty"`
+	Visibility      string   `json:"visibility,omitempty"`
+	RedactHost      bool     `json:"redact_host,omitempty"`
+	PublicName      string   `json:"public_name,omitempty"`
+	AuthSecret      string   `json:"auth_secret,omitempty"`
 }
 
 type TrafficStat struct {
@@ -0,0 +1,148 @@
+package domain
+
+import "time"
+
+// MaxOutages is how many outages are kept per node. Older ones are dropped
+// and the node's tracked time starts after them.
+const MaxOutages = 1000
+
+// Outage is a stretch of time a node did not report, from its last report
+// before the gap to the first one after it. End is zero while the node is
+// still offline.
+type Outage struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end,omitempty"`
+}
+
+// Ongoing reports whether the node has not come back yet.
+func (o Outage) Ongoing() bool {
+	return o.End == 0
+}
+
+// Duration is how long the outage lasted, or has lasted so far.
+func (o Outage) Duration(now time.Time) time.Duration {
+	end := o.End
+	if o.Ongoing() {
+		end = now.Unix()
+	}
+	if end < o.Start {
+		return 0
+	}
+	return time.Duration(end-o.Start) * time.Second
+}
+
+// UptimeLog is a node's availability record: the outages seen since Since,
+// the first report after the server started tracking it, oldest first.
+type UptimeLog struct {
+	Since   int64    `json:"since"`
+	Outages []Outage `json:"outages,omitempty"`
+}
+
+// Add records a finished outage, dropping the oldest ones past MaxOutages.
+func (l *UptimeLog) Add(outage Outage) {
+	if l.Since == 0 {
+		l.Since = outage.Start
+	}
+	l.Outages = append(l.Outages, outage)
+	if drop := len(l.Outages) - MaxOutages; drop > 0 {
+		l.Since = l.Outages[drop-1].End
+		l.Outages = append([]Outage(nil), l.Outages[drop:]...)
+	}
+}
+
+// UptimeStat is a node's availability over one period. Monitored is the
+// part of the period the log covers; a period with nothing monitored has no
+// meaningful Percent.
+type UptimeStat struct {
+	Start     int64   `json:"start"`
+	End       int64   `json:"end"`
+	Monitored int64   `json:"monitored_seconds"`
+	Downtime  int64   `json:"downtime_seconds"`
+	Outages   int     `json:"outages"`
+	Percent   float64 `json:"uptime_percent"`
+}
+
+// Stat returns the node's availability between start and end, counting only
+// the time from Since until now.
+func (l UptimeLog) Stat(start, end, now time.Time) UptimeStat {
+	stat := UptimeStat{Start: start.Unix(), End: end.Unix()}
+	from, to := max(stat.Start, l.Since), min(stat.End, now.Unix())
+	if l.Since == 0 || to <= from {
+		return stat
+	}
+	stat.Monitored = to - from
+	for _, outage := range l.Outages {
+		outageEnd := outage.End
+		if outage.Ongoing() {
+			outageEnd = now.Unix()
+		}
+		if down := min(outageEnd, to) - max(outage.Start, from); down > 0 {
+			stat.Downtime += down
+			stat.Outages++
+		}
+	}
+	stat.Downtime = min(stat.Downtime, stat.Monitored)
+	stat.Percent = 100 * float64(stat.Monitored-stat.Downtime) / float64(stat.Monitored)
+	return stat
+}
+
+// OutagesIn returns the outages that overlap start to end.
+func (l UptimeLog) OutagesIn(start, end time.Time) []Outage {
+	var out []Outage
+	for _, outage := range l.Outages {
+		if outage.Start < end.Unix() && (outage.Ongoing() || outage.End > start.Unix()) {
+			out = append(out, outage)
+		}
+	}
+	return out
+}
+
+// billingCycleMonths maps the billing cycles offered in the admin console to
+// their length in months. A daily cycle is handled on its own.
+var billingCycleMonths = map[string]int{
+	"月":  1,
+	"半年": 6,
+	"年":  12,
+	"三年": 36,
+	"五年": 60,
+	"十年": 120,
+}
+
+// BillingPeriod returns the billing period that contains now: the cycle
+// counted back and forth from the due date, given in milliseconds as the
+// admin console stores it. Without a due date or a known cycle, the
+// node's traffic period stands in for it.
+func BillingPeriod(now time.Time, dueMillis int64, cycle string, resetDay int) (time.Time, time.Time) {
+	months, monthly := billingCycleMonths[cycle]
+	if dueMillis <= 0 || !monthly && cycle != "日" {
+		return TrafficPeriod(now, resetDay)
+	}
+	due := time.UnixMilli(dueMillis).In(now.Location())
+	boundary := func(n int) time.Time {
+		if !monthly {
+			return due.AddDate(0, 0, n)
+		}
+		return addMonths(due, n*months)
+	}
+	var n int
+	if monthly {
+		n = ((now.Year()-due.Year())*12 + int(now.Month()-due.Month())) / months
+	} else {
+		n = int(now.Sub(due).Hours() / 24)
+	}
+	for !boundary(n).After(now) {
+		n++
+	}
+	for boundary(n - 1).After(now) {
+		n--
+	}
+	return boundary(n - 1), boundary(n)
+}
+
+// addMonths moves t by n months, keeping the day of the month or the last
+// day of shorter months.
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), 0, t.Location())
+	day := min(t.Day(), daysInMonth(first.Year(), first.Month(), t.Location()))
+	return first.AddDate(0, 0, day-1)
+}
@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUptimeLogStat(t *testing.T) {
+	day := time.Date(2026, time.July, 10, 0, 0, 0, 0, time.UTC)
+	at := func(hour int) int64 { return day.Add(time.Duration(hour) * time.Hour).Unix() }
+	log := UptimeLog{Since: at(-2)}
+	log.Add(Outage{Start: at(-1), End: at(1)})
+	log.Add(Outage{Start: at(6), End: at(9)})
+	log.Outages = append(log.Outages, Outage{Start: at(20)})
+	now := day.Add(22 * time.Hour)
+
+	stat := log.Stat(day, day.Add(24*time.Hour), now)
+	if stat.Monitored != 22*3600 || stat.Downtime != 6*3600 || stat.Outages != 3 {
+		t.Fatalf("stat = %#v", stat)
+	}
+	if want := 100 * 16.0 / 22.0; stat.Percent != want {
+		t.Fatalf("percent = %v, want %v", stat.Percent, want)
+	}
+	if stat := log.Stat(day.Add(-48*time.Hour), day.Add(-24*time.Hour), now); stat.Monitored != 0 || stat.Percent != 0 {
+		t.Fatalf("stat before tracking = %#v", stat)
+	}
+	if got := log.OutagesIn(day.Add(2*time.Hour), day.Add(7*time.Hour)); len(got) != 1 || got[0].Start != at(6) {
+		t.Fatalf("outages in window = %#v", got)
+	}
+	if got := (Outage{Start: at(20)}).Duration(now); got != 2*time.Hour {
+		t.Fatalf("ongoing duration = %v", got)
+	}
+}
+
+func TestUptimeLogAddDropsOldestOutages(t *testing.T) {
+	log := UptimeLog{Since: 1}
+	for i := range MaxOutages + 2 {
+		start := int64(100 * (i + 1))
+		log.Add(Outage{Start: start, End: start + 10})
+	}
+	if len(log.Outages) != MaxOutages || log.Outages[0].Start != 300 || log.Since != 210 {
+		t.Fatalf("len = %d, first = %#v, since = %d", len(log.Outages), log.Outages[0], log.Since)
+	}
+}
+
+func TestBillingPeriod(t *testing.T) {
+	loc := time.FixedZone("test", 8*60*60)
+	due := func(year int, month time.Month, day int) int64 {
+		return time.Date(year, month, day, 0, 0, 0, 0, loc).UnixMilli()
+	}
+	now := time.Date(2026, time.July, 20, 12, 0, 0, 0, loc)
+	tests := []struct {
+		name      string
+		due       int64
+		cycle     string
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{
+			name:      "monthly before due date",
+			due:       due(2026, time.December, 5),
+			cycle:     "月",
+			wantStart: time.Date(2026, time.July, 5, 0, 0, 0, 0, loc),
+			wantEnd:   time.Date(2026, time.August, 5, 0, 0, 0, 0, loc),
+		},
+		{
+			name:      "yearly past due date",
+			due:       due(2025, time.March, 1),
+			cycle:     "年",
+			wantStart: time.Date(2026, time.March, 1, 0, 0, 0, 0, loc),
+			wantEnd:   time.Date(2027, time.March, 1, 0, 0, 0, 0, loc),
+		},
+		{
+			name:      "month end clamps",
+			due:       due(2026, time.January, 31),
+			cycle:     "半年",
+			wantStart: time.Date(2026, time.July, 31, 0, 0, 0, 0, loc).AddDate(0, -6, 0),
+			wantEnd:   time.Date(2026, time.July, 31, 0, 0, 0, 0, loc),
+		},
+		{
+			name:      "daily",
+			due:       due(2026, time.August, 1),
+			cycle:     "日",
+			wantStart: time.Date(2026, time.July, 20, 0, 0, 0, 0, loc),
+			wantEnd:   time.Date(2026, time.July, 21, 0, 0, 0, 0, loc),
+		},
+		{
+			name:      "unknown cycle uses traffic period",
+			due:       due(2026, time.December, 5),
+			cycle:     "",
+			wantStart: time.Date(2026, time.July, 15, 0, 0, 0, 0, loc),
+			wantEnd:   time.Date(2026, time.August, 15, 0, 0, 0, 0, loc),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := BillingPeriod(now, tt.due, tt.cycle, 15)
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Fatalf("BillingPeriod() = %s - %s, want %s - %s", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Visibility decides who sees a node on the public status page. Public is
+// stored as the empty string.
+const (
+	VisibilityPublic  = "public"
+	VisibilityHidden  = "hidden"
+	VisibilityPrivate = "private"
+)
+
+const MaxPublicNameLength = 64
+
+// NormalizeVisibility checks a visibility value and returns it in the form
+// it is stored in.
+func NormalizeVisibility(value string) (string, error) {
+	switch value = strings.ToLower(strings.TrimSpace(value)); value {
+	case "", VisibilityPublic:
+		return "", nil
+	case VisibilityHidden, VisibilityPrivate:
+		return value, nil
+	}
+	return "", fmt.Errorf("invalid visibility %q", value)
+}
+
+// VisibleTo reports whether the node is listed on the status page. Hidden
+// nodes are only in the admin console; private ones are also shown to a
+// logged-in admin.
+func (info HostInfo) VisibleTo(admin bool) bool {
+	switch info.Visibility {
+	case VisibilityHidden:
+		return false
+	case VisibilityPrivate:
+		return admin
+	}
+	return true
+}
+
+// PublicHostName is the name the status page knows the node by: its public
+// name when one is set, otherwise the node ID, with IP addresses masked when
+// the host is redacted.
+func (info HostInfo) PublicHostName(nodeID string) string {
+	if info.PublicName != "" {
+		return info.PublicName
+	}
+	if info.RedactHost {
+		return RedactAddresses(nodeID)
+	}
+	return nodeID
+}
+
+// PublicDisplayName is the display name the status page may show. A public
+// name replaces it, since the display name is often the real one, and a
+// redacted host has the IP addresses in it masked.
+func (info HostInfo) PublicDisplayName() string {
+	if info.PublicName != "" {
+		return ""
+	}
+	if info.RedactHost {
+		return RedactAddresses(info.DisplayName)
+	}
+	return info.DisplayName
+}
+
+// PublicInfo returns the details of a node as /info serves them: under its
+// public name and without the settings that only concern the admin. The
+// purchase details are left out unless the admin chose to show them.
+func (info HostInfo) PublicInfo() HostInfo {
+	info.Name = info.PublicHostName(info.Name)
+	info.DisplayName = info.PublicDisplayName()
+	info.Visibility, info.RedactHost, info.PublicName, info.AuthSecret = "", false, "", ""
+	if !info.Show {
+		info.Seller, info.Price, info.Cycle, info.Bandwidth, info.Traffic, info.BuyURL, info.DueTime = "", "", "", "", "", "", 0
+	}
+	return info
+}
+
+var addressCandidate = regexp.MustCompile(`[0-9A-Fa-f]*[.:][0-9A-Fa-f.:]*`)
+
+// RedactAddresses replaces every IPv4 or IPv6 address in s with "***".
+func RedactAddresses(s string) string {
+	return addressCandidate.ReplaceAllStringFunc(s, func(candidate string) string {
+		if net.ParseIP(strings.Trim(candidate, ".:")) == nil {
+			return candidate
+		}
+		return "***"
+	})
+}
+
+func normalizePublicName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > MaxPublicNameLength || strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return "", fmt.Errorf("invalid public name %q", name)
+	}
+	return name, nil
+}
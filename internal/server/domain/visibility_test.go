@@ -0,0 +1,82 @@
+package domain
+
+import "testing"
+
+func TestNormalizeVisibility(t *testing.T) {
+	for value, want := range map[string]string{"": "", " Public ": "", "hidden": VisibilityHidden, "PRIVATE": VisibilityPrivate} {
+		if got, err := NormalizeVisibility(value); err != nil || got != want {
+			t.Fatalf("NormalizeVisibility(%q) = %q, %v", value, got, err)
+		}
+	}
+	if _, err := NormalizeVisibility("secret"); err == nil {
+		t.Fatal("expected error for unknown visibility")
+	}
+}
+
+func TestHostInfoVisibleTo(t *testing.T) {
+	for _, tt := range []struct {
+		visibility    string
+		public, admin bool
+	}{
+		{"", true, true},
+		{VisibilityPrivate, false, true},
+		{VisibilityHidden, false, false},
+	} {
+		info := HostInfo{Visibility: tt.visibility}
+		if info.VisibleTo(false) != tt.public || info.VisibleTo(true) != tt.admin {
+			t.Fatalf("visibility %q: public %v admin %v", tt.visibility, info.VisibleTo(false), info.VisibleTo(true))
+		}
+	}
+}
+
+func TestHostInfoPublicHostName(t *testing.T) {
+	if got := (HostInfo{}).PublicHostName("HK-1.2.3.4"); got != "HK-1.2.3.4" {
+		t.Fatalf("plain name = %q", got)
+	}
+	if got := (HostInfo{RedactHost: true}).PublicHostName("HK-1.2.3.4"); got != "HK-***" {
+		t.Fatalf("redacted name = %q", got)
+	}
+	if got := (HostInfo{RedactHost: true, PublicName: "Hong Kong"}).PublicHostName("HK-1.2.3.4"); got != "Hong Kong" {
+		t.Fatalf("public name = %q", got)
+	}
+
+	if got := (HostInfo{DisplayName: "Tokyo 10.0.0.1", RedactHost: true}).PublicDisplayName(); got != "Tokyo ***" {
+		t.Fatalf("redacted display name = %q", got)
+	}
+	if got := (HostInfo{DisplayName: "Tokyo 10.0.0.1", PublicName: "Tokyo"}).PublicDisplayName(); got != "" {
+		t.Fatalf("display name behind public name = %q", got)
+	}
+
+	info := HostInfo{Name: "US-node", DisplayName: "US 10.0.0.1", Visibility: VisibilityPrivate, PublicName: "US", RedactHost: true, AuthSecret: "secret", Seller: "seller", Show: true}.PublicInfo()
+	if info.Name != "US" || info.DisplayName != "" || info.Visibility != "" || info.PublicName != "" || info.RedactHost || info.AuthSecret != "" || info.Seller != "seller" {
+		t.Fatalf("public info = %#v", info)
+	}
+}
+
+func TestHostInfoPublicInfoHidesPurchaseDetails(t *testing.T) {
+	info := HostInfo{Name: "US-node", Seller: "seller", Price: "$5", Cycle: "月", Bandwidth: "1Gbps", Traffic: "1TB", BuyURL: "https://seller.example/buy", DueTime: 1767225600000, TrafficResetDay: 15, Group: "US"}
+	public := info.PublicInfo()
+	if public.Seller != "" || public.Price != "" || public.Cycle != "" || public.Bandwidth != "" || public.Traffic != "" || public.BuyURL != "" || public.DueTime != 0 {
+		t.Fatalf("hidden purchase details served: %#v", public)
+	}
+	if public.Name != "US-node" || public.Group != "US" || public.TrafficResetDay != 15 {
+		t.Fatalf("public info = %#v", public)
+	}
+	info.Show = true
+	if public := info.PublicInfo(); public.Seller != "seller" || public.BuyURL != info.BuyURL || public.DueTime != info.DueTime {
+		t.Fatalf("shown purchase details = %#v", public)
+	}
+}
+
+func TestRedactAddresses(t *testing.T) {
+	for in, want := range map[string]string{
+		"edge-10.0.0.1":         "edge-***",
+		"v6 2001:db8::1 node":   "v6 *** node",
+		"node.example.com v1.2": "node.example.com v1.2",
+		"no address":            "no address",
+	} {
+		if got := RedactAddresses(in); got != want {
+			t.Fatalf("RedactAddresses(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"vps-agent/internal/agent"
+	serverdomain "vps-agent/internal/server/domain"
 )
 
 type Store struct {
@@ -17,13 +18,18 @@ type Store struct {
 	Infos    map[string]HostInfo      `json:"infos"`
 	Planned  map[string]PlannedNode   `json:"planned"`
 	Settings Settings                 `json:"settings"`
+	Probes   []ProbeTarget            `json:"probe_targets,omitempty"`
+	Agent    AgentOverrides           `json:"agent_overrides"`
+	Pages    []Dashboard              `json:"dashboards,omitempty"`
+	Share    string                   `json:"share_secret,omitempty"`
 	Traffic  map[string]TrafficStat   `json:"traffic"`
+	Uptime   map[string]UptimeLog     `json:"uptime,omitempty"`
 
 	lastTrafficSave time.Time `json:"-"`
 }
 
 func NewStore(path string) (*Store, error) {
-	s := &Store{path: path, Reports: map[string]agent.Metrics{}, Infos: map[string]HostInfo{}, Planned: map[string]PlannedNode{}, Settings: Settings{SiteName: "Monitor Party"}, Traffic: map[string]TrafficStat{}}
+	s := &Store{path: path, Reports: map[string]agent.Metrics{}, Infos: map[string]HostInfo{}, Planned: map[string]PlannedNode{}, Settings: Settings{SiteName: "Monitor Party"}, Traffic: map[string]TrafficStat{}, Uptime: map[string]UptimeLog{}}
 	data, err := os.ReadFile(path)
 	if errors.Is(err, os.ErrNotExist) {
 		return s, nil
@@ -48,8 +54,30 @@ func NewStore(path string) (*Store, error) {
 	if s.Traffic == nil {
 		s.Traffic = map[string]TrafficStat{}
 	}
+	if s.Uptime == nil {
+		s.Uptime = map[string]UptimeLog{}
+	}
 	if s.Settings.SiteName == "" {
 		s.Settings.SiteName = "Monitor Party"
 	}
+	if s.assignNodeKeysLocked() {
+		if err := s.saveLocked(); err != nil {
+			return nil, err
+		}
+	}
 	return s, nil
 }
+
+// assignNodeKeysLocked gives planned nodes from files written before nodes
+// had internal IDs one of their own.
+func (s *Store) assignNodeKeysLocked() bool {
+	assigned := false
+	for nodeID, planned := range s.Planned {
+		if planned.ID == "" {
+			planned.ID = serverdomain.NewNodeKey()
+			s.Planned[nodeID] = planned
+			assigned = true
+		}
+	}
+	return assigned
+}
@@ -2,37 +2,54 @@ package server
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
+	serverapp "vps-agent/internal/server/application"
 	serverdomain "vps-agent/internal/server/domain"
 )
 
 func (s *Store) AddPlannedNode(nodeID string, maxNodes int) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, exists := s.Planned[nodeID]; !exists && len(s.Planned) >= maxNodes {
+	current, exists := s.Planned[nodeID]
+	if !exists && len(s.Planned) >= maxNodes {
 		return fmt.Errorf("max nodes reached")
 	}
-	s.Planned[nodeID] = PlannedNode{NodeID: nodeID, CreatedAt: time.Now().Unix()}
+	if current.ID == "" {
+		current.ID = serverdomain.NewNodeKey()
+	}
+	s.Planned[nodeID] = PlannedNode{NodeID: nodeID, ID: current.ID, CreatedAt: time.Now().Unix(), Aliases: current.Aliases}
 	return s.saveLocked()
 }
 
 func (s *Store) SetNodeToken(nodeID, tokenHash string, maxNodes int) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if err := s.setNodeTokenLocked(nodeID, tokenHash, maxNodes); err != nil {
+		return err
+	}
+	return s.saveLocked()
+}
+
+func (s *Store) setNodeTokenLocked(nodeID, tokenHash string, maxNodes int) error {
 	if _, exists := s.Planned[nodeID]; !exists && len(s.Planned) >= maxNodes {
 		return fmt.Errorf("max nodes reached")
 	}
 	planned := s.Planned[nodeID]
 	planned.NodeID = nodeID
+	if planned.ID == "" {
+		planned.ID = serverdomain.NewNodeKey()
+	}
 	if planned.CreatedAt == 0 {
 		planned.CreatedAt = time.Now().Unix()
 	}
 	planned.TokenHash = tokenHash
 	s.Planned[nodeID] = planned
-	return s.saveLocked()
+	return nil
 }
 
 func (s *Store) ValidNodeToken(nodeID, tokenHash string) bool {
@@ -45,14 +62,175 @@ func (s *Store) ValidNodeToken(nodeID, tokenHash string) bool {
 	return constantEqual(planned.TokenHash, tokenHash)
 }
 
+// ResolveNodeID returns the node an agent reporting as nodeID belongs to:
+// nodeID itself when it is planned, otherwise the node it is an alias of.
+func (s *Store) ResolveNodeID(nodeID string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.Planned[nodeID]; ok {
+		return nodeID
+	}
+	for name, planned := range s.Planned {
+		if slices.Contains(planned.Aliases, nodeID) {
+			return name
+		}
+	}
+	return nodeID
+}
+
+// NodeByKey returns the current node ID of the planned node with internal
+// ID key.
+func (s *Store) NodeByKey(key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for name, planned := range s.Planned {
+		if planned.ID == key {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// RenameNode moves everything stored under oldID to newID and keeps oldID
+// as an alias, so the agent can still report until it picks up its new ID.
+func (s *Store) RenameNode(oldID, newID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.nodeExistsLocked(oldID) {
+		return serverdomain.ErrUnknownNode
+	}
+	if s.nodeExistsLocked(newID) {
+		return serverdomain.ErrNodeExists
+	}
+	for name, planned := range s.Planned {
+		if slices.Contains(planned.Aliases, oldID) || slices.Contains(planned.Aliases, newID) {
+			planned.Aliases = slices.DeleteFunc(slices.Clone(planned.Aliases), func(alias string) bool { return alias == oldID || alias == newID })
+			s.Planned[name] = planned
+		}
+	}
+	if planned, ok := s.Planned[oldID]; ok {
+		delete(s.Planned, oldID)
+		planned.NodeID = newID
+		planned.Aliases = serverdomain.AddAlias(planned.Aliases, oldID, newID)
+		s.Planned[newID] = planned
+	}
+	if info, ok := s.Infos[oldID]; ok {
+		delete(s.Infos, oldID)
+		info.Name = newID
+		s.Infos[newID] = info
+	}
+	if report, ok := s.Reports[oldID]; ok {
+		delete(s.Reports, oldID)
+		report.NodeID = newID
+		s.Reports[newID] = report
+	}
+	if stat, ok := s.Traffic[oldID]; ok {
+		delete(s.Traffic, oldID)
+		s.Traffic[newID] = stat
+	}
+	if uptime, ok := s.Uptime[oldID]; ok {
+		delete(s.Uptime, oldID)
+		s.Uptime[newID] = uptime
+	}
+	serverdomain.RenameTargets(s.Probes, oldID, newID)
+	serverdomain.RenameDashboards(s.Pages, oldID, newID)
+	overrides := s.Agent
+	overrides.Nodes = maps.Clone(s.Agent.Nodes)
+	if overrides.RenameOverrides(oldID, newID) {
+		s.Agent = overrides
+	}
+	return s.saveLocked()
+}
+
+func (s *Store) nodeExistsLocked(nodeID string) bool {
+	_, planned := s.Planned[nodeID]
+	_, reported := s.Reports[nodeID]
+	_, described := s.Infos[nodeID]
+	return planned || reported || described
+}
+
 func (s *Store) Delete(name string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.deleteLocked(name)
+	return s.saveLocked()
+}
+
+func (s *Store) deleteLocked(name string) {
 	delete(s.Reports, name)
 	delete(s.Planned, name)
 	delete(s.Infos, name)
 	delete(s.Traffic, name)
-	return s.saveLocked()
+	delete(s.Uptime, name)
+}
+
+// BulkNodes applies ops in order and saves the file once at the end. A failed
+// item does not stop the others. With atomic set, any failure puts the node
+// maps back as they were and nothing is saved. This is best effort: unlike
+// the SQLite store, a failed save leaves the changes in memory, as it does
+// for every other JSON store write.
+func (s *Store) BulkNodes(ops []BulkOp, maxNodes int, atomic bool) ([]BulkResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var restore func()
+	if atomic {
+		planned, infos, reports, traffic, uptime := maps.Clone(s.Planned), maps.Clone(s.Infos), maps.Clone(s.Reports), maps.Clone(s.Traffic), maps.Clone(s.Uptime)
+		restore = func() { s.Planned, s.Infos, s.Reports, s.Traffic, s.Uptime = planned, infos, reports, traffic, uptime }
+	}
+	results := make([]BulkResult, len(ops))
+	failed := false
+	for i, op := range ops {
+		results[i] = BulkResult{Op: op.Op, NodeID: op.NodeID, OK: true}
+		if err := s.bulkOpLocked(op, maxNodes); err != nil {
+			results[i].OK = false
+			results[i].Error = err.Error()
+			failed = true
+		}
+	}
+	if atomic && failed {
+		restore()
+		serverdomain.RollBack(results)
+		return results, nil
+	}
+	return results, s.saveLocked()
+}
+
+func (s *Store) bulkOpLocked(op BulkOp, maxNodes int) error {
+	_, planned := s.Planned[op.NodeID]
+	_, reported := s.Reports[op.NodeID]
+	_, described := s.Infos[op.NodeID]
+	switch op.Op {
+	case serverdomain.BulkAdd:
+		if planned {
+			return nil
+		}
+		if len(s.Planned) >= maxNodes {
+			return fmt.Errorf("max nodes reached")
+		}
+		s.Planned[op.NodeID] = PlannedNode{NodeID: op.NodeID, ID: serverdomain.NewNodeKey(), CreatedAt: time.Now().Unix()}
+	case serverdomain.BulkToken:
+		return s.setNodeTokenLocked(op.NodeID, op.TokenHash, maxNodes)
+	case serverdomain.BulkUpdate:
+		if !planned && !reported && !described {
+			return serverdomain.ErrUnknownNode
+		}
+		info, err := op.Info.Apply(s.Infos[op.NodeID])
+		if err != nil {
+			return err
+		}
+		info.Name = op.NodeID
+		info.AuthSecret = ""
+		s.Infos[op.NodeID] = info
+		s.syncTrafficResetDayLocked(op.NodeID, info.TrafficResetDay)
+	case serverdomain.BulkDelete:
+		if !planned && !reported && !described {
+			return serverdomain.ErrUnknownNode
+		}
+		s.deleteLocked(op.NodeID)
+	default:
+		return fmt.Errorf("unknown op %q", op.Op)
+	}
+	return nil
 }
 
 func (s *Store) AdminNodes(offlineWait time.Duration) []AdminNode {
@@ -66,11 +244,15 @@ func (s *Store) AdminNodes(offlineWait time.Duration) []AdminNode {
 		report, hasReport := s.Reports[name]
 		lastSeen := int64(0)
 		online := false
+		var failed, health, agentFlags []string
 		if hasReport {
 			lastSeen = report.Timestamp
 			online = report.Timestamp > 0 && now-report.Timestamp <= threshold
+			failed = serverapp.FailedServices(report)
+			health = serverapp.HealthFlags(report)
+			agentFlags = serverapp.AgentFlags(report)
 		}
-		out = append(out, AdminNode{NodeID: name, Online: online, LastSeen: lastSeen, CreatedAt: planned.CreatedAt, Info: s.Infos[name]})
+		out = append(out, AdminNode{NodeID: name, ID: planned.ID, Aliases: planned.Aliases, Online: online, LastSeen: lastSeen, CreatedAt: planned.CreatedAt, Info: s.Infos[name], FailedServices: failed, Health: health, AgentVersion: report.AgentVersion, AgentFlags: agentFlags})
 		seen[name] = true
 	}
 	for name, report := range s.Reports {
@@ -78,7 +260,7 @@ func (s *Store) AdminNodes(offlineWait time.Duration) []AdminNode {
 			continue
 		}
 		online := report.Timestamp > 0 && now-report.Timestamp <= threshold
-		out = append(out, AdminNode{NodeID: name, Online: online, LastSeen: report.Timestamp, Info: s.Infos[name]})
+		out = append(out, AdminNode{NodeID: name, Online: online, LastSeen: report.Timestamp, Info: s.Infos[name], FailedServices: serverapp.FailedServices(report), Health: serverapp.HealthFlags(report), AgentVersion: report.AgentVersion, AgentFlags: serverapp.AgentFlags(report)})
 	}
 	sort.Slice(out, func(i, j int) bool { return out[i].NodeID < out[j].NodeID })
 	return out
@@ -103,7 +285,7 @@ func (s *Store) ExportNodes() NodeBackup {
 		info := s.Infos[name]
 		info.AuthSecret = ""
 		info.TrafficResetDay = serverdomain.NormalizeTrafficResetDay(info.TrafficResetDay)
-		out.Nodes = append(out.Nodes, NodeBackupRecord{NodeID: name, CreatedAt: planned.CreatedAt, TokenHash: planned.TokenHash, Info: info})
+		out.Nodes = append(out.Nodes, NodeBackupRecord{NodeID: name, ID: planned.ID, CreatedAt: planned.CreatedAt, TokenHash: planned.TokenHash, Info: info})
 	}
 	sort.Slice(out.Nodes, func(i, j int) bool { return out.Nodes[i].NodeID < out.Nodes[j].NodeID })
 	return out
@@ -133,6 +315,9 @@ func (s *Store) ImportNodes(backup NodeBackup, maxNodes int) (int, error) {
 		}
 		planned := s.Planned[nodeID]
 		planned.NodeID = nodeID
+		if planned.ID == "" {
+			planned.ID = s.unusedNodeKeyLocked(strings.TrimSpace(record.ID))
+		}
 		if record.CreatedAt > 0 {
 			planned.CreatedAt = record.CreatedAt
 		} else if planned.CreatedAt == 0 {
@@ -157,3 +342,17 @@ func (s *Store) ImportNodes(backup NodeBackup, maxNodes int) (int, error) {
 	}
 	return imported, nil
 }
+
+// unusedNodeKeyLocked returns id when no planned node has it yet, and a new
+// internal ID otherwise.
+func (s *Store) unusedNodeKeyLocked(id string) string {
+	if id == "" {
+		return serverdomain.NewNodeKey()
+	}
+	for _, planned := range s.Planned {
+		if planned.ID == id {
+			return serverdomain.NewNodeKey()
+		}
+	}
+	return id
+}
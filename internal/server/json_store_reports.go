@@ -2,6 +2,7 @@ package server
 
 import (
 	"fmt"
+	"slices"
 	"sort"
 	"time"
 
@@ -20,9 +21,45 @@ func (s *Store) UpsertReport(metrics agent.Metrics, maxNodes int) error {
 	if _, ok := s.Planned[metrics.NodeID]; !ok {
 		s.Planned[metrics.NodeID] = PlannedNode{NodeID: metrics.NodeID, CreatedAt: time.Now().Unix()}
 	}
+	if _, ok := s.Uptime[metrics.NodeID]; !ok {
+		// Save now rather than with the next traffic save, so the start of
+		// tracking survives a restart.
+		s.Uptime[metrics.NodeID] = UptimeLog{Since: metrics.Timestamp}
+		s.lastTrafficSave = time.Time{}
+	}
 	return s.updateTrafficLocked(metrics)
 }
 
+// RecordOutage adds a gap between two of the node's reports to its uptime
+// log.
+func (s *Store) RecordOutage(nodeID string, outage Outage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	uptime := s.Uptime[nodeID]
+	uptime.Add(outage)
+	s.Uptime[nodeID] = uptime
+	return s.saveLocked()
+}
+
+// UptimeLogs returns every node's uptime log by node ID.
+func (s *Store) UptimeLogs() map[string]UptimeLog {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[string]UptimeLog, len(s.Uptime))
+	for nodeID, uptime := range s.Uptime {
+		uptime.Outages = slices.Clone(uptime.Outages)
+		out[nodeID] = uptime
+	}
+	return out
+}
+
+func (s *Store) Report(nodeID string) (agent.Metrics, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	metrics, ok := s.Reports[nodeID]
+	return metrics, ok
+}
+
 func (s *Store) UpsertInfo(info HostInfo) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -44,18 +81,31 @@ func (s *Store) InfoList() []HostInfo {
 	return out
 }
 
-func (s *Store) AkileHosts() []AkileHost {
+// Info returns the admin-set details of one node.
+func (s *Store) Info(nodeID string) (HostInfo, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	info, ok := s.Infos[nodeID]
+	return info, ok
+}
+
+// AkileHosts returns the status page view of the nodes filter shows.
+func (s *Store) AkileHosts(filter serverdomain.HostFilter) []AkileHost {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	out := make([]AkileHost, 0, len(s.Planned)+len(s.Reports))
 	for _, m := range s.Reports {
-		out = append(out, serverapp.ToAkileHost(m, s.Traffic[m.NodeID]))
+		if info := s.Infos[m.NodeID]; filter.Shows(m.NodeID, info) {
+			out = append(out, serverapp.ToAkileHost(m, s.Traffic[m.NodeID], info))
+		}
 	}
 	for name := range s.Planned {
 		if _, ok := s.Reports[name]; ok {
 			continue
 		}
-		out = append(out, serverapp.OfflineAkileHost(name))
+		if info := s.Infos[name]; filter.Shows(name, info) {
+			out = append(out, serverapp.OfflineAkileHost(name, info))
+		}
 	}
 	sort.Slice(out, func(i, j int) bool { return out[i].Host.Name < out[j].Host.Name })
 	return out
@@ -1,5 +1,7 @@
 package server
 
+import serverdomain "vps-agent/internal/server/domain"
+
 func (s *Store) SiteName() string {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -25,3 +27,70 @@ func (s *Store) UpdateSettings(settings Settings) error {
 	s.Settings = settings
 	return s.saveLocked()
 }
+
+func (s *Store) ProbeTargets() []ProbeTarget {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]ProbeTarget(nil), s.Probes...)
+}
+
+func (s *Store) SetProbeTargets(targets []ProbeTarget) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Probes = append([]ProbeTarget(nil), targets...)
+	return s.saveLocked()
+}
+
+func (s *Store) AgentOverrides() AgentOverrides {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Agent
+}
+
+func (s *Store) SetAgentOverrides(overrides AgentOverrides) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Agent = overrides
+	return s.saveLocked()
+}
+
+func (s *Store) Dashboards() []Dashboard {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Dashboard(nil), s.Pages...)
+}
+
+func (s *Store) SetDashboards(dashboards []Dashboard) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Pages = append([]Dashboard(nil), dashboards...)
+	return s.saveLocked()
+}
+
+// ShareSecret returns the key share links are signed with, creating it the
+// first time a link is made.
+func (s *Store) ShareSecret() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Share != "" {
+		return s.Share, nil
+	}
+	s.Share = serverdomain.NewShareSecret()
+	return s.Share, s.saveLocked()
+}
+
+// SavedShareSecret returns the share key without creating one; there is
+// none until the first link is made.
+func (s *Store) SavedShareSecret() (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Share, s.Share != ""
+}
+
+// ResetShareSecret replaces the share key, which revokes every link.
+func (s *Store) ResetShareSecret() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Share = serverdomain.NewShareSecret()
+	return s.saveLocked()
+}
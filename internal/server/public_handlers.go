@@ -3,10 +3,13 @@ package server
 import (
 	"compress/gzip"
 	"fmt"
+	"io/fs"
 	"mime"
 	"net/http"
 	"path/filepath"
 	"strings"
+
+	"vps-agent/internal/release"
 )
 
 func (s *Server) handleAdminPage(w http.ResponseWriter, r *http.Request) {
@@ -71,14 +74,20 @@ func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "not found", http.StatusNotFound)
 		return
 	}
-	data, err := agentBinaries.ReadFile("agent_bins/" + name)
+	data, err := fs.ReadFile(agentBinaries, "agent_bins/"+name)
 	if err != nil {
 		http.Error(w, "not found", http.StatusNotFound)
 		return
 	}
-	w.Header().Set("Content-Type", "application/octet-stream")
+	contentType := "application/octet-stream"
+	if name == release.ManifestName {
+		contentType = "application/json"
+	}
+	w.Header().Set("Content-Type", contentType)
 	w.Header().Set("Content-Disposition", "attachment; filename=\""+name+"\"")
-	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	// File names stay the same across releases and must match the manifest
+	// of the running server, so caches have to revalidate.
+	w.Header().Set("Cache-Control", "no-cache")
 	_, _ = w.Write(data)
 }
 
@@ -128,6 +137,12 @@ func (s *Server) handleStatic(w http.ResponseWriter, r *http.Request) {
 	if path == "" {
 		path = "index.html"
 	}
+	serveStaticFile(w, r, path)
+}
+
+// serveStaticFile writes a file of the built frontend, or index.html for a
+// path the frontend routes itself.
+func serveStaticFile(w http.ResponseWriter, r *http.Request, path string) {
 	data, err := staticFiles.ReadFile("web/dist/" + path)
 	if err != nil {
 		data, err = staticFiles.ReadFile("web/dist/index.html")
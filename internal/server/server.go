@@ -4,12 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"vps-agent/internal/agent"
 	serverapp "vps-agent/internal/server/application"
+	serverdomain "vps-agent/internal/server/domain"
 )
 
 type Server struct {
@@ -18,6 +20,9 @@ type Server struct {
 	http     *http.Server
 	sessions *SessionStore
 	cache    *ResponseCache
+	// started is when this process came up. Gaps in agent reports before it
+	// are the server's own downtime, not node outages.
+	started time.Time
 }
 
 func New(cfg Config) (*Server, error) {
@@ -30,17 +35,29 @@ func New(cfg Config) (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
-	s := &Server{cfg: cfg, store: store, sessions: NewSessionStore(), cache: NewResponseCache()}
+	s := &Server{cfg: cfg, store: store, sessions: NewSessionStore(), cache: NewResponseCache(), started: time.Now()}
 	mux := http.NewServeMux()
 	mux.HandleFunc("/api/agent/ping", s.handleAgentPing)
 	mux.HandleFunc("/api/agent/report", s.handleAgentReport)
+	mux.HandleFunc("/api/agent/config", s.handleAgentConfig)
 	mux.HandleFunc("/api/admin/login", s.handleAdminLogin)
 	mux.HandleFunc("/api/admin/logout", s.handleAdminLogout)
 	mux.HandleFunc("/api/admin/me", s.handleAdminMe)
 	mux.HandleFunc("/api/admin/settings", s.handleAdminSettings)
+	mux.HandleFunc("/api/admin/probes", s.handleAdminProbes)
+	mux.HandleFunc("/api/admin/dashboards", s.handleAdminDashboards)
+	mux.HandleFunc("/api/admin/agent-config", s.handleAdminAgentConfig)
+	mux.HandleFunc("/api/admin/node", s.handleAdminNode)
 	mux.HandleFunc("/api/admin/nodes", s.handleAdminNodes)
+	mux.HandleFunc("/api/admin/fleet", s.handleAdminFleet)
+	mux.HandleFunc("/api/admin/uptime", s.handleAdminUptime)
 	mux.HandleFunc("/api/admin/nodes/export", s.handleAdminNodesExport)
 	mux.HandleFunc("/api/admin/nodes/import", s.handleAdminNodesImport)
+	mux.HandleFunc("/api/admin/nodes/tags", s.handleAdminNodeTags)
+	mux.HandleFunc("/api/admin/nodes/bulk", s.handleAdminNodesBulk)
+	mux.HandleFunc("/api/admin/nodes/rename", s.handleAdminNodeRename)
+	mux.HandleFunc("/api/admin/nodes/share", s.handleAdminNodeShare)
+	mux.HandleFunc("/api/admin/nodes/share/reset", s.handleAdminNodeShareReset)
 	mux.HandleFunc("/api/admin/install-command", s.handleAdminInstallCommand)
 	mux.HandleFunc("/install/agent-linux.sh", s.handleAgentLinuxInstaller)
 	mux.HandleFunc("/install/agent-windows.ps1", s.handleAgentWindowsInstaller)
@@ -54,6 +71,8 @@ func New(cfg Config) (*Server, error) {
 	mux.HandleFunc("/info", s.handleInfo)
 	mux.HandleFunc("/delete", s.handleDelete)
 	mux.HandleFunc("/api/nodes", s.handleNodes)
+	mux.HandleFunc("/d/", s.handleDashboard)
+	mux.HandleFunc("/s/", s.handleShare)
 	mux.HandleFunc("/", s.handleStatic)
 	s.http = &http.Server{
 		Addr:           cfg.Addr,
@@ -75,7 +94,7 @@ func (s *Server) handleAgentPing(w http.ResponseWriter, r *http.Request) {
 		methodNotAllowed(w)
 		return
 	}
-	if !s.agentAuthorized(r) {
+	if _, ok := s.agentNode(r); !ok {
 		http.Error(w, "missing agent identity", http.StatusUnauthorized)
 		return
 	}
@@ -87,7 +106,8 @@ func (s *Server) handleAgentReport(w http.ResponseWriter, r *http.Request) {
 		methodNotAllowed(w)
 		return
 	}
-	if !s.agentAuthorized(r) {
+	nodeID, ok := s.agentNode(r)
+	if !ok {
 		http.Error(w, "missing agent identity", http.StatusUnauthorized)
 		return
 	}
@@ -97,18 +117,71 @@ func (s *Server) handleAgentReport(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	metrics.NodeID = strings.TrimSpace(r.Header.Get("X-Node-ID"))
-	if !validNodeID(metrics.NodeID) {
-		http.Error(w, "invalid node_id", http.StatusBadRequest)
-		return
-	}
+	metrics.NodeID = nodeID
 	metrics.Timestamp = time.Now().Unix()
+	prev, hasPrev := s.store.Report(metrics.NodeID)
 	if err := s.store.UpsertReport(metrics, s.cfg.MaxNodes); err != nil {
 		http.Error(w, err.Error(), http.StatusTooManyRequests)
 		return
 	}
+	if hasPrev {
+		for _, change := range serverapp.ServiceTransitions(prev, metrics) {
+			log.Printf("node %s service %s: %s -> %s", metrics.NodeID, change.Name, change.From, change.To)
+		}
+		start := max(prev.Timestamp, s.started.Unix())
+		if prev.Timestamp > 0 && metrics.Timestamp-start > int64(s.cfg.OfflineWait.Seconds()) {
+			outage := serverdomain.Outage{Start: start, End: metrics.Timestamp}
+			if err := s.store.RecordOutage(metrics.NodeID, outage); err != nil {
+				log.Printf("node %s outage record failed: %v", metrics.NodeID, err)
+			}
+		}
+	}
 	s.cache.MarkDirty()
-	writeJSON(w, map[string]string{"ok": "true"})
+	writeJSON(w, agent.ReportResponse{OK: "true", Update: agentUpdate(metrics)})
+}
+
+// handleAgentConfig serves the node's effective settings. The version doubles
+// as the ETag so an agent polling with If-None-Match gets a 304 until an admin
+// changes something that applies to it.
+func (s *Server) handleAgentConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+	nodeID, ok := s.agentNode(r)
+	if !ok {
+		http.Error(w, "missing agent identity", http.StatusUnauthorized)
+		return
+	}
+	cfg := serverapp.AgentConfigFor(nodeID, s.hostInfo(nodeID), s.store.ProbeTargets(), s.store.AgentOverrides())
+	etag := `"` + cfg.Version + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeJSON(w, cfg)
+}
+
+// publicInfos lists the purchase details of the nodes filter shows, under
+// their public names.
+func (s *Server) publicInfos(filter serverdomain.HostFilter) []HostInfo {
+	infos := s.store.InfoList()
+	out := make([]HostInfo, 0, len(infos))
+	for _, info := range infos {
+		if filter.Shows(info.Name, info) {
+			out = append(out, info.PublicInfo())
+		}
+	}
+	return out
+}
+
+// hostInfo returns the admin-set details of a node, or a zero HostInfo for a
+// node that has none.
+func (s *Server) hostInfo(nodeID string) HostInfo {
+	info, _ := s.store.Info(nodeID)
+	return info
 }
 
 func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
@@ -128,7 +201,7 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		writeJSON(w, s.store.InfoList())
+		writeJSON(w, s.publicInfos(s.rootView(r).filter))
 	case http.MethodPost:
 		if !s.adminAuthorized(r) {
 			http.Error(w, "admin login required", http.StatusUnauthorized)
@@ -148,10 +221,15 @@ func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "invalid node_id", http.StatusBadRequest)
 			return
 		}
+		if err := req.Normalize(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if err := s.store.UpsertInfo(req); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		s.cache.MarkDirty()
 		writeJSON(w, map[string]string{"ok": "true"})
 	default:
 		methodNotAllowed(w)
@@ -195,39 +273,137 @@ func (s *Server) handleNodes(w http.ResponseWriter, r *http.Request) {
 		methodNotAllowed(w)
 		return
 	}
-	s.writeCachedHosts(w)
+	tag, err := serverdomain.NormalizeTag(r.URL.Query().Get("tag"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(s.hostsJSON(s.rootView(r), tag))
 }
 
 func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
+	s.streamHosts(w, r, func() (hostView, bool) { return s.rootView(r), true })
+}
+
+// streamHosts serves a status page WebSocket. view is asked again before
+// every update, so private nodes disappear from the stream once the admin
+// logs out, and the stream ends when the viewer loses access to the page.
+func (s *Server) streamHosts(w http.ResponseWriter, r *http.Request, view func() (hostView, bool)) {
 	if r.Method != http.MethodGet {
 		methodNotAllowed(w)
 		return
 	}
-	conn, rw, err := upgradeWebSocket(w, r)
+	tag, err := serverdomain.NormalizeTag(r.URL.Query().Get("tag"))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer conn.Close()
-	if err := writeWSBytes(rw, s.cachedHostsJSON()); err != nil {
+	conn, rw, err := upgradeWebSocket(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	defer conn.Close()
 	for {
+		current, ok := view()
+		if !ok {
+			return
+		}
+		if err := writeWSBytes(rw, s.hostsJSON(current, tag)); err != nil {
+			return
+		}
 		_ = conn.SetReadDeadline(time.Now().Add(90 * time.Second))
-		_, err := readWS(conn)
-		if err != nil {
+		if _, err := readWS(conn); err != nil {
 			return
 		}
 		_ = conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-		if err := writeWSBytes(rw, s.cachedHostsJSON()); err != nil {
+	}
+}
+
+// statusPage is a status page mounted under a path prefix, such as a
+// dashboard at /d/{slug}. view is asked again on every request and stream
+// update, and reports false once the visitor may no longer see the page.
+type statusPage struct {
+	base     string
+	siteName string
+	theme    string
+	view     func() (hostView, bool)
+}
+
+// serveStatusPage serves rest, the path below page.base: the page itself and
+// its own config.json, WebSocket, node list and /info.
+func (s *Server) serveStatusPage(w http.ResponseWriter, r *http.Request, rest string, page statusPage) {
+	switch rest {
+	case "":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w)
+			return
+		}
+		serveStaticFile(w, r, "index.html")
+		return
+	case "ws":
+		s.streamHosts(w, r, page.view)
+		return
+	case "config.json", "api/nodes", "info":
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+	view, ok := page.view()
+	if !ok {
+		http.Error(w, "access denied", http.StatusUnauthorized)
+		return
+	}
+	switch rest {
+	case "config.json":
+		base := s.requestBase(r) + page.base
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, map[string]string{
+			"socket":      socketURL(base),
+			"apiURL":      base,
+			"siteName":    page.siteName,
+			"theme":       page.theme,
+			"offlineWait": fmt.Sprintf("%.0f", s.cfg.OfflineWait.Seconds()),
+		})
+	case "api/nodes":
+		tag, err := serverdomain.NormalizeTag(r.URL.Query().Get("tag"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write(s.hostsJSON(view, tag))
+	case "info":
+		writeJSON(w, s.publicInfos(view.filter))
 	}
 }
 
-func (s *Server) cachedHostsJSON() []byte {
-	return s.cache.Get(func() []byte {
-		data, err := json.Marshal(s.store.AkileHosts())
+// hostView is the selection of nodes one status page lists and the cache key
+// its host list is kept under.
+type hostView struct {
+	key    string
+	filter serverdomain.HostFilter
+}
+
+// rootView is the status page at /: every public node, and the private ones
+// for a logged-in admin.
+func (s *Server) rootView(r *http.Request) hostView {
+	if s.adminViewer(r) {
+		return hostView{key: "hosts-private", filter: serverdomain.HostFilter{Private: true}}
+	}
+	return hostView{key: "hosts"}
+}
+
+func (s *Server) cachedHostsJSON(view hostView) []byte {
+	return s.cache.Get(view.key, func() []byte {
+		data, err := json.Marshal(s.store.AkileHosts(view.filter))
 		if err != nil {
 			return []byte("[]")
 		}
@@ -235,10 +411,44 @@ func (s *Server) cachedHostsJSON() []byte {
 	})
 }
 
-func (s *Server) writeCachedHosts(w http.ResponseWriter) {
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Cache-Control", "no-store")
-	_, _ = w.Write(s.cachedHostsJSON())
+// hostsJSON returns the cached host list of view, or the part of it whose
+// group or tags include tag. The filtered list is cached too, under the view
+// key and the tag, and built from the cached bytes so a tagged view costs no
+// extra store reads.
+func (s *Server) hostsJSON(view hostView, tag string) []byte {
+	data := s.cachedHostsJSON(view)
+	if tag == "" {
+		return data
+	}
+	return s.cache.Get(view.key+"?tag="+tag, func() []byte { return filterHostsJSON(data, tag) })
+}
+
+// filterHostsJSON keeps the hosts in data whose group or tags include tag.
+func filterHostsJSON(data []byte, tag string) []byte {
+	var hosts []json.RawMessage
+	if err := json.Unmarshal(data, &hosts); err != nil {
+		return []byte("[]")
+	}
+	out := make([]json.RawMessage, 0, len(hosts))
+	for _, raw := range hosts {
+		var host struct {
+			Host struct {
+				Group string
+				Tags  []string
+			}
+		}
+		if err := json.Unmarshal(raw, &host); err != nil {
+			continue
+		}
+		if (HostInfo{Group: host.Host.Group, Tags: host.Host.Tags}).HasLabel(tag) {
+			out = append(out, raw)
+		}
+	}
+	filtered, err := json.Marshal(out)
+	if err != nil {
+		return []byte("[]")
+	}
+	return filtered
 }
 
 func (s *Server) requestBase(r *http.Request) string {
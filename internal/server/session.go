@@ -1,8 +1,12 @@
 package server
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -10,10 +14,15 @@ import (
 type SessionStore struct {
 	mu       sync.Mutex
 	sessions map[string]time.Time
+	key      []byte
 }
 
 func NewSessionStore() *SessionStore {
-	return &SessionStore{sessions: map[string]time.Time{}}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return &SessionStore{sessions: map[string]time.Time{}, key: key}
 }
 
 func (s *SessionStore) Create() (string, error) {
@@ -47,3 +56,30 @@ func (s *SessionStore) Delete(token string) {
 	defer s.mu.Unlock()
 	delete(s.sessions, token)
 }
+
+// Grant returns a token that stays valid for scope until it expires. Grants
+// are signed rather than stored, so a visitor who enters a dashboard password
+// costs no memory; they lapse when the server restarts, like sessions do.
+func (s *SessionStore) Grant(scope string, ttl time.Duration) string {
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	return expires + "." + s.sign(scope, expires)
+}
+
+// ValidGrant reports whether token was granted for scope and has not expired.
+func (s *SessionStore) ValidGrant(token, scope string) bool {
+	expires, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(s.sign(scope, expires)))
+}
+
+func (s *SessionStore) sign(scope, expires string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(scope + "\n" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -38,6 +38,28 @@ func TestSessionStoreValidExpiresAndRemovesSession(t *testing.T) {
 	}
 }
 
+func TestSessionStoreGrantIsScopedAndExpires(t *testing.T) {
+	store := NewSessionStore()
+	grant := store.Grant("dashboard\na", time.Hour)
+	if !store.ValidGrant(grant, "dashboard\na") {
+		t.Fatal("grant should be valid for its scope")
+	}
+	if store.ValidGrant(grant, "dashboard\nb") {
+		t.Fatal("grant should not be valid for another scope")
+	}
+	if NewSessionStore().ValidGrant(grant, "dashboard\na") {
+		t.Fatal("grant should not be valid for another server key")
+	}
+	if store.ValidGrant(store.Grant("dashboard\na", -time.Second), "dashboard\na") {
+		t.Fatal("expired grant should be invalid")
+	}
+	for _, bad := range []string{"", "garbage", "9999999999.sig"} {
+		if store.ValidGrant(bad, "dashboard\na") {
+			t.Fatalf("ValidGrant(%q) = true", bad)
+		}
+	}
+}
+
 func TestSessionStoreConcurrentCreateAndValidate(t *testing.T) {
 	store := NewSessionStore()
 	var wg sync.WaitGroup
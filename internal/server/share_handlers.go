@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	serverdomain "vps-agent/internal/server/domain"
+)
+
+// handleShare serves the read-only page of a share link at /s/{token}. The
+// token is checked on every request and before every stream update, so the
+// page stops updating as soon as the link expires or is revoked.
+func (s *Server) handleShare(w http.ResponseWriter, r *http.Request) {
+	token, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/s/"), "/")
+	nodeID, ok := s.sharedNode(token)
+	if !ok {
+		http.Error(w, "share link is invalid or expired", http.StatusUnauthorized)
+		return
+	}
+	page := statusPage{
+		base:     "/s/" + token,
+		siteName: s.hostInfo(nodeID).PublicHostName(nodeID),
+		view:     func() (hostView, bool) { return s.shareView(token) },
+	}
+	s.serveStatusPage(w, r, rest, page)
+}
+
+// sharedNode returns the node a share token was signed for, under its
+// current node ID. It only reads the share key: no link is valid before the
+// admin made the first one, which is what creates the key.
+func (s *Server) sharedNode(token string) (string, bool) {
+	secret, ok := s.store.SavedShareSecret()
+	if !ok {
+		return "", false
+	}
+	key, _, ok := serverdomain.ParseShare(secret, token, time.Now())
+	if !ok {
+		return "", false
+	}
+	return s.store.NodeByKey(key)
+}
+
+// shareView lists the shared node alone, whatever its visibility: the admin
+// chose to show it to whoever holds the link.
+func (s *Server) shareView(token string) (hostView, bool) {
+	nodeID, ok := s.sharedNode(token)
+	if !ok {
+		return hostView{}, false
+	}
+	return hostView{key: "share/" + nodeID, filter: serverdomain.HostFilter{Hidden: true, Nodes: []string{nodeID}}}, true
+}
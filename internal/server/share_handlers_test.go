@@ -0,0 +1,124 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	serverdomain "vps-agent/internal/server/domain"
+)
+
+func TestShareLinkShowsOneNodeUntilRevoked(t *testing.T) {
+	s := newTestServer(t)
+	for _, nodeID := range []string{"US-node-001", "JP-node-001"} {
+		if err := s.store.AddPlannedNode(nodeID, 10); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.store.UpsertReport(sampleMetrics(nodeID, 100, 200), 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.store.UpsertInfo(HostInfo{Name: "JP-node-001", Visibility: serverdomain.VisibilityHidden, PublicName: "Tokyo", Seller: "seller", Show: true}); err != nil {
+		t.Fatal(err)
+	}
+	token, err := s.sessions.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := httptest.NewRecorder()
+	s.handleShare(before, httptest.NewRequest(http.MethodGet, "/s/garbage/api/nodes", nil))
+	if before.Code != http.StatusUnauthorized {
+		t.Fatalf("share before any link: status = %d", before.Code)
+	}
+	if _, ok := s.store.SavedShareSecret(); ok {
+		t.Fatal("a public share request created the share key")
+	}
+
+	share := func(body string) *httptest.ResponseRecorder {
+		t.Helper()
+		resp := httptest.NewRecorder()
+		s.handleAdminNodeShare(resp, adminRequestWithBody(http.MethodPost, "/api/admin/nodes/share", token, body))
+		return resp
+	}
+	if resp := share(`{"node_id":"JP-node-001","hours":-1}`); resp.Code != http.StatusBadRequest {
+		t.Fatalf("negative hours: status = %d", resp.Code)
+	}
+	if resp := share(`{"node_id":"JP-node-001","hours":9000}`); resp.Code != http.StatusBadRequest {
+		t.Fatalf("too many hours: status = %d", resp.Code)
+	}
+	if resp := share(`{"node_id":"ghost"}`); resp.Code != http.StatusNotFound {
+		t.Fatalf("unknown node: status = %d", resp.Code)
+	}
+	resp := httptest.NewRecorder()
+	s.handleAdminNodeShare(resp, adminRequestWithBody(http.MethodPost, "/api/admin/nodes/share", "", `{"node_id":"JP-node-001"}`))
+	if resp.Code != http.StatusUnauthorized {
+		t.Fatalf("share without login: status = %d", resp.Code)
+	}
+	var link struct {
+		URL       string `json:"url"`
+		ExpiresAt int64  `json:"expires_at"`
+	}
+	decodeJSONResponse(t, share(`{"node_id":"JP-node-001"}`), &link)
+	if !strings.HasPrefix(link.URL, "http://monitor.example.com/s/") || link.ExpiresAt < time.Now().Add(serverdomain.DefaultShareTTL-time.Minute).Unix() {
+		t.Fatalf("share link = %#v", link)
+	}
+	path := strings.TrimPrefix(link.URL, "http://monitor.example.com")
+
+	get := func(target string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Host = "monitor.example.com"
+		resp := httptest.NewRecorder()
+		s.handleShare(resp, req)
+		return resp
+	}
+	var hosts []AkileHost
+	decodeJSONResponse(t, get(path+"/api/nodes"), &hosts)
+	if len(hosts) != 1 || hosts[0].Host.Name != "Tokyo" {
+		t.Fatalf("shared hosts = %#v", hosts)
+	}
+	var infos []HostInfo
+	decodeJSONResponse(t, get(path+"/info"), &infos)
+	if len(infos) != 1 || infos[0].Name != "Tokyo" || infos[0].Seller != "seller" {
+		t.Fatalf("shared infos = %#v", infos)
+	}
+	var cfg map[string]string
+	decodeJSONResponse(t, get(path+"/config.json"), &cfg)
+	if cfg["siteName"] != "Tokyo" || cfg["socket"] != "ws://monitor.example.com"+path+"/ws" {
+		t.Fatalf("shared config = %#v", cfg)
+	}
+	if resp := get(path); resp.Code != http.StatusOK {
+		t.Fatalf("share page: status = %d", resp.Code)
+	}
+
+	secret, err := s.store.ShareSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _, _ := serverdomain.ParseShare(secret, strings.TrimPrefix(path, "/s/"), time.Now())
+	expired := "/s/" + serverdomain.SignShare(secret, key, time.Now().Add(-time.Minute))
+	for _, target := range []string{expired + "/api/nodes", "/s/garbage/api/nodes", "/s/"} {
+		if resp := get(target); resp.Code != http.StatusUnauthorized {
+			t.Fatalf("%s: status = %d", target, resp.Code)
+		}
+	}
+
+	if err := s.store.RenameNode("JP-node-001", "JP-node-002"); err != nil {
+		t.Fatal(err)
+	}
+	s.cache.MarkDirty()
+	if resp := get(path + "/api/nodes"); resp.Code != http.StatusOK {
+		t.Fatalf("share after rename: status = %d", resp.Code)
+	}
+
+	resp = httptest.NewRecorder()
+	s.handleAdminNodeShareReset(resp, adminRequestWithBody(http.MethodPost, "/api/admin/nodes/share/reset", token, ""))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("reset: status = %d", resp.Code)
+	}
+	if resp := get(path + "/api/nodes"); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("share after reset: status = %d", resp.Code)
+	}
+}
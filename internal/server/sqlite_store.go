@@ -2,7 +2,9 @@ package server
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -118,6 +120,62 @@ func (s *SQLiteStore) initSchema() error {
 			return err
 		}
 	}
+	return s.migrate()
+}
+
+// migrations upgrade databases created by earlier versions, in order. Each
+// runs in its own transaction and is recorded in schema_migrations.
+var migrations = []struct {
+	version    int
+	statements []string
+}{
+	// Internal node IDs and the aliases left behind by renames.
+	{2, []string{
+		`ALTER TABLE planned_nodes ADD COLUMN id TEXT NOT NULL DEFAULT ''`,
+		`UPDATE planned_nodes SET id = lower(hex(randomblob(8))) WHERE id = ''`,
+		`CREATE TABLE node_aliases (
+			alias TEXT PRIMARY KEY,
+			node_id TEXT NOT NULL,
+			position INTEGER NOT NULL
+		)`,
+		`CREATE INDEX node_aliases_node_id ON node_aliases(node_id)`,
+	}},
+	// Per-node uptime logs for availability reports.
+	{3, []string{
+		`CREATE TABLE uptime_logs (
+			node_id TEXT PRIMARY KEY,
+			log_json TEXT NOT NULL
+		)`,
+	}},
+}
+
+func (s *SQLiteStore) migrate() error {
+	var version int
+	if err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return err
+	}
+	for _, migration := range migrations {
+		if migration.version <= version {
+			continue
+		}
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		for _, query := range migration.statements {
+			if _, err := tx.Exec(query); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("schema migration %d failed: %w", migration.version, err)
+			}
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations(version, applied_at) VALUES (?, strftime('%s', 'now'))`, migration.version); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -159,6 +217,9 @@ func jsonStoreHasData(store *Store) bool {
 		len(store.Infos) > 0 ||
 		len(store.Planned) > 0 ||
 		len(store.Traffic) > 0 ||
+		len(store.Probes) > 0 ||
+		len(store.Pages) > 0 ||
+		len(store.Agent.Global) > 0 || len(store.Agent.Nodes) > 0 ||
 		store.Settings.SiteName != "" && store.Settings.SiteName != "Monitor Party"
 }
 
@@ -171,10 +232,45 @@ func (s *SQLiteStore) importJSONStore(store *Store) error {
 	if err := upsertSettingTx(tx, "site_name", store.SiteName()); err != nil {
 		return err
 	}
+	if len(store.Probes) > 0 {
+		data, err := json.Marshal(store.Probes)
+		if err != nil {
+			return err
+		}
+		if err := upsertSettingTx(tx, "probe_targets", string(data)); err != nil {
+			return err
+		}
+	}
+	if store.Share != "" {
+		if err := upsertSettingTx(tx, "share_secret", store.Share); err != nil {
+			return err
+		}
+	}
+	if len(store.Pages) > 0 {
+		data, err := json.Marshal(store.Pages)
+		if err != nil {
+			return err
+		}
+		if err := upsertSettingTx(tx, "dashboards", string(data)); err != nil {
+			return err
+		}
+	}
+	if len(store.Agent.Global) > 0 || len(store.Agent.Nodes) > 0 {
+		data, err := json.Marshal(store.Agent)
+		if err != nil {
+			return err
+		}
+		if err := upsertSettingTx(tx, "agent_overrides", string(data)); err != nil {
+			return err
+		}
+	}
 	for _, planned := range store.Planned {
 		if err := upsertPlannedTx(tx, planned); err != nil {
 			return err
 		}
+		if err := setAliasesTx(tx, planned.NodeID, planned.Aliases); err != nil {
+			return err
+		}
 	}
 	for _, info := range store.Infos {
 		info.AuthSecret = ""
@@ -193,6 +289,11 @@ func (s *SQLiteStore) importJSONStore(store *Store) error {
 			return err
 		}
 	}
+	for nodeID, uptime := range store.Uptime {
+		if err := upsertUptimeTx(tx, nodeID, uptime); err != nil {
+			return err
+		}
+	}
 	return tx.Commit()
 }
 
@@ -2,6 +2,7 @@ package server
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -9,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	serverapp "vps-agent/internal/server/application"
 	serverdomain "vps-agent/internal/server/domain"
 )
 
@@ -47,24 +49,7 @@ func (s *SQLiteStore) SetNodeToken(nodeID, tokenHash string, maxNodes int) error
 		return err
 	}
 	defer tx.Rollback()
-	exists, err := plannedExistsTx(tx, nodeID)
-	if err != nil {
-		return err
-	}
-	if !exists {
-		count, err := countRowsTx(tx, "planned_nodes")
-		if err != nil {
-			return err
-		}
-		if count >= maxNodes {
-			return fmt.Errorf("max nodes reached")
-		}
-	}
-	if _, err := tx.Exec(`
-		INSERT INTO planned_nodes(node_id, created_at, token_hash)
-		VALUES (?, ?, ?)
-		ON CONFLICT(node_id) DO UPDATE SET token_hash = excluded.token_hash
-	`, nodeID, time.Now().Unix(), tokenHash); err != nil {
+	if err := setNodeTokenTx(tx, nodeID, tokenHash, maxNodes); err != nil {
 		return err
 	}
 	return tx.Commit()
@@ -83,7 +68,43 @@ func (s *SQLiteStore) ValidNodeToken(nodeID, tokenHash string) bool {
 	return constantEqual(stored, tokenHash)
 }
 
-func (s *SQLiteStore) Delete(name string) error {
+// ResolveNodeID returns the node an agent reporting as nodeID belongs to:
+// nodeID itself when it is planned, otherwise the node it is an alias of.
+func (s *SQLiteStore) ResolveNodeID(nodeID string) string {
+	var resolved string
+	err := s.db.QueryRow(`
+		SELECT COALESCE(
+			(SELECT node_id FROM planned_nodes WHERE node_id = ?),
+			(SELECT node_id FROM node_aliases WHERE alias = ?),
+			?
+		)
+	`, nodeID, nodeID, nodeID).Scan(&resolved)
+	if err != nil {
+		log.Printf("sqlite alias read failed: %v", err)
+		return nodeID
+	}
+	return resolved
+}
+
+// NodeByKey returns the current node ID of the planned node with internal
+// ID key.
+func (s *SQLiteStore) NodeByKey(key string) (string, bool) {
+	var nodeID string
+	err := s.db.QueryRow(`SELECT node_id FROM planned_nodes WHERE id = ?`, key).Scan(&nodeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false
+	}
+	if err != nil {
+		log.Printf("sqlite planned read failed: %v", err)
+		return "", false
+	}
+	return nodeID, true
+}
+
+// RenameNode moves every row stored under oldID to newID in one transaction
+// and keeps oldID as an alias, so the agent can still report until it picks
+// up its new ID.
+func (s *SQLiteStore) RenameNode(oldID, newID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	tx, err := s.db.Begin()
@@ -91,19 +112,222 @@ func (s *SQLiteStore) Delete(name string) error {
 		return err
 	}
 	defer tx.Rollback()
+	exists, err := nodeExistsTx(tx, oldID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return serverdomain.ErrUnknownNode
+	}
+	if exists, err = nodeExistsTx(tx, newID); err != nil {
+		return err
+	}
+	if exists {
+		return serverdomain.ErrNodeExists
+	}
 	for _, query := range []string{
-		`DELETE FROM reports WHERE node_id = ?`,
-		`DELETE FROM planned_nodes WHERE node_id = ?`,
-		`DELETE FROM host_infos WHERE node_id = ?`,
-		`DELETE FROM traffic_stats WHERE node_id = ?`,
+		`UPDATE planned_nodes SET node_id = ? WHERE node_id = ?`,
+		`UPDATE host_infos SET node_id = ? WHERE node_id = ?`,
+		`UPDATE reports SET node_id = ? WHERE node_id = ?`,
+		`UPDATE traffic_stats SET node_id = ? WHERE node_id = ?`,
+		`UPDATE uptime_logs SET node_id = ? WHERE node_id = ?`,
+		`UPDATE node_aliases SET node_id = ? WHERE node_id = ?`,
 	} {
-		if _, err := tx.Exec(query, name); err != nil {
+		if _, err := tx.Exec(query, newID, oldID); err != nil {
 			return err
 		}
 	}
+	if info, ok, err := getInfoTx(tx, newID); err != nil {
+		return err
+	} else if ok {
+		info.Name = newID
+		if err := upsertInfoTx(tx, info); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM node_aliases WHERE alias IN (?, ?)`, oldID, newID); err != nil {
+		return err
+	}
+	if planned, err := plannedExistsTx(tx, newID); err != nil {
+		return err
+	} else if planned {
+		aliases, err := aliasesTx(tx, newID)
+		if err != nil {
+			return err
+		}
+		if err := setAliasesTx(tx, newID, serverdomain.AddAlias(aliases, oldID, newID)); err != nil {
+			return err
+		}
+	}
+	if err := renameNodeSettingsTx(tx, oldID, newID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// renameNodeSettingsTx updates the probe targets, dashboards and agent
+// overrides that name oldID.
+func renameNodeSettingsTx(tx *sql.Tx, oldID, newID string) error {
+	if raw, ok, err := getSettingTx(tx, "dashboards"); err != nil {
+		return err
+	} else if ok {
+		var dashboards []Dashboard
+		if err := json.Unmarshal([]byte(raw), &dashboards); err != nil {
+			return err
+		}
+		if serverdomain.RenameDashboards(dashboards, oldID, newID) {
+			data, err := json.Marshal(dashboards)
+			if err != nil {
+				return err
+			}
+			if err := upsertSettingTx(tx, "dashboards", string(data)); err != nil {
+				return err
+			}
+		}
+	}
+	if raw, ok, err := getSettingTx(tx, "probe_targets"); err != nil {
+		return err
+	} else if ok {
+		var targets []ProbeTarget
+		if err := json.Unmarshal([]byte(raw), &targets); err != nil {
+			return err
+		}
+		if serverdomain.RenameTargets(targets, oldID, newID) {
+			data, err := json.Marshal(targets)
+			if err != nil {
+				return err
+			}
+			if err := upsertSettingTx(tx, "probe_targets", string(data)); err != nil {
+				return err
+			}
+		}
+	}
+	raw, ok, err := getSettingTx(tx, "agent_overrides")
+	if err != nil || !ok {
+		return err
+	}
+	var overrides AgentOverrides
+	if err := json.Unmarshal([]byte(raw), &overrides); err != nil {
+		return err
+	}
+	if !overrides.RenameOverrides(oldID, newID) {
+		return nil
+	}
+	data, err := json.Marshal(overrides)
+	if err != nil {
+		return err
+	}
+	return upsertSettingTx(tx, "agent_overrides", string(data))
+}
+
+func (s *SQLiteStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := deleteNodeTx(tx, name); err != nil {
+		return err
+	}
 	return tx.Commit()
 }
 
+// BulkNodes applies ops in one transaction. Each item runs under its own
+// savepoint, so a failed item is undone on its own and the rest commit
+// together; with atomic set, any failure rolls back the whole batch.
+func (s *SQLiteStore) BulkNodes(ops []BulkOp, maxNodes int, atomic bool) ([]BulkResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	results := make([]BulkResult, len(ops))
+	failed := false
+	for i, op := range ops {
+		results[i] = BulkResult{Op: op.Op, NodeID: op.NodeID, OK: true}
+		if _, err := tx.Exec(`SAVEPOINT bulk_op`); err != nil {
+			return nil, err
+		}
+		if err := bulkOpTx(tx, op, maxNodes); err != nil {
+			if _, rollbackErr := tx.Exec(`ROLLBACK TO bulk_op`); rollbackErr != nil {
+				return nil, rollbackErr
+			}
+			results[i].OK = false
+			results[i].Error = err.Error()
+			failed = true
+		}
+		if _, err := tx.Exec(`RELEASE bulk_op`); err != nil {
+			return nil, err
+		}
+	}
+	if atomic && failed {
+		serverdomain.RollBack(results)
+		return results, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func bulkOpTx(tx *sql.Tx, op BulkOp, maxNodes int) error {
+	switch op.Op {
+	case serverdomain.BulkAdd:
+		exists, err := plannedExistsTx(tx, op.NodeID)
+		if err != nil || exists {
+			return err
+		}
+		count, err := countRowsTx(tx, "planned_nodes")
+		if err != nil {
+			return err
+		}
+		if count >= maxNodes {
+			return fmt.Errorf("max nodes reached")
+		}
+		return insertPlannedIfMissingTx(tx, op.NodeID, time.Now().Unix())
+	case serverdomain.BulkToken:
+		return setNodeTokenTx(tx, op.NodeID, op.TokenHash, maxNodes)
+	case serverdomain.BulkUpdate:
+		current, exists, err := getInfoTx(tx, op.NodeID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			if exists, err = nodeExistsTx(tx, op.NodeID); err != nil {
+				return err
+			}
+		}
+		if !exists {
+			return serverdomain.ErrUnknownNode
+		}
+		info, err := op.Info.Apply(current)
+		if err != nil {
+			return err
+		}
+		info.Name = op.NodeID
+		info.AuthSecret = ""
+		if err := upsertInfoTx(tx, info); err != nil {
+			return err
+		}
+		return syncTrafficResetDayTx(tx, op.NodeID, info.TrafficResetDay, time.Now())
+	case serverdomain.BulkDelete:
+		exists, err := nodeExistsTx(tx, op.NodeID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return serverdomain.ErrUnknownNode
+		}
+		return deleteNodeTx(tx, op.NodeID)
+	default:
+		return fmt.Errorf("unknown op %q", op.Op)
+	}
+}
+
 func (s *SQLiteStore) AdminNodes(offlineWait time.Duration) []AdminNode {
 	planned, err := s.loadPlanned()
 	if err != nil {
@@ -128,11 +352,15 @@ func (s *SQLiteStore) AdminNodes(offlineWait time.Duration) []AdminNode {
 		report, hasReport := reports[name]
 		lastSeen := int64(0)
 		online := false
+		var failed, health, agentFlags []string
 		if hasReport {
 			lastSeen = report.Timestamp
 			online = report.Timestamp > 0 && now-report.Timestamp <= threshold
+			failed = serverapp.FailedServices(report)
+			health = serverapp.HealthFlags(report)
+			agentFlags = serverapp.AgentFlags(report)
 		}
-		out = append(out, AdminNode{NodeID: name, Online: online, LastSeen: lastSeen, CreatedAt: plannedNode.CreatedAt, Info: infos[name]})
+		out = append(out, AdminNode{NodeID: name, ID: plannedNode.ID, Aliases: plannedNode.Aliases, Online: online, LastSeen: lastSeen, CreatedAt: plannedNode.CreatedAt, Info: infos[name], FailedServices: failed, Health: health, AgentVersion: report.AgentVersion, AgentFlags: agentFlags})
 		seen[name] = true
 	}
 	for name, report := range reports {
@@ -140,7 +368,7 @@ func (s *SQLiteStore) AdminNodes(offlineWait time.Duration) []AdminNode {
 			continue
 		}
 		online := report.Timestamp > 0 && now-report.Timestamp <= threshold
-		out = append(out, AdminNode{NodeID: name, Online: online, LastSeen: report.Timestamp, Info: infos[name]})
+		out = append(out, AdminNode{NodeID: name, Online: online, LastSeen: report.Timestamp, Info: infos[name], FailedServices: serverapp.FailedServices(report), Health: serverapp.HealthFlags(report), AgentVersion: report.AgentVersion, AgentFlags: serverapp.AgentFlags(report)})
 	}
 	sort.Slice(out, func(i, j int) bool { return out[i].NodeID < out[j].NodeID })
 	return out
@@ -177,7 +405,7 @@ func (s *SQLiteStore) ExportNodes() NodeBackup {
 		info := infos[name]
 		info.AuthSecret = ""
 		info.TrafficResetDay = serverdomain.NormalizeTrafficResetDay(info.TrafficResetDay)
-		out.Nodes = append(out.Nodes, NodeBackupRecord{NodeID: name, CreatedAt: planned[name].CreatedAt, TokenHash: planned[name].TokenHash, Info: info})
+		out.Nodes = append(out.Nodes, NodeBackupRecord{NodeID: name, ID: planned[name].ID, CreatedAt: planned[name].CreatedAt, TokenHash: planned[name].TokenHash, Info: info})
 	}
 	sort.Slice(out.Nodes, func(i, j int) bool { return out.Nodes[i].NodeID < out.Nodes[j].NodeID })
 	return out
@@ -222,6 +450,15 @@ func (s *SQLiteStore) ImportNodes(backup NodeBackup, maxNodes int) (int, error)
 			plannedCount++
 		}
 		planned.NodeID = nodeID
+		if id := strings.TrimSpace(record.ID); !exists && id != "" {
+			used, err := plannedKeyUsedTx(tx, id)
+			if err != nil {
+				return imported, err
+			}
+			if !used {
+				planned.ID = id
+			}
+		}
 		if record.CreatedAt > 0 {
 			planned.CreatedAt = record.CreatedAt
 		} else if planned.CreatedAt == 0 {
@@ -252,7 +489,7 @@ func (s *SQLiteStore) ImportNodes(backup NodeBackup, maxNodes int) (int, error)
 }
 
 func (s *SQLiteStore) loadPlanned() (map[string]PlannedNode, error) {
-	rows, err := s.db.Query(`SELECT node_id, created_at, token_hash FROM planned_nodes`)
+	rows, err := s.db.Query(`SELECT node_id, id, created_at, token_hash FROM planned_nodes`)
 	if err != nil {
 		return nil, err
 	}
@@ -260,10 +497,28 @@ func (s *SQLiteStore) loadPlanned() (map[string]PlannedNode, error) {
 	out := map[string]PlannedNode{}
 	for rows.Next() {
 		var planned PlannedNode
-		if err := rows.Scan(&planned.NodeID, &planned.CreatedAt, &planned.TokenHash); err != nil {
+		if err := rows.Scan(&planned.NodeID, &planned.ID, &planned.CreatedAt, &planned.TokenHash); err != nil {
 			return nil, err
 		}
 		out[planned.NodeID] = planned
 	}
-	return out, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	aliases, err := s.db.Query(`SELECT alias, node_id FROM node_aliases ORDER BY node_id, position`)
+	if err != nil {
+		return nil, err
+	}
+	defer aliases.Close()
+	for aliases.Next() {
+		var alias, nodeID string
+		if err := aliases.Scan(&alias, &nodeID); err != nil {
+			return nil, err
+		}
+		if planned, ok := out[nodeID]; ok {
+			planned.Aliases = append(planned.Aliases, alias)
+			out[nodeID] = planned
+		}
+	}
+	return out, aliases.Err()
 }
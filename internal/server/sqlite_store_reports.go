@@ -1,7 +1,9 @@
 package server
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"sort"
@@ -39,12 +41,84 @@ func (s *SQLiteStore) UpsertReport(metrics agent.Metrics, maxNodes int) error {
 	if err := insertPlannedIfMissingTx(tx, metrics.NodeID, time.Now().Unix()); err != nil {
 		return err
 	}
+	if err := startUptimeTx(tx, metrics.NodeID, metrics.Timestamp); err != nil {
+		return err
+	}
 	if err := updateTrafficTx(tx, metrics, time.Now()); err != nil {
 		return err
 	}
 	return tx.Commit()
 }
 
+// RecordOutage adds a gap between two of the node's reports to its uptime
+// log.
+func (s *SQLiteStore) RecordOutage(nodeID string, outage Outage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	uptime, err := getUptimeTx(tx, nodeID)
+	if err != nil {
+		return err
+	}
+	uptime.Add(outage)
+	if err := upsertUptimeTx(tx, nodeID, uptime); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UptimeLogs returns every node's uptime log by node ID.
+func (s *SQLiteStore) UptimeLogs() map[string]UptimeLog {
+	rows, err := s.db.Query(`SELECT node_id, log_json FROM uptime_logs`)
+	if err != nil {
+		log.Printf("sqlite uptime read failed: %v", err)
+		return nil
+	}
+	defer rows.Close()
+	out := map[string]UptimeLog{}
+	for rows.Next() {
+		var nodeID, payload string
+		if err := rows.Scan(&nodeID, &payload); err != nil {
+			log.Printf("sqlite uptime read failed: %v", err)
+			return nil
+		}
+		var uptime UptimeLog
+		if err := json.Unmarshal([]byte(payload), &uptime); err != nil {
+			log.Printf("sqlite uptime decode failed: %v", err)
+			return nil
+		}
+		out[nodeID] = uptime
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("sqlite uptime read failed: %v", err)
+		return nil
+	}
+	return out
+}
+
+func (s *SQLiteStore) Report(nodeID string) (agent.Metrics, bool) {
+	var payload string
+	err := s.db.QueryRow(`SELECT metrics_json FROM reports WHERE node_id = ?`, nodeID).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return agent.Metrics{}, false
+	}
+	if err != nil {
+		log.Printf("sqlite report read failed: %v", err)
+		return agent.Metrics{}, false
+	}
+	var metrics agent.Metrics
+	if err := json.Unmarshal([]byte(payload), &metrics); err != nil {
+		log.Printf("sqlite report decode failed: %v", err)
+		return agent.Metrics{}, false
+	}
+	metrics.NodeID = nodeID
+	return metrics, true
+}
+
 func (s *SQLiteStore) UpsertInfo(info HostInfo) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -78,7 +152,30 @@ func (s *SQLiteStore) InfoList() []HostInfo {
 	return out
 }
 
-func (s *SQLiteStore) AkileHosts() []AkileHost {
+// Info returns the admin-set details of one node.
+func (s *SQLiteStore) Info(nodeID string) (HostInfo, bool) {
+	var payload string
+	err := s.db.QueryRow(`SELECT info_json FROM host_infos WHERE node_id = ?`, nodeID).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return HostInfo{}, false
+	}
+	if err != nil {
+		log.Printf("sqlite info read failed: %v", err)
+		return HostInfo{}, false
+	}
+	var info HostInfo
+	if err := json.Unmarshal([]byte(payload), &info); err != nil {
+		log.Printf("sqlite info decode failed: %v", err)
+		return HostInfo{}, false
+	}
+	info.Name = nodeID
+	info.AuthSecret = ""
+	info.TrafficResetDay = serverdomain.NormalizeTrafficResetDay(info.TrafficResetDay)
+	return info, true
+}
+
+// AkileHosts returns the status page view of the nodes filter shows.
+func (s *SQLiteStore) AkileHosts(filter serverdomain.HostFilter) []AkileHost {
 	reports, err := s.loadReports()
 	if err != nil {
 		log.Printf("sqlite reports read failed: %v", err)
@@ -94,15 +191,24 @@ func (s *SQLiteStore) AkileHosts() []AkileHost {
 		log.Printf("sqlite traffic read failed: %v", err)
 		return nil
 	}
+	infos, err := s.loadInfos()
+	if err != nil {
+		log.Printf("sqlite infos read failed: %v", err)
+		return nil
+	}
 	out := make([]AkileHost, 0, len(planned)+len(reports))
 	for _, metrics := range reports {
-		out = append(out, serverapp.ToAkileHost(metrics, traffic[metrics.NodeID]))
+		if info := infos[metrics.NodeID]; filter.Shows(metrics.NodeID, info) {
+			out = append(out, serverapp.ToAkileHost(metrics, traffic[metrics.NodeID], info))
+		}
 	}
 	for name := range planned {
 		if _, ok := reports[name]; ok {
 			continue
 		}
-		out = append(out, serverapp.OfflineAkileHost(name))
+		if info := infos[name]; filter.Shows(name, info) {
+			out = append(out, serverapp.OfflineAkileHost(name, info))
+		}
 	}
 	sort.Slice(out, func(i, j int) bool { return out[i].Host.Name < out[j].Host.Name })
 	return out
@@ -2,8 +2,11 @@ package server
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"log"
+
+	serverdomain "vps-agent/internal/server/domain"
 )
 
 func (s *SQLiteStore) SiteName() string {
@@ -30,3 +33,126 @@ func (s *SQLiteStore) UpdateSettings(settings Settings) error {
 	_, err := s.db.Exec(`INSERT OR REPLACE INTO settings(key, value) VALUES ('site_name', ?)`, settings.SiteName)
 	return err
 }
+
+func (s *SQLiteStore) ProbeTargets() []ProbeTarget {
+	var raw string
+	err := s.db.QueryRow(`SELECT value FROM settings WHERE key = 'probe_targets'`).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		log.Printf("sqlite probe targets read failed: %v", err)
+		return nil
+	}
+	var targets []ProbeTarget
+	if err := json.Unmarshal([]byte(raw), &targets); err != nil {
+		log.Printf("sqlite probe targets decode failed: %v", err)
+		return nil
+	}
+	return targets
+}
+
+func (s *SQLiteStore) SetProbeTargets(targets []ProbeTarget) error {
+	data, err := json.Marshal(targets)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO settings(key, value) VALUES ('probe_targets', ?)`, string(data))
+	return err
+}
+
+func (s *SQLiteStore) AgentOverrides() AgentOverrides {
+	var raw string
+	err := s.db.QueryRow(`SELECT value FROM settings WHERE key = 'agent_overrides'`).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return AgentOverrides{}
+	}
+	if err != nil {
+		log.Printf("sqlite agent overrides read failed: %v", err)
+		return AgentOverrides{}
+	}
+	var overrides AgentOverrides
+	if err := json.Unmarshal([]byte(raw), &overrides); err != nil {
+		log.Printf("sqlite agent overrides decode failed: %v", err)
+		return AgentOverrides{}
+	}
+	return overrides
+}
+
+func (s *SQLiteStore) SetAgentOverrides(overrides AgentOverrides) error {
+	data, err := json.Marshal(overrides)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO settings(key, value) VALUES ('agent_overrides', ?)`, string(data))
+	return err
+}
+
+func (s *SQLiteStore) Dashboards() []Dashboard {
+	var raw string
+	err := s.db.QueryRow(`SELECT value FROM settings WHERE key = 'dashboards'`).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		log.Printf("sqlite dashboards read failed: %v", err)
+		return nil
+	}
+	var dashboards []Dashboard
+	if err := json.Unmarshal([]byte(raw), &dashboards); err != nil {
+		log.Printf("sqlite dashboards decode failed: %v", err)
+		return nil
+	}
+	return dashboards
+}
+
+func (s *SQLiteStore) SetDashboards(dashboards []Dashboard) error {
+	data, err := json.Marshal(dashboards)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO settings(key, value) VALUES ('dashboards', ?)`, string(data))
+	return err
+}
+
+// ShareSecret returns the key share links are signed with, creating it the
+// first time a link is made.
+func (s *SQLiteStore) ShareSecret() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	secret, ok, err := getSettingTx(tx, "share_secret")
+	if err != nil || ok {
+		return secret, err
+	}
+	secret = serverdomain.NewShareSecret()
+	if err := upsertSettingTx(tx, "share_secret", secret); err != nil {
+		return "", err
+	}
+	return secret, tx.Commit()
+}
+
+// SavedShareSecret returns the share key without creating one; there is
+// none until the first link is made.
+func (s *SQLiteStore) SavedShareSecret() (string, bool) {
+	var secret string
+	err := s.db.QueryRow(`SELECT value FROM settings WHERE key = 'share_secret'`).Scan(&secret)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false
+	}
+	if err != nil {
+		log.Printf("sqlite share secret read failed: %v", err)
+		return "", false
+	}
+	return secret, secret != ""
+}
+
+// ResetShareSecret replaces the share key, which revokes every link.
+func (s *SQLiteStore) ResetShareSecret() error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO settings(key, value) VALUES ('share_secret', ?)`, serverdomain.NewShareSecret())
+	return err
+}
@@ -17,19 +17,28 @@ func upsertSettingTx(tx *sql.Tx, key, value string) error {
 	return err
 }
 
+// upsertPlannedTx writes a planned node. An existing row keeps its internal
+// ID; a new one gets planned.ID, or a fresh ID when that is empty.
 func upsertPlannedTx(tx *sql.Tx, planned PlannedNode) error {
-	_, err := tx.Exec(`INSERT OR REPLACE INTO planned_nodes(node_id, created_at, token_hash) VALUES (?, ?, ?)`, planned.NodeID, planned.CreatedAt, planned.TokenHash)
+	if planned.ID == "" {
+		planned.ID = serverdomain.NewNodeKey()
+	}
+	_, err := tx.Exec(`
+		INSERT INTO planned_nodes(node_id, id, created_at, token_hash)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(node_id) DO UPDATE SET created_at = excluded.created_at, token_hash = excluded.token_hash
+	`, planned.NodeID, planned.ID, planned.CreatedAt, planned.TokenHash)
 	return err
 }
 
 func insertPlannedIfMissingTx(tx *sql.Tx, nodeID string, createdAt int64) error {
-	_, err := tx.Exec(`INSERT OR IGNORE INTO planned_nodes(node_id, created_at, token_hash) VALUES (?, ?, '')`, nodeID, createdAt)
+	_, err := tx.Exec(`INSERT OR IGNORE INTO planned_nodes(node_id, id, created_at, token_hash) VALUES (?, ?, ?, '')`, nodeID, serverdomain.NewNodeKey(), createdAt)
 	return err
 }
 
 func getPlannedTx(tx *sql.Tx, nodeID string) (PlannedNode, bool, error) {
 	var planned PlannedNode
-	err := tx.QueryRow(`SELECT node_id, created_at, token_hash FROM planned_nodes WHERE node_id = ?`, nodeID).Scan(&planned.NodeID, &planned.CreatedAt, &planned.TokenHash)
+	err := tx.QueryRow(`SELECT node_id, id, created_at, token_hash FROM planned_nodes WHERE node_id = ?`, nodeID).Scan(&planned.NodeID, &planned.ID, &planned.CreatedAt, &planned.TokenHash)
 	if errors.Is(err, sql.ErrNoRows) {
 		return PlannedNode{}, false, nil
 	}
@@ -44,6 +53,124 @@ func plannedExistsTx(tx *sql.Tx, nodeID string) (bool, error) {
 	return exists, err
 }
 
+func setNodeTokenTx(tx *sql.Tx, nodeID, tokenHash string, maxNodes int) error {
+	exists, err := plannedExistsTx(tx, nodeID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		count, err := countRowsTx(tx, "planned_nodes")
+		if err != nil {
+			return err
+		}
+		if count >= maxNodes {
+			return fmt.Errorf("max nodes reached")
+		}
+	}
+	_, err = tx.Exec(`
+		INSERT INTO planned_nodes(node_id, id, created_at, token_hash)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(node_id) DO UPDATE SET token_hash = excluded.token_hash
+	`, nodeID, serverdomain.NewNodeKey(), time.Now().Unix(), tokenHash)
+	return err
+}
+
+func plannedKeyUsedTx(tx *sql.Tx, id string) (bool, error) {
+	var exists int
+	err := tx.QueryRow(`SELECT 1 FROM planned_nodes WHERE id = ?`, id).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func aliasesTx(tx *sql.Tx, nodeID string) ([]string, error) {
+	rows, err := tx.Query(`SELECT alias FROM node_aliases WHERE node_id = ? ORDER BY position`, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var alias string
+		if err := rows.Scan(&alias); err != nil {
+			return nil, err
+		}
+		out = append(out, alias)
+	}
+	return out, rows.Err()
+}
+
+// setAliasesTx replaces the aliases of nodeID, taking over any of them that
+// another node had.
+func setAliasesTx(tx *sql.Tx, nodeID string, aliases []string) error {
+	if _, err := tx.Exec(`DELETE FROM node_aliases WHERE node_id = ?`, nodeID); err != nil {
+		return err
+	}
+	for i, alias := range aliases {
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO node_aliases(alias, node_id, position) VALUES (?, ?, ?)`, alias, nodeID, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getSettingTx(tx *sql.Tx, key string) (string, bool, error) {
+	var value string
+	err := tx.QueryRow(`SELECT value FROM settings WHERE key = ?`, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	return value, err == nil, err
+}
+
+// nodeExistsTx reports whether any table has a row for nodeID.
+func nodeExistsTx(tx *sql.Tx, nodeID string) (bool, error) {
+	var exists int
+	err := tx.QueryRow(`
+		SELECT 1 FROM planned_nodes WHERE node_id = ?
+		UNION ALL SELECT 1 FROM reports WHERE node_id = ?
+		UNION ALL SELECT 1 FROM host_infos WHERE node_id = ?
+		LIMIT 1
+	`, nodeID, nodeID, nodeID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func deleteNodeTx(tx *sql.Tx, nodeID string) error {
+	for _, query := range []string{
+		`DELETE FROM reports WHERE node_id = ?`,
+		`DELETE FROM planned_nodes WHERE node_id = ?`,
+		`DELETE FROM host_infos WHERE node_id = ?`,
+		`DELETE FROM traffic_stats WHERE node_id = ?`,
+		`DELETE FROM uptime_logs WHERE node_id = ?`,
+		`DELETE FROM node_aliases WHERE node_id = ?`,
+	} {
+		if _, err := tx.Exec(query, nodeID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getInfoTx(tx *sql.Tx, nodeID string) (HostInfo, bool, error) {
+	var payload string
+	err := tx.QueryRow(`SELECT info_json FROM host_infos WHERE node_id = ?`, nodeID).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return HostInfo{}, false, nil
+	}
+	if err != nil {
+		return HostInfo{}, false, err
+	}
+	var info HostInfo
+	if err := json.Unmarshal([]byte(payload), &info); err != nil {
+		return HostInfo{}, false, err
+	}
+	return info, true, nil
+}
+
 func upsertInfoTx(tx *sql.Tx, info HostInfo) error {
 	payload, err := json.Marshal(info)
 	if err != nil {
@@ -62,6 +189,39 @@ func upsertReportTx(tx *sql.Tx, metrics agent.Metrics) error {
 	return err
 }
 
+// startUptimeTx starts the node's uptime log at its first report.
+func startUptimeTx(tx *sql.Tx, nodeID string, since int64) error {
+	payload, err := json.Marshal(UptimeLog{Since: since})
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT OR IGNORE INTO uptime_logs(node_id, log_json) VALUES (?, ?)`, nodeID, string(payload))
+	return err
+}
+
+func getUptimeTx(tx *sql.Tx, nodeID string) (UptimeLog, error) {
+	var payload string
+	err := tx.QueryRow(`SELECT log_json FROM uptime_logs WHERE node_id = ?`, nodeID).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return UptimeLog{}, nil
+	}
+	if err != nil {
+		return UptimeLog{}, err
+	}
+	var uptime UptimeLog
+	err = json.Unmarshal([]byte(payload), &uptime)
+	return uptime, err
+}
+
+func upsertUptimeTx(tx *sql.Tx, nodeID string, uptime UptimeLog) error {
+	payload, err := json.Marshal(uptime)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT OR REPLACE INTO uptime_logs(node_id, log_json) VALUES (?, ?)`, nodeID, string(payload))
+	return err
+}
+
 func reportExistsTx(tx *sql.Tx, nodeID string) (bool, error) {
 	var exists int
 	err := tx.QueryRow(`SELECT 1 FROM reports WHERE node_id = ?`, nodeID).Scan(&exists)
@@ -131,18 +291,10 @@ func syncTrafficResetDayTx(tx *sql.Tx, nodeID string, resetDay int, now time.Tim
 }
 
 func trafficResetDayTx(tx *sql.Tx, nodeID string) (int, error) {
-	var payload string
-	err := tx.QueryRow(`SELECT info_json FROM host_infos WHERE node_id = ?`, nodeID).Scan(&payload)
-	if errors.Is(err, sql.ErrNoRows) {
-		return 1, nil
-	}
+	info, _, err := getInfoTx(tx, nodeID)
 	if err != nil {
 		return 1, err
 	}
-	var info HostInfo
-	if err := json.Unmarshal([]byte(payload), &info); err != nil {
-		return 1, err
-	}
 	return serverdomain.NormalizeTrafficResetDay(info.TrafficResetDay), nil
 }
 
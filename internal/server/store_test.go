@@ -2,16 +2,20 @@ package server
 
 import (
 	"compress/gzip"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"vps-agent/internal/agent"
+	serverdomain "vps-agent/internal/server/domain"
 )
 
 func TestNormalizeConfigDefaultsAndOrigins(t *testing.T) {
@@ -869,7 +873,7 @@ func TestAgentAuthorizationRequiresBearerToken(t *testing.T) {
 				req.Header.Set("X-Node-ID", tt.nodeID)
 			}
 			req.Header.Set("Authorization", tt.auth)
-			if got := s.agentAuthorized(req); got != tt.authorized {
+			if _, got := s.agentNode(req); got != tt.authorized {
 				t.Fatalf("authorized = %v, want %v", got, tt.authorized)
 			}
 		})
@@ -905,11 +909,13 @@ func TestAgentReportNormalizesAuthorizedNodeID(t *testing.T) {
 	}
 }
 
-func TestStoreBackendsNodeLifecycle(t *testing.T) {
-	tests := []struct {
-		name    string
-		factory func(t *testing.T) dataStore
-	}{
+type storeBackend struct {
+	name    string
+	factory func(t *testing.T) dataStore
+}
+
+func storeBackends() []storeBackend {
+	return []storeBackend{
 		{
 			name: "json",
 			factory: func(t *testing.T) dataStore {
@@ -933,8 +939,10 @@ func TestStoreBackendsNodeLifecycle(t *testing.T) {
 			},
 		},
 	}
+}
 
-	for _, tt := range tests {
+func TestStoreBackendsNodeLifecycle(t *testing.T) {
+	for _, tt := range storeBackends() {
 		t.Run(tt.name, func(t *testing.T) {
 			store := tt.factory(t)
 			const nodeID = "CN-test-001"
@@ -946,6 +954,20 @@ func TestStoreBackendsNodeLifecycle(t *testing.T) {
 			if got := store.SiteName(); got != "Ops Monitor" {
 				t.Fatalf("site name = %q", got)
 			}
+			targets := []ProbeTarget{{Name: "CT", Type: "icmp", Target: "202.96.209.133", WarnMs: 300, Nodes: []string{nodeID}}}
+			if err := store.SetProbeTargets(targets); err != nil {
+				t.Fatal(err)
+			}
+			if got := store.ProbeTargets(); !reflect.DeepEqual(got, targets) {
+				t.Fatalf("probe targets = %#v", got)
+			}
+			overrides := AgentOverrides{Global: map[string]string{"BASIC_INTERVAL": "5s"}, Nodes: map[string]map[string]string{nodeID: {"MOUNTS": "/"}}}
+			if err := store.SetAgentOverrides(overrides); err != nil {
+				t.Fatal(err)
+			}
+			if got := store.AgentOverrides(); !reflect.DeepEqual(got, overrides) {
+				t.Fatalf("agent overrides = %#v", got)
+			}
 			if err := store.AddPlannedNode(nodeID, 10); err != nil {
 				t.Fatal(err)
 			}
@@ -961,17 +983,33 @@ func TestStoreBackendsNodeLifecycle(t *testing.T) {
 			if err := store.SetNodeToken("CN-over-limit", "hash", 1); err == nil {
 				t.Fatal("expected max nodes error from SetNodeToken")
 			}
-			if err := store.UpsertInfo(HostInfo{Name: nodeID, Seller: "seller", Price: "$5", AuthSecret: "drop-me", TrafficResetDay: 31, Show: true}); err != nil {
+			if err := store.UpsertInfo(HostInfo{Name: nodeID, Seller: "seller", Price: "$5", AuthSecret: "drop-me", TrafficResetDay: 31, Show: true, Group: "CN", Tags: []string{"cn2"}}); err != nil {
 				t.Fatal(err)
 			}
 
 			if err := store.UpsertReport(sampleMetrics(nodeID, 1000, 2000), 10); err != nil {
 				t.Fatal(err)
 			}
-			if err := store.UpsertReport(sampleMetrics(nodeID, 1500, 2600), 10); err != nil {
+			latest := sampleMetrics(nodeID, 1500, 2600)
+			latest.Services = []agent.Service{{Name: "nginx", LoadState: "loaded", ActiveState: "failed"}}
+			latest.Disks = append(latest.Disks, agent.Disk{Mount: "/data", ReadOnly: true})
+			if err := store.UpsertReport(latest, 10); err != nil {
 				t.Fatal(err)
 			}
 
+			if report, ok := store.Report(nodeID); !ok || report.NodeID != nodeID || report.Network.RxBytes != 1500 {
+				t.Fatalf("report = %#v ok = %v", report, ok)
+			}
+			if _, ok := store.Report("CN-missing"); ok {
+				t.Fatal("unexpected report for unknown node")
+			}
+			if info, ok := store.Info(nodeID); !ok || info.Name != nodeID || info.Seller != "seller" || info.AuthSecret != "" || info.TrafficResetDay != 31 {
+				t.Fatalf("info = %#v ok = %v", info, ok)
+			}
+			if _, ok := store.Info("CN-missing"); ok {
+				t.Fatal("unexpected info for unknown node")
+			}
+
 			nodes := store.AdminNodes(time.Minute)
 			if len(nodes) != 1 {
 				t.Fatalf("nodes len = %d", len(nodes))
@@ -985,14 +1023,23 @@ func TestStoreBackendsNodeLifecycle(t *testing.T) {
 			if nodes[0].Info.TrafficResetDay != 31 {
 				t.Fatalf("traffic reset day = %d", nodes[0].Info.TrafficResetDay)
 			}
+			if len(nodes[0].FailedServices) != 1 || nodes[0].FailedServices[0] != "nginx" {
+				t.Fatalf("failed services = %#v", nodes[0].FailedServices)
+			}
+			if len(nodes[0].Health) != 1 || nodes[0].Health[0] != "/data read-only" {
+				t.Fatalf("health = %#v", nodes[0].Health)
+			}
 
-			hosts := store.AkileHosts()
+			hosts := store.AkileHosts(serverdomain.HostFilter{})
 			if len(hosts) != 1 {
 				t.Fatalf("hosts len = %d", len(hosts))
 			}
 			if hosts[0].State.CycleNetInTransfer != 1500 || hosts[0].State.CycleNetOutTransfer != 2600 {
 				t.Fatalf("cycle traffic = %d/%d", hosts[0].State.CycleNetInTransfer, hosts[0].State.CycleNetOutTransfer)
 			}
+			if hosts[0].Host.Group != "CN" || !reflect.DeepEqual(hosts[0].Host.Tags, []string{"cn2"}) {
+				t.Fatalf("host labels = %q %q", hosts[0].Host.Group, hosts[0].Host.Tags)
+			}
 
 			backup := store.ExportNodes()
 			if len(backup.Nodes) != 1 {
@@ -1012,6 +1059,341 @@ func TestStoreBackendsNodeLifecycle(t *testing.T) {
 	}
 }
 
+func TestStoreBackendsBulkNodes(t *testing.T) {
+	price := "$5"
+	tags := []string{"edge"}
+	for _, tt := range storeBackends() {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.factory(t)
+			ops := []BulkOp{
+				{Op: "add", NodeID: "node-1"},
+				{Op: "add", NodeID: "node-2"},
+				{Op: "token", NodeID: "node-3", TokenHash: "hash-3"},
+				{Op: "add", NodeID: "node-4"},
+				{Op: "update", NodeID: "node-1", Info: &serverdomain.HostInfoPatch{Price: &price, Tags: &tags}},
+				{Op: "update", NodeID: "ghost", Info: &serverdomain.HostInfoPatch{Price: &price}},
+				{Op: "delete", NodeID: "node-2"},
+				{Op: "add", NodeID: "node-1"},
+			}
+			results, err := store.BulkNodes(ops, 3, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, result := range results {
+				got = append(got, result.NodeID+":"+result.Error)
+			}
+			want := []string{"node-1:", "node-2:", "node-3:", "node-4:max nodes reached", "node-1:", "ghost:unknown node", "node-2:", "node-1:"}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("results = %q", got)
+			}
+			if !store.ValidNodeToken("node-3", "hash-3") {
+				t.Fatal("token item was not stored")
+			}
+			nodes := store.AdminNodes(time.Minute)
+			if len(nodes) != 2 || nodes[0].NodeID != "node-1" || nodes[0].Info.Price != "$5" || !reflect.DeepEqual(nodes[0].Info.Tags, tags) || nodes[1].NodeID != "node-3" {
+				t.Fatalf("nodes = %#v", nodes)
+			}
+
+			results, err = store.BulkNodes([]BulkOp{{Op: "add", NodeID: "node-5"}, {Op: "delete", NodeID: "ghost"}}, 10, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if results[0].OK || results[0].Error != "rolled back" || results[1].Error != "unknown node" {
+				t.Fatalf("atomic results = %#v", results)
+			}
+			if nodes := store.AdminNodes(time.Minute); len(nodes) != 2 {
+				t.Fatalf("nodes after rolled back batch = %d", len(nodes))
+			}
+		})
+	}
+}
+
+func TestStoreBackendsRenameNode(t *testing.T) {
+	for _, tt := range storeBackends() {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.factory(t)
+			if err := store.SetNodeToken("old-1", "hash-1", 10); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.UpsertInfo(HostInfo{Name: "old-1", DisplayName: "Tokyo", Seller: "seller", TrafficResetDay: 15}); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.UpsertReport(sampleMetrics("old-1", 1000, 2000), 10); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.UpsertReport(sampleMetrics("other", 1000, 2000), 10); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.SetProbeTargets([]ProbeTarget{{Name: "CT", Type: "icmp", Target: "202.96.209.133", Nodes: []string{"old-1", "other"}}}); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.SetAgentOverrides(AgentOverrides{Nodes: map[string]map[string]string{"old-1": {"BASIC_INTERVAL": "5s"}}}); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.SetDashboards([]Dashboard{{Slug: "tokyo", SiteName: "Tokyo", Nodes: []string{"old-1"}}}); err != nil {
+				t.Fatal(err)
+			}
+			before := store.AdminNodes(time.Minute)[0]
+			if before.ID == "" {
+				t.Fatal("planned node has no internal id")
+			}
+
+			if err := store.RenameNode("old-1", "other"); !errors.Is(err, serverdomain.ErrNodeExists) {
+				t.Fatalf("rename onto a reported node = %v", err)
+			}
+			if err := store.RenameNode("ghost", "new-1"); !errors.Is(err, serverdomain.ErrUnknownNode) {
+				t.Fatalf("rename of an unknown node = %v", err)
+			}
+			if err := store.RenameNode("old-1", "new-1"); err != nil {
+				t.Fatal(err)
+			}
+
+			nodes := store.AdminNodes(time.Minute)
+			if len(nodes) != 2 || nodes[0].NodeID != "new-1" || nodes[0].ID != before.ID || nodes[0].CreatedAt != before.CreatedAt || !reflect.DeepEqual(nodes[0].Aliases, []string{"old-1"}) {
+				t.Fatalf("nodes after rename = %#v", nodes)
+			}
+			if info := nodes[0].Info; info.Name != "new-1" || info.DisplayName != "Tokyo" || info.Seller != "seller" || !nodes[0].Online {
+				t.Fatalf("renamed node = %#v", nodes[0])
+			}
+			if _, ok := store.Report("old-1"); ok {
+				t.Fatal("report left under the old id")
+			}
+			if report, ok := store.Report("new-1"); !ok || report.NodeID != "new-1" {
+				t.Fatalf("report = %#v, %v", report, ok)
+			}
+			if !store.ValidNodeToken("new-1", "hash-1") || store.ValidNodeToken("old-1", "hash-1") {
+				t.Fatal("token did not move with the node")
+			}
+			if got := store.ResolveNodeID("old-1"); got != "new-1" {
+				t.Fatalf("ResolveNodeID(old-1) = %q", got)
+			}
+			if got := store.ResolveNodeID("other"); got != "other" {
+				t.Fatalf("ResolveNodeID(other) = %q", got)
+			}
+			if targets := store.ProbeTargets(); !reflect.DeepEqual(targets[0].Nodes, []string{"new-1", "other"}) {
+				t.Fatalf("probe target nodes = %q", targets[0].Nodes)
+			}
+			if overrides := store.AgentOverrides(); overrides.Nodes["new-1"]["BASIC_INTERVAL"] != "5s" || overrides.Nodes["old-1"] != nil {
+				t.Fatalf("overrides = %#v", overrides)
+			}
+			if dashboards := store.Dashboards(); !reflect.DeepEqual(dashboards[0].Nodes, []string{"new-1"}) {
+				t.Fatalf("dashboard nodes = %q", dashboards[0].Nodes)
+			}
+			var renamed *AkileHost
+			for _, host := range store.AkileHosts(serverdomain.HostFilter{}) {
+				if host.Host.Name == "new-1" {
+					renamed = &host
+				}
+			}
+			if renamed == nil || renamed.Host.DisplayName != "Tokyo" || renamed.State.TrafficResetDay != 15 || renamed.State.TrafficPeriodStart == 0 {
+				t.Fatalf("public host = %#v", renamed)
+			}
+
+			if err := store.RenameNode("new-1", "old-1"); err != nil {
+				t.Fatal(err)
+			}
+			nodes = store.AdminNodes(time.Minute)
+			if nodes[0].NodeID != "old-1" || nodes[0].ID != before.ID || !reflect.DeepEqual(nodes[0].Aliases, []string{"new-1"}) {
+				t.Fatalf("nodes after renaming back = %#v", nodes)
+			}
+			if err := store.Delete("old-1"); err != nil {
+				t.Fatal(err)
+			}
+			if got := store.ResolveNodeID("new-1"); got != "new-1" {
+				t.Fatalf("alias outlived its node: %q", got)
+			}
+		})
+	}
+}
+
+func TestStoreBackendsAkileHostsHonorVisibility(t *testing.T) {
+	for _, tt := range storeBackends() {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.factory(t)
+			infos := []HostInfo{
+				{Name: "public-1"},
+				{Name: "edge-10.0.0.1", RedactHost: true},
+				{Name: "named-1", PublicName: "Tokyo"},
+				{Name: "private-1", Visibility: serverdomain.VisibilityPrivate},
+				{Name: "hidden-1", Visibility: serverdomain.VisibilityHidden},
+			}
+			for _, info := range infos {
+				if err := store.UpsertReport(sampleMetrics(info.Name, 100, 200), 10); err != nil {
+					t.Fatal(err)
+				}
+				if err := store.UpsertInfo(info); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := store.SetNodeToken("offline-1", "hash-1", 10); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.UpsertInfo(HostInfo{Name: "offline-1", Visibility: serverdomain.VisibilityPrivate}); err != nil {
+				t.Fatal(err)
+			}
+
+			hosts := func(includePrivate bool) map[string]AkileHost {
+				out := make(map[string]AkileHost)
+				for _, host := range store.AkileHosts(serverdomain.HostFilter{Private: includePrivate}) {
+					out[host.Host.Name] = host
+				}
+				return out
+			}
+			public := hosts(false)
+			if len(public) != 3 {
+				t.Fatalf("public hosts = %#v", public)
+			}
+			if host, ok := public["edge-***"]; !ok || host.Host.Hostname != "" {
+				t.Fatalf("redacted host = %#v, %v", host, ok)
+			}
+			if host, ok := public["Tokyo"]; !ok || host.Host.Hostname != "test-host" {
+				t.Fatalf("aliased host = %#v, %v", host, ok)
+			}
+			if _, ok := public["public-1"]; !ok {
+				t.Fatal("public host missing")
+			}
+			private := hosts(true)
+			if len(private) != 5 {
+				t.Fatalf("admin hosts = %#v", private)
+			}
+			if _, ok := private["hidden-1"]; ok {
+				t.Fatal("hidden host shown to admin")
+			}
+			if _, ok := private["offline-1"]; !ok {
+				t.Fatal("offline private host missing for admin")
+			}
+		})
+	}
+}
+
+func TestStoreBackendsShareSecretAndNodeKey(t *testing.T) {
+	for _, tt := range storeBackends() {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.factory(t)
+			if saved, ok := store.SavedShareSecret(); ok {
+				t.Fatalf("SavedShareSecret before the first link = %q", saved)
+			}
+			secret, err := store.ShareSecret()
+			if err != nil || secret == "" {
+				t.Fatalf("ShareSecret = %q, %v", secret, err)
+			}
+			if again, err := store.ShareSecret(); err != nil || again != secret {
+				t.Fatalf("second ShareSecret = %q, %v", again, err)
+			}
+			if saved, ok := store.SavedShareSecret(); !ok || saved != secret {
+				t.Fatalf("SavedShareSecret = %q, %v", saved, ok)
+			}
+			if err := store.ResetShareSecret(); err != nil {
+				t.Fatal(err)
+			}
+			if reset, err := store.ShareSecret(); err != nil || reset == secret || reset == "" {
+				t.Fatalf("ShareSecret after reset = %q, %v", reset, err)
+			}
+
+			if err := store.AddPlannedNode("old-1", 10); err != nil {
+				t.Fatal(err)
+			}
+			key := store.AdminNodes(time.Minute)[0].ID
+			if nodeID, ok := store.NodeByKey(key); !ok || nodeID != "old-1" {
+				t.Fatalf("NodeByKey = %q, %v", nodeID, ok)
+			}
+			if err := store.RenameNode("old-1", "new-1"); err != nil {
+				t.Fatal(err)
+			}
+			if nodeID, ok := store.NodeByKey(key); !ok || nodeID != "new-1" {
+				t.Fatalf("NodeByKey after rename = %q, %v", nodeID, ok)
+			}
+			if _, ok := store.NodeByKey("missing"); ok {
+				t.Fatal("NodeByKey found a missing key")
+			}
+		})
+	}
+}
+
+func TestStoreBackendsUptimeLogFollowsNode(t *testing.T) {
+	for _, tt := range storeBackends() {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.factory(t)
+			metrics := sampleMetrics("old-1", 100, 200)
+			metrics.Timestamp = 1000
+			if err := store.UpsertReport(metrics, 10); err != nil {
+				t.Fatal(err)
+			}
+			metrics.Timestamp = 2000
+			if err := store.UpsertReport(metrics, 10); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.RecordOutage("old-1", serverdomain.Outage{Start: 1100, End: 1500}); err != nil {
+				t.Fatal(err)
+			}
+			want := serverdomain.UptimeLog{Since: 1000, Outages: []serverdomain.Outage{{Start: 1100, End: 1500}}}
+			if got := store.UptimeLogs()["old-1"]; !reflect.DeepEqual(got, want) {
+				t.Fatalf("uptime log = %#v", got)
+			}
+			if err := store.RenameNode("old-1", "new-1"); err != nil {
+				t.Fatal(err)
+			}
+			logs := store.UptimeLogs()
+			if _, ok := logs["old-1"]; ok || !reflect.DeepEqual(logs["new-1"], want) {
+				t.Fatalf("uptime logs after rename = %#v", logs)
+			}
+			if err := store.Delete("new-1"); err != nil {
+				t.Fatal(err)
+			}
+			if logs := store.UptimeLogs(); len(logs) != 0 {
+				t.Fatalf("uptime logs after delete = %#v", logs)
+			}
+		})
+	}
+}
+
+func TestSQLiteStoreMigratesPlannedNodes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, query := range []string{
+		`CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, applied_at INTEGER NOT NULL)`,
+		`CREATE TABLE planned_nodes (node_id TEXT PRIMARY KEY, created_at INTEGER NOT NULL, token_hash TEXT NOT NULL DEFAULT '')`,
+		`INSERT INTO schema_migrations(version, applied_at) VALUES (1, 1)`,
+		`INSERT INTO planned_nodes(node_id, created_at, token_hash) VALUES ('HK-1', 1, 'hash'), ('HK-2', 2, '')`,
+	} {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	store, err := NewSQLiteStore(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := store.AdminNodes(time.Minute)
+	if len(nodes) != 2 || nodes[0].ID == "" || nodes[1].ID == "" || nodes[0].ID == nodes[1].ID {
+		t.Fatalf("migrated nodes = %#v", nodes)
+	}
+	if !store.ValidNodeToken("HK-1", "hash") {
+		t.Fatal("token lost in migration")
+	}
+	if err := store.RenameNode("HK-1", "HK-3"); err != nil {
+		t.Fatal(err)
+	}
+	store.db.Close()
+
+	reopened, err := NewSQLiteStore(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.ResolveNodeID("HK-1"); got != "HK-3" {
+		t.Fatalf("alias after reopening = %q", got)
+	}
+	if again := reopened.AdminNodes(time.Minute); again[1].NodeID != "HK-3" || again[1].ID != nodes[0].ID {
+		t.Fatalf("internal id changed on rename and reopen: %#v, was %q", again[1], nodes[0].ID)
+	}
+}
+
 func TestSQLiteStoreImportsExistingJSON(t *testing.T) {
 	dir := t.TempDir()
 	jsonPath := filepath.Join(dir, "server.json")
@@ -1036,6 +1418,12 @@ func TestSQLiteStoreImportsExistingJSON(t *testing.T) {
 	if err := jsonStore.UpsertReport(sampleMetrics(nodeID, 2000, 3000), 10); err != nil {
 		t.Fatal(err)
 	}
+	if err := jsonStore.SetProbeTargets([]ProbeTarget{{Name: "CT", Type: "icmp", Target: "202.96.209.133"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := jsonStore.SetDashboards([]Dashboard{{Slug: "jp", SiteName: "Japan", Nodes: []string{nodeID}}}); err != nil {
+		t.Fatal(err)
+	}
 
 	sqliteStore, err := NewSQLiteStore(filepath.Join(dir, "server.db"), jsonPath)
 	if err != nil {
@@ -1044,6 +1432,12 @@ func TestSQLiteStoreImportsExistingJSON(t *testing.T) {
 	if got := sqliteStore.SiteName(); got != "Migrated Monitor" {
 		t.Fatalf("site name = %q", got)
 	}
+	if got := sqliteStore.ProbeTargets(); len(got) != 1 || got[0].Name != "CT" {
+		t.Fatalf("probe targets = %#v", got)
+	}
+	if got := sqliteStore.Dashboards(); len(got) != 1 || got[0].Slug != "jp" || got[0].Nodes[0] != nodeID {
+		t.Fatalf("dashboards = %#v", got)
+	}
 	if !sqliteStore.ValidNodeToken(nodeID, tokenHash) {
 		t.Fatal("expected imported token to be valid")
 	}
@@ -6,12 +6,19 @@ import (
 )
 
 type Settings = domain.Settings
+type ProbeTarget = domain.ProbeTarget
+type AgentOverrides = domain.AgentOverrides
+type Dashboard = domain.Dashboard
 type PlannedNode = domain.PlannedNode
 type AdminNode = domain.AdminNode
 type NodeBackup = domain.NodeBackup
 type NodeBackupRecord = domain.NodeBackupRecord
 type HostInfo = domain.HostInfo
 type TrafficStat = domain.TrafficStat
+type UptimeLog = domain.UptimeLog
+type Outage = domain.Outage
+type BulkOp = domain.BulkOp
+type BulkResult = domain.BulkResult
 
 type AkileHost = serverapp.AkileHost
 type AkileHostMeta = serverapp.AkileHostMeta
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"time"
+
+	serverapp "vps-agent/internal/server/application"
+	serverdomain "vps-agent/internal/server/domain"
+)
+
+// handleAdminUptime serves the availability report for ?month=YYYY-MM, the
+// current month by default, optionally limited to one ?tag. With
+// ?format=csv it downloads one row per node instead, or one row per outage
+// with &view=outages.
+func (s *Server) handleAdminUptime(w http.ResponseWriter, r *http.Request) {
+	if !s.adminAuthorized(r) {
+		http.Error(w, "admin login required", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+	query := r.URL.Query()
+	tag, err := serverdomain.NormalizeTag(query.Get("tag"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	if raw := query.Get("month"); raw != "" {
+		if month, err = time.ParseInLocation("2006-01", raw, now.Location()); err != nil {
+			http.Error(w, "invalid month, want YYYY-MM", http.StatusBadRequest)
+			return
+		}
+	}
+	nodes := s.store.AdminNodes(s.cfg.OfflineWait)
+	if tag != "" {
+		matched := nodes[:0]
+		for _, node := range nodes {
+			if node.Info.HasLabel(tag) {
+				matched = append(matched, node)
+			}
+		}
+		nodes = matched
+	}
+	report := serverapp.Uptime(nodes, s.store.UptimeLogs(), month, now)
+	switch query.Get("format") {
+	case "", "json":
+		writeJSON(w, report)
+	case "csv":
+		if query.Get("view") == "outages" {
+			writeCSV(w, "monitor-outages-"+report.Month+".csv", outageRows(report))
+		} else {
+			writeCSV(w, "monitor-uptime-"+report.Month+".csv", uptimeRows(report))
+		}
+	default:
+		http.Error(w, "invalid format", http.StatusBadRequest)
+	}
+}
+
+func uptimeRows(report serverapp.UptimeReport) [][]string {
+	rows := [][]string{{"node_id", "display_name", "seller", "month", "uptime_percent", "downtime_seconds", "outages", "billing_start", "billing_end", "billing_uptime_percent", "billing_downtime_seconds", "today_uptime_percent", "tracked_since", "online"}}
+	for _, node := range report.Nodes {
+		rows = append(rows, []string{
+			node.NodeID,
+			node.DisplayName,
+			node.Seller,
+			report.Month,
+			uptimePercent(node.Month),
+			strconv.FormatInt(node.Month.Downtime, 10),
+			strconv.Itoa(node.Month.Outages),
+			csvTime(node.Billing.Start),
+			csvTime(node.Billing.End),
+			uptimePercent(node.Billing),
+			strconv.FormatInt(node.Billing.Downtime, 10),
+			uptimePercent(node.Today),
+			csvTime(node.Since),
+			strconv.FormatBool(node.Online),
+		})
+	}
+	return rows
+}
+
+func outageRows(report serverapp.UptimeReport) [][]string {
+	rows := [][]string{{"node_id", "display_name", "start", "end", "duration_seconds", "ongoing"}}
+	for _, node := range report.Nodes {
+		for _, outage := range node.Outages {
+			end := ""
+			if !outage.Ongoing {
+				end = csvTime(outage.End)
+			}
+			rows = append(rows, []string{node.NodeID, node.DisplayName, csvTime(outage.Start), end, strconv.FormatInt(outage.Duration, 10), strconv.FormatBool(outage.Ongoing)})
+		}
+	}
+	return rows
+}
+
+// uptimePercent leaves the cell empty for a period nothing was tracked in.
+func uptimePercent(stat serverdomain.UptimeStat) string {
+	if stat.Monitored == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(stat.Percent, 'f', 3, 64)
+}
+
+func csvTime(unix int64) string {
+	return time.Unix(unix, 0).Format(time.RFC3339)
+}
+
+func writeCSV(w http.ResponseWriter, name string, rows [][]string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename="+name)
+	_ = csv.NewWriter(w).WriteAll(rows)
+}
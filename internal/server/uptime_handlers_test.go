@@ -0,0 +1,122 @@
+package server
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	serverapp "vps-agent/internal/server/application"
+)
+
+func TestAgentReportGapShowsInUptimeReport(t *testing.T) {
+	s := newTestServer(t)
+	admin, err := s.sessions.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const token = "agent-token"
+	if err := s.store.SetNodeToken("HK-1", hashToken(token), 10); err != nil {
+		t.Fatal(err)
+	}
+	lastSeen := time.Now().Add(-5 * time.Minute)
+	s.started = lastSeen.Add(-time.Hour)
+	metrics := sampleMetrics("HK-1", 0, 0)
+	metrics.Timestamp = lastSeen.Unix()
+	if err := s.store.UpsertReport(metrics, 10); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "https://monitor.example.com/api/agent/report", strings.NewReader(`{}`))
+	req.Header.Set("X-Node-ID", "HK-1")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	s.handleAgentReport(resp, req)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("report status = %d body = %s", resp.Code, resp.Body.String())
+	}
+
+	month := lastSeen.Format("2006-01")
+	var report serverapp.UptimeReport
+	decodeJSONResponse(t, uptimeRequest(s, "/api/admin/uptime?month="+month, admin), &report)
+	if report.Month != month || len(report.Nodes) != 1 {
+		t.Fatalf("report = %#v", report)
+	}
+	node := report.Nodes[0]
+	if !node.Online || len(node.Outages) != 1 || node.Outages[0].Start != lastSeen.Unix() || node.Outages[0].Duration < 290 || node.Month.Outages != 1 {
+		t.Fatalf("node = %#v", node)
+	}
+
+	resp = uptimeRequest(s, "/api/admin/uptime?month="+month+"&format=csv", admin)
+	rows, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header().Get("Content-Type") != "text/csv; charset=utf-8" || len(rows) != 2 || rows[0][0] != "node_id" || rows[1][0] != "HK-1" || rows[1][6] != "1" {
+		t.Fatalf("summary csv = %q", rows)
+	}
+	resp = uptimeRequest(s, "/api/admin/uptime?month="+month+"&format=csv&view=outages", admin)
+	if rows, err = csv.NewReader(resp.Body).ReadAll(); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1][0] != "HK-1" || rows[1][5] != "false" {
+		t.Fatalf("outages csv = %q", rows)
+	}
+
+	for target, status := range map[string]int{
+		"/api/admin/uptime?month=2026-13": http.StatusBadRequest,
+		"/api/admin/uptime?format=xml":    http.StatusBadRequest,
+	} {
+		if resp := uptimeRequest(s, target, admin); resp.Code != status {
+			t.Fatalf("%s: status = %d, want %d", target, resp.Code, status)
+		}
+	}
+	if resp := uptimeRequest(s, "/api/admin/uptime", ""); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("without login: status = %d", resp.Code)
+	}
+}
+
+func TestAgentReportGapIgnoresServerDowntime(t *testing.T) {
+	s := newTestServer(t)
+	const token = "agent-token"
+	if err := s.store.SetNodeToken("HK-1", hashToken(token), 10); err != nil {
+		t.Fatal(err)
+	}
+	report := func(lastSeen time.Time) {
+		t.Helper()
+		metrics := sampleMetrics("HK-1", 0, 0)
+		metrics.Timestamp = lastSeen.Unix()
+		if err := s.store.UpsertReport(metrics, 10); err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "https://monitor.example.com/api/agent/report", strings.NewReader(`{}`))
+		req.Header.Set("X-Node-ID", "HK-1")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp := httptest.NewRecorder()
+		s.handleAgentReport(resp, req)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("report status = %d body = %s", resp.Code, resp.Body.String())
+		}
+	}
+
+	now := time.Now()
+	s.started = now.Add(-30 * time.Second)
+	report(now.Add(-time.Hour))
+	if outages := s.store.UptimeLogs()["HK-1"].Outages; len(outages) != 0 {
+		t.Fatalf("outage recorded while the server was down: %#v", outages)
+	}
+
+	s.started = now.Add(-5 * time.Minute)
+	report(now.Add(-time.Hour))
+	outages := s.store.UptimeLogs()["HK-1"].Outages
+	if len(outages) != 1 || outages[0].Start != s.started.Unix() {
+		t.Fatalf("outage not clipped to server start: %#v", outages)
+	}
+}
+
+func uptimeRequest(s *Server, target, token string) *httptest.ResponseRecorder {
+	resp := httptest.NewRecorder()
+	s.handleAdminUptime(resp, authedAdminRequest(http.MethodGet, target, token))
+	return resp
+}
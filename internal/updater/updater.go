@@ -0,0 +1,289 @@
+// Package updater replaces the agent executable with a build offered by the
+// server and rolls it back when the new build cannot report.
+//
+// The swap keeps the running binary next to the new one as <exe>.old and
+// records the pending version in <exe>.update.json. The new build runs on
+// trial until its first successful report; if it keeps failing, or keeps
+// restarting before it gets that far, the old binary is moved back and the
+// version is remembered so the same offer is not taken again.
+//
+// With a release key pinned in config.env, a download is only installed when
+// it is listed in the server's signed release manifest.
+package updater
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"vps-agent/internal/agent"
+	"vps-agent/internal/buildinfo"
+	"vps-agent/internal/release"
+)
+
+// ErrRestart tells the caller to exit so that systemd or the Windows service
+// manager starts the executable that is now in place.
+var ErrRestart = errors.New("agent restart required")
+
+const (
+	// trialTimeout is how long a new build may go without a successful
+	// report before it is rolled back.
+	trialTimeout = 5 * time.Minute
+	// maxTrialStarts rolls back a build that keeps exiting before it
+	// reports, since the trial timer restarts with every process.
+	maxTrialStarts = 3
+	// retryAfter spaces out attempts after a failed download or check, as
+	// the offer comes back with every report.
+	retryAfter    = time.Hour
+	verifyTimeout = 10 * time.Second
+)
+
+type state struct {
+	Pending  string `json:"pending,omitempty"`
+	Previous string `json:"previous,omitempty"`
+	Starts   int    `json:"starts,omitempty"`
+	Failed   string `json:"failed,omitempty"`
+}
+
+type Updater struct {
+	exe     string
+	version string
+	state   state
+	started time.Time
+	retryAt time.Time
+	// key verifies the release manifest; nil trusts the checksum in the
+	// server's offer.
+	key ed25519.PublicKey
+
+	// verify checks that a downloaded binary runs on this host and reports
+	// the expected version.
+	verify func(ctx context.Context, path, version string) error
+}
+
+// New prepares the updater for the running executable. releaseKey is the
+// RELEASE_KEY from config.env, empty when none was pinned. It returns
+// ErrRestart after rolling back a build that failed too many starts.
+func New(version, releaseKey string) (*Updater, error) {
+	var key ed25519.PublicKey
+	if releaseKey != "" {
+		var err error
+		if key, err = release.ParsePublicKey(releaseKey); err != nil {
+			return nil, err
+		}
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	u, err := open(exe, version)
+	if u != nil {
+		u.key = key
+	}
+	return u, err
+}
+
+func open(exe, version string) (*Updater, error) {
+	u := &Updater{exe: exe, version: version, started: time.Now(), verify: verifyBinary}
+	// Leftovers of an interrupted download or of a rollback on Windows,
+	// where the replaced executable could not be removed while running.
+	_ = os.Remove(exe + ".new")
+	_ = os.Remove(exe + ".bad")
+	if data, err := os.ReadFile(u.statePath()); err == nil {
+		_ = json.Unmarshal(data, &u.state)
+	}
+	if u.state.Pending == "" {
+		return u, nil
+	}
+	if u.state.Pending != version || !exists(exe+".old") {
+		// Reinstalled by hand while a trial was pending.
+		_ = os.Remove(exe + ".old")
+		u.state = state{Failed: u.state.Failed}
+		return u, u.save()
+	}
+	u.state.Starts++
+	if u.state.Starts > maxTrialStarts {
+		return u, u.rollback(fmt.Sprintf("exited %d times before reporting", u.state.Starts-1))
+	}
+	return u, u.save()
+}
+
+// Trial reports whether the running build is waiting for its first
+// successful report.
+func (u *Updater) Trial() bool {
+	return u.state.Pending != ""
+}
+
+// Reported confirms a build on trial once it has reached the server.
+func (u *Updater) Reported() {
+	if !u.Trial() {
+		return
+	}
+	log.Printf("update to %s confirmed", u.version)
+	_ = os.Remove(u.exe + ".old")
+	u.state = state{Failed: u.state.Failed}
+	if err := u.save(); err != nil {
+		log.Printf("update state save failed: %v", err)
+	}
+}
+
+// CheckTrial rolls back a build on trial that has not reported in time and
+// returns ErrRestart when it did.
+func (u *Updater) CheckTrial() error {
+	if !u.Trial() || time.Since(u.started) < trialTimeout {
+		return nil
+	}
+	return u.rollback(fmt.Sprintf("no successful report within %s", trialTimeout))
+}
+
+// Apply installs an offered build. download fetches the binary from the
+// server. On success the new executable is in place and ErrRestart is
+// returned; offers that are not newer, or that already failed, are ignored.
+func (u *Updater) Apply(ctx context.Context, offer agent.UpdateOffer, download func(context.Context, string) ([]byte, error)) error {
+	if u.Trial() || offer.Version == u.state.Failed || !buildinfo.Newer(offer.Version, u.version) || time.Now().Before(u.retryAt) {
+		return nil
+	}
+	err := u.install(ctx, offer, download)
+	if err != nil && !errors.Is(err, ErrRestart) {
+		u.retryAt = time.Now().Add(retryAfter)
+	}
+	return err
+}
+
+func (u *Updater) install(ctx context.Context, offer agent.UpdateOffer, download func(context.Context, string) ([]byte, error)) error {
+	data, err := download(ctx, offer.URL)
+	if err != nil {
+		return fmt.Errorf("download %s: %w", offer.URL, err)
+	}
+	sum := sha256.Sum256(data)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), offer.SHA256) {
+		return fmt.Errorf("download %s: sha256 mismatch", offer.URL)
+	}
+	if u.key != nil {
+		if err := u.checkManifest(ctx, offer, data, download); err != nil {
+			return err
+		}
+	}
+	info, err := os.Stat(u.exe)
+	if err != nil {
+		return err
+	}
+	next := u.exe + ".new"
+	if err := os.WriteFile(next, data, info.Mode().Perm()|0100); err != nil {
+		return err
+	}
+	if err := u.verify(ctx, next, offer.Version); err != nil {
+		_ = os.Remove(next)
+		return err
+	}
+	_ = os.Remove(u.exe + ".old")
+	if err := os.Rename(u.exe, u.exe+".old"); err != nil {
+		_ = os.Remove(next)
+		return err
+	}
+	if err := os.Rename(next, u.exe); err != nil {
+		_ = os.Rename(u.exe+".old", u.exe)
+		_ = os.Remove(next)
+		return err
+	}
+	u.state = state{Pending: offer.Version, Previous: u.version, Failed: u.state.Failed}
+	if err := u.save(); err != nil {
+		return err
+	}
+	log.Printf("updated %s -> %s, restarting", u.version, offer.Version)
+	return ErrRestart
+}
+
+// checkManifest fetches the signed manifest from the directory the binary
+// was offered from and requires the download to be listed there under the
+// offered version.
+func (u *Updater) checkManifest(ctx context.Context, offer agent.UpdateOffer, data []byte, download func(context.Context, string) ([]byte, error)) error {
+	dir := path.Dir(offer.URL)
+	manifest, err := download(ctx, path.Join(dir, release.ManifestName))
+	if err != nil {
+		return fmt.Errorf("download release manifest: %w", err)
+	}
+	signature, err := download(ctx, path.Join(dir, release.SignatureName))
+	if err != nil {
+		return fmt.Errorf("download release signature: %w", err)
+	}
+	signed, err := release.Verify(u.key, manifest, string(signature))
+	if err != nil {
+		return err
+	}
+	if signed.Version != offer.Version {
+		return fmt.Errorf("release manifest is for %s, offer is %s", signed.Version, offer.Version)
+	}
+	return signed.Check(path.Base(offer.URL), data)
+}
+
+func (u *Updater) rollback(reason string) error {
+	log.Printf("update to %s rolled back to %s: %s", u.state.Pending, u.state.Previous, reason)
+	// Renaming works on a running executable on Windows as well, where
+	// removing it does not.
+	if err := os.Rename(u.exe, u.exe+".bad"); err != nil {
+		return err
+	}
+	if err := os.Rename(u.exe+".old", u.exe); err != nil {
+		_ = os.Rename(u.exe+".bad", u.exe)
+		return err
+	}
+	_ = os.Remove(u.exe + ".bad")
+	u.state = state{Failed: u.state.Pending}
+	if err := u.save(); err != nil {
+		log.Printf("update state save failed: %v", err)
+	}
+	return ErrRestart
+}
+
+func (u *Updater) statePath() string {
+	return u.exe + ".update.json"
+}
+
+func (u *Updater) save() error {
+	if u.state == (state{}) {
+		err := os.Remove(u.statePath())
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	data, err := json.Marshal(u.state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(u.statePath(), data, 0600)
+}
+
+// verifyBinary runs "<path> version" so that a binary for the wrong
+// architecture or a truncated file is caught before it replaces a working one.
+func verifyBinary(ctx context.Context, path, version string) error {
+	ctx, cancel := context.WithTimeout(ctx, verifyTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "version").Output()
+	if err != nil {
+		return fmt.Errorf("new binary does not run: %w", err)
+	}
+	if fields := strings.Fields(string(out)); len(fields) < 2 || fields[1] != version {
+		return fmt.Errorf("new binary reports %q, want %s", strings.TrimSpace(string(out)), version)
+	}
+	return nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,234 @@
+package updater
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"vps-agent/internal/agent"
+	"vps-agent/internal/release"
+)
+
+func TestApplySwapsExecutableAndConfirmsOnReport(t *testing.T) {
+	exe := writeExecutable(t, "old build")
+	u := openForTest(t, exe, "0.1.0")
+
+	offer, download := offerFor("0.2.0", "new build")
+	if err := u.Apply(context.Background(), offer, download); !errors.Is(err, ErrRestart) {
+		t.Fatalf("Apply() = %v, want ErrRestart", err)
+	}
+	assertContent(t, exe, "new build")
+	assertContent(t, exe+".old", "old build")
+
+	u = openForTest(t, exe, "0.2.0")
+	if !u.Trial() {
+		t.Fatal("new build should start on trial")
+	}
+	u.Reported()
+	if u.Trial() {
+		t.Fatal("trial should end after a report")
+	}
+	for _, leftover := range []string{exe + ".old", exe + ".update.json"} {
+		if _, err := os.Stat(leftover); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("%s left behind: %v", leftover, err)
+		}
+	}
+}
+
+func TestApplyRejectsBadDownloadsAndWaitsBeforeRetrying(t *testing.T) {
+	exe := writeExecutable(t, "old build")
+	u := openForTest(t, exe, "0.1.0")
+
+	offer, _ := offerFor("0.2.0", "new build")
+	calls := 0
+	tampered := func(context.Context, string) ([]byte, error) {
+		calls++
+		return []byte("tampered"), nil
+	}
+	if err := u.Apply(context.Background(), offer, tampered); err == nil || errors.Is(err, ErrRestart) {
+		t.Fatalf("Apply() = %v, want checksum error", err)
+	}
+	if err := u.Apply(context.Background(), offer, tampered); err != nil || calls != 1 {
+		t.Fatalf("retry Apply() = %v after %d downloads, want no new attempt", err, calls)
+	}
+	assertContent(t, exe, "old build")
+
+	older, download := offerFor("0.0.9", "older build")
+	u.retryAt = time.Time{}
+	if err := u.Apply(context.Background(), older, download); err != nil {
+		t.Fatal(err)
+	}
+	assertContent(t, exe, "old build")
+}
+
+func TestApplyWithReleaseKeyRequiresSignedManifest(t *testing.T) {
+	private, public, err := release.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := release.ParsePrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinned, err := release.ParsePublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offer, _ := offerFor("0.2.0", "new build")
+	sum := offer.SHA256
+	server := func(manifestVersion string) func(context.Context, string) ([]byte, error) {
+		manifest, _ := json.Marshal(release.Manifest{Version: manifestVersion, Files: []release.File{
+			{Name: "vps-agent-linux-amd64", SHA256: sum, Size: int64(len("new build"))},
+		}})
+		files := map[string][]byte{
+			"/download/vps-agent-linux-amd64": []byte("new build"),
+			"/download/manifest.json":         manifest,
+			"/download/manifest.json.sig":     []byte(release.Sign(signer, manifest)),
+		}
+		return func(_ context.Context, path string) ([]byte, error) {
+			data, ok := files[path]
+			if !ok {
+				return nil, fmt.Errorf("%s not found", path)
+			}
+			return data, nil
+		}
+	}
+
+	exe := writeExecutable(t, "old build")
+	u := openForTest(t, exe, "0.1.0")
+	u.key = pinned
+	if err := u.Apply(context.Background(), offer, server("0.1.5")); err == nil || errors.Is(err, ErrRestart) {
+		t.Fatalf("Apply() = %v, want manifest version error", err)
+	}
+	assertContent(t, exe, "old build")
+
+	_, other, err := release.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.key, err = release.ParsePublicKey(other); err != nil {
+		t.Fatal(err)
+	}
+	u.retryAt = time.Time{}
+	if err := u.Apply(context.Background(), offer, server("0.2.0")); err == nil || errors.Is(err, ErrRestart) {
+		t.Fatalf("Apply() = %v, want signature error", err)
+	}
+	assertContent(t, exe, "old build")
+
+	u.key = pinned
+	u.retryAt = time.Time{}
+	if err := u.Apply(context.Background(), offer, server("0.2.0")); !errors.Is(err, ErrRestart) {
+		t.Fatalf("Apply() = %v, want ErrRestart", err)
+	}
+	assertContent(t, exe, "new build")
+}
+
+func TestBuildThatNeverReportsIsRolledBack(t *testing.T) {
+	exe := writeExecutable(t, "old build")
+	u := openForTest(t, exe, "0.1.0")
+	offer, download := offerFor("0.2.0", "new build")
+	if err := u.Apply(context.Background(), offer, download); !errors.Is(err, ErrRestart) {
+		t.Fatal(err)
+	}
+
+	u = openForTest(t, exe, "0.2.0")
+	if err := u.CheckTrial(); err != nil {
+		t.Fatalf("CheckTrial() = %v before the timeout", err)
+	}
+	u.started = time.Now().Add(-trialTimeout)
+	if err := u.CheckTrial(); !errors.Is(err, ErrRestart) {
+		t.Fatalf("CheckTrial() = %v, want ErrRestart", err)
+	}
+	assertContent(t, exe, "old build")
+
+	u = openForTest(t, exe, "0.1.0")
+	called := false
+	if err := u.Apply(context.Background(), offer, func(context.Context, string) ([]byte, error) {
+		called = true
+		return nil, nil
+	}); err != nil || called {
+		t.Fatalf("failed version offered again: err = %v download = %v", err, called)
+	}
+}
+
+func TestBuildThatKeepsExitingIsRolledBack(t *testing.T) {
+	exe := writeExecutable(t, "old build")
+	u := openForTest(t, exe, "0.1.0")
+	offer, download := offerFor("0.2.0", "new build")
+	if err := u.Apply(context.Background(), offer, download); !errors.Is(err, ErrRestart) {
+		t.Fatal(err)
+	}
+
+	for start := 1; start <= maxTrialStarts; start++ {
+		if _, err := open(exe, "0.2.0"); err != nil {
+			t.Fatalf("start %d: %v", start, err)
+		}
+	}
+	if _, err := open(exe, "0.2.0"); !errors.Is(err, ErrRestart) {
+		t.Fatalf("start %d = %v, want ErrRestart", maxTrialStarts+1, err)
+	}
+	assertContent(t, exe, "old build")
+}
+
+func TestVerifyBinaryRunsVersionCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the executable")
+	}
+	script := filepath.Join(t.TempDir(), "vps-agent.new")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho vps-agent 0.2.0 linux/amd64\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyBinary(context.Background(), script, "0.2.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyBinary(context.Background(), script, "0.3.0"); err == nil {
+		t.Fatal("expected version mismatch")
+	}
+	if err := verifyBinary(context.Background(), filepath.Join(t.TempDir(), "missing"), "0.2.0"); err == nil {
+		t.Fatal("expected error for a binary that does not run")
+	}
+}
+
+func openForTest(t *testing.T, exe, version string) *Updater {
+	t.Helper()
+	u, err := open(exe, version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.verify = func(context.Context, string, string) error { return nil }
+	return u
+}
+
+func writeExecutable(t *testing.T, content string) string {
+	t.Helper()
+	exe := filepath.Join(t.TempDir(), "vps-agent")
+	if err := os.WriteFile(exe, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return exe
+}
+
+func offerFor(version, content string) (agent.UpdateOffer, func(context.Context, string) ([]byte, error)) {
+	sum := sha256.Sum256([]byte(content))
+	offer := agent.UpdateOffer{Version: version, URL: "/download/vps-agent-linux-amd64", SHA256: hex.EncodeToString(sum[:])}
+	return offer, func(context.Context, string) ([]byte, error) { return []byte(content), nil }
+}
+
+func assertContent(t *testing.T, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Fatalf("%s = %q, want %q", path, data, want)
+	}
+}
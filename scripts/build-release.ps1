@@ -56,6 +56,22 @@ foreach ($target in $targets) {
 
 Copy-Item (Join-Path $release "vps-agent-*") $embedBins -Force
 
+# The manifest is signed with a key kept off the server (see vps-release
+# keygen); agents installed with its public key refuse unsigned builds.
+foreach ($name in @("manifest.json", "manifest.json.sig", "release.pub")) {
+  Remove-Item (Join-Path $embedBins $name) -Force -ErrorAction SilentlyContinue
+}
+if ($env:RELEASE_SIGNING_KEY) {
+  Remove-Item Env:\GOOS, Env:\GOARCH, Env:\GOARM -ErrorAction SilentlyContinue
+  go run ./cmd/vps-release sign -key $env:RELEASE_SIGNING_KEY -dir $embedBins
+  if ($LASTEXITCODE -ne 0) { throw "signing the release manifest failed" }
+  foreach ($name in @("manifest.json", "manifest.json.sig", "release.pub")) {
+    Copy-Item (Join-Path $embedBins $name) $release -Force
+  }
+} else {
+  Write-Warning "RELEASE_SIGNING_KEY not set, agent binaries are not signed"
+}
+
 foreach ($target in $targets) {
   if ($target.OS -eq "linux" -or $target.OS -eq "windows") {
     Build-One "./cmd/vps-server" "vps-server" $target
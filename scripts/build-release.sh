@@ -41,6 +41,16 @@ done
 
 cp "$RELEASE"/vps-agent-* "$EMBED_BINS"/
 
+# The manifest is signed with a key kept off the server (see vps-release
+# keygen); agents installed with its public key refuse unsigned builds.
+rm -f "$EMBED_BINS/manifest.json" "$EMBED_BINS/manifest.json.sig" "$EMBED_BINS/release.pub"
+if [ -n "${RELEASE_SIGNING_KEY:-}" ]; then
+  go run ./cmd/vps-release sign -key "$RELEASE_SIGNING_KEY" -dir "$EMBED_BINS"
+  cp "$EMBED_BINS/manifest.json" "$EMBED_BINS/manifest.json.sig" "$EMBED_BINS/release.pub" "$RELEASE"/
+else
+  echo "RELEASE_SIGNING_KEY not set, agent binaries are not signed" >&2
+fi
+
 for pair in "linux amd64" "linux arm64" "linux arm 7" "linux 386" "windows amd64" "windows arm64" "windows 386"; do
   set -- $pair
   build_one ./cmd/vps-server vps-server "$1" "$2" "${3:-}"
@@ -87,6 +87,8 @@ if ($service) {
 }
 
 sc.exe create vps-agent binPath= "`"$installDir\vps-agent.exe`" run --config `"$configDir\config.env`"" start= auto DisplayName= "VPS Monitor Agent" | Out-Null
+sc.exe failure vps-agent reset= 86400 actions= restart/5000/restart/5000/restart/5000 | Out-Null
+sc.exe failureflag vps-agent 1 | Out-Null
 Start-Service vps-agent
 Get-Service vps-agent
 Write-Host "agent installed: $NodeId -> $Server"
@@ -7,7 +7,7 @@ if [ "$(id -u)" -ne 0 ]; then
 fi
 
 systemctl disable --now vps-agent 2>/dev/null || true
-rm -f /etc/systemd/system/vps-agent.service /usr/local/bin/vps-agent
+rm -f /etc/systemd/system/vps-agent.service /usr/local/bin/vps-agent /usr/local/bin/vps-agent.old /usr/local/bin/vps-agent.update.json
 rm -rf /etc/vps-agent
 systemctl daemon-reload 2>/dev/null || true
 echo "vps-agent uninstalled"
@@ -2,8 +2,12 @@ import assert from 'node:assert/strict'
 
 import {
   getHostChartSeries,
+  groupHosts,
+  hostHasLabel,
+  hostTitle,
   normalizeMonitorHosts,
-  regionFlag
+  regionFlag,
+  statusPagePath
 } from '../src/utils/monitor.js'
 import {
   DEFAULT_CHART_LOCALE,
@@ -15,6 +19,9 @@ const result = normalizeMonitorHosts([
   {
     Host: {
       Name: 'UK-node-1',
+      DisplayName: 'London',
+      Group: 'Europe',
+      Tags: ['aws', 'edge'],
       CPU: 'not-an-array',
       MemTotal: '2048',
       LogicalCores: '4'
@@ -42,7 +49,8 @@ const result = normalizeMonitorHosts([
   },
   {
     Host: {
-      Name: 'CN-pending'
+      Name: 'CN-pending',
+      Tags: ['Edge']
     },
     TimeStamp: 0
   },
@@ -55,6 +63,12 @@ const result = normalizeMonitorHosts([
 ], 100, 10, charts)
 
 assert.deepEqual(result.areas, ['UK', 'CN'])
+assert.deepEqual(result.labels, ['Europe', 'aws', 'edge'])
+assert.equal(hostTitle(result.hosts[0]), 'London')
+assert.equal(hostTitle(result.hosts[1]), 'CN-pending')
+assert.equal(hostHasLabel(result.hosts[1], 'EDGE'), true)
+assert.equal(hostHasLabel(result.hosts[1], 'Europe'), false)
+assert.deepEqual(groupHosts(result.hosts).map((group) => [group.name, group.hosts.length]), [['Europe', 1], ['', 1]])
 assert.equal(result.hosts.length, 2)
 assert.equal(result.hosts[0].status, 1)
 assert.equal(result.hosts[1].status, 0)
@@ -72,15 +86,24 @@ assert.deepEqual(getHostChartSeries(charts, 'missing-node'), {
   cpu: [],
   mem: [],
   net_in: [],
-  net_out: []
+  net_out: [],
+  probes: {}
 })
 assert.equal(regionFlag('UK-node-1'), '🇬🇧')
 
 const emptyResult = normalizeMonitorHosts({ bad: 'shape' }, 100, 10, {})
-assert.deepEqual(emptyResult, { areas: [], hosts: [] })
+assert.deepEqual(emptyResult, { areas: [], labels: [], hosts: [] })
 
 assert.equal(normalizeChartLocale(''), DEFAULT_CHART_LOCALE)
 assert.equal(normalizeChartLocale('zh'), 'zh-CN')
 assert.equal(normalizeChartLocale('en'), 'en-US')
 assert.equal(normalizeChartLocale('en_US'), 'en-US')
 assert.equal(normalizeChartLocale('not a locale'), DEFAULT_CHART_LOCALE)
+
+assert.equal(statusPagePath('/'), '')
+assert.equal(statusPagePath('/d/customer-a'), '/d/customer-a')
+assert.equal(statusPagePath('/d/customer-a/'), '/d/customer-a')
+assert.equal(statusPagePath('/d/Customer'), '')
+assert.equal(statusPagePath('/dash/x'), '')
+assert.equal(statusPagePath('/s/0a1b.1700000000.Ab_c-9'), '/s/0a1b.1700000000.Ab_c-9')
+assert.equal(statusPagePath('/s/'), '')
@@ -4,6 +4,15 @@ export const normalizeAPIURL = (value) => {
   return (value || '').replace(/\/$/, '')
 }
 
+// statusPagePath returns the /d/{slug} prefix of a dashboard or the
+// /s/{token} prefix of a share link, or '' for the main status page. Config
+// and socket URLs hang off it.
+export const statusPagePath = (pathname) => {
+  const path = String(pathname || '')
+  const match = path.match(/^\/d\/[a-z0-9-]+(?=\/|$)/) || path.match(/^\/s\/[A-Za-z0-9._-]+(?=\/|$)/)
+  return match ? match[0] : ''
+}
+
 export const toFiniteNumber = (value, fallback = 0) => {
   const number = Number(value)
   return Number.isFinite(number) ? number : fallback
@@ -38,6 +47,55 @@ export const collectHostAreas = (hosts) => {
   return Array.from(new Set(areas))
 }
 
+// hostTitle is the name a card shows: the display name set in the admin
+// console, or the node ID when there is none.
+export const hostTitle = (host) => host?.Host?.DisplayName || host?.Host?.Name || ''
+
+// hostLabels returns the group followed by the tags, the same set the
+// server's tag filter matches.
+export const hostLabels = (host) => {
+  const group = host?.Host?.Group ? [host.Host.Group] : []
+  return group.concat(Array.isArray(host?.Host?.Tags) ? host.Host.Tags : [])
+}
+
+export const hostHasLabel = (host, label) => {
+  const wanted = String(label || '').toLowerCase()
+  return hostLabels(host).some((value) => String(value).toLowerCase() === wanted)
+}
+
+export const collectHostLabels = (hosts) => {
+  const seen = new Map()
+  ;(Array.isArray(hosts) ? hosts : []).forEach((host) => {
+    hostLabels(host).forEach((label) => {
+      const key = String(label).toLowerCase()
+      if (!seen.has(key)) {
+        seen.set(key, String(label))
+      }
+    })
+  })
+  return Array.from(seen.values())
+}
+
+// groupHosts splits hosts by group in the order the groups first appear.
+// Hosts without a group come last under an empty name.
+export const groupHosts = (hosts) => {
+  const groups = new Map()
+  ;(Array.isArray(hosts) ? hosts : []).forEach((host) => {
+    const name = String(host?.Host?.Group || '')
+    if (!groups.has(name)) {
+      groups.set(name, [])
+    }
+    groups.get(name).push(host)
+  })
+  const ungrouped = groups.get('')
+  groups.delete('')
+  const out = Array.from(groups, ([name, list]) => ({ name, hosts: list }))
+  if (ungrouped) {
+    out.push({ name: '', hosts: ungrouped })
+  }
+  return out
+}
+
 export const ensureHostChartSeries = (charts, hostName) => {
   if (!charts || !hostName) {
     return createEmptyChartSeries()
@@ -51,7 +109,8 @@ export const ensureHostChartSeries = (charts, hostName) => {
       cpu: Array.isArray(current.cpu) ? current.cpu : [],
       mem: Array.isArray(current.mem) ? current.mem : [],
       net_in: Array.isArray(current.net_in) ? current.net_in : [],
-      net_out: Array.isArray(current.net_out) ? current.net_out : []
+      net_out: Array.isArray(current.net_out) ? current.net_out : [],
+      probes: current.probes && typeof current.probes === 'object' ? current.probes : {}
     }
   }
   return charts[hostName]
@@ -61,7 +120,8 @@ export const createEmptyChartSeries = () => ({
   cpu: [],
   mem: [],
   net_in: [],
-  net_out: []
+  net_out: [],
+  probes: {}
 })
 
 export const getHostChartSeries = (charts, hostName) => {
@@ -80,10 +140,54 @@ export const normalizeDisk = (disk) => {
     ...source,
     mount: String(source.mount || source.Mount || ''),
     fs_type: String(source.fs_type || source.FSType || ''),
+    device: String(source.device || ''),
+    read_only: Boolean(source.read_only),
     total: toFiniteNumber(source.total ?? source.Total),
     used: toFiniteNumber(source.used ?? source.Used),
     free: toFiniteNumber(source.free ?? source.Free),
-    used_percent: toFiniteNumber(source.used_percent ?? source.UsedPercent)
+    used_percent: toFiniteNumber(source.used_percent ?? source.UsedPercent),
+    inodes_total: toFiniteNumber(source.inodes_total),
+    inodes_used_percent: toFiniteNumber(source.inodes_used_percent)
+  }
+}
+
+export const normalizeDiskDevice = (device) => {
+  const source = device && typeof device === 'object' ? device : {}
+  return {
+    ...source,
+    name: String(source.name || ''),
+    read_rate: toFiniteNumber(source.read_rate),
+    write_rate: toFiniteNumber(source.write_rate),
+    read_iops: toFiniteNumber(source.read_iops),
+    write_iops: toFiniteNumber(source.write_iops),
+    await_ms: toFiniteNumber(source.await_ms),
+    queue_depth: toFiniteNumber(source.queue_depth),
+    util_percent: toFiniteNumber(source.util_percent)
+  }
+}
+
+export const normalizeSensor = (sensor) => {
+  const source = sensor && typeof sensor === 'object' ? sensor : {}
+  return {
+    ...source,
+    chip: String(source.chip || ''),
+    label: String(source.label || ''),
+    kind: String(source.kind || ''),
+    value: toFiniteNumber(source.value),
+    critical: toFiniteNumber(source.critical)
+  }
+}
+
+export const normalizeProbe = (probe) => {
+  const source = probe && typeof probe === 'object' ? probe : {}
+  return {
+    ...source,
+    name: String(source.name || ''),
+    type: String(source.type || ''),
+    ts: toFiniteNumber(source.ts),
+    latency_ms: toFiniteNumber(source.latency_ms),
+    loss_percent: toFiniteNumber(source.loss_percent),
+    status_code: toFiniteNumber(source.status_code)
   }
 }
 
@@ -92,18 +196,21 @@ export const normalizeHostMeta = (host) => {
   return {
     ...source,
     Name: String(source.Name || ''),
+    DisplayName: String(source.DisplayName || ''),
     Hostname: String(source.Hostname || ''),
     Platform: String(source.Platform || 'unknown'),
     PlatformVersion: String(source.PlatformVersion || ''),
     Kernel: String(source.Kernel || ''),
     Arch: String(source.Arch || ''),
     Virtualization: String(source.Virtualization || ''),
-    CPU: Array.isArray(source.CPU) ? source.CPU : [],
+    CPU: Array.isArray(source.CPU) ? source.CPU.map((value) => toFiniteNumber(value)) : [],
     CPUModel: String(source.CPUModel || ''),
     PhysicalCores: toFiniteNumber(source.PhysicalCores),
     LogicalCores: toFiniteNumber(source.LogicalCores),
     MemTotal: toFiniteNumber(source.MemTotal),
-    SwapTotal: toFiniteNumber(source.SwapTotal)
+    SwapTotal: toFiniteNumber(source.SwapTotal),
+    Group: String(source.Group || ''),
+    Tags: Array.isArray(source.Tags) ? source.Tags.map((value) => String(value)) : []
   }
 }
 
@@ -112,6 +219,11 @@ export const normalizeHostState = (state) => {
   return {
     ...source,
     CPU: toFiniteNumber(source.CPU),
+    CPUUser: toFiniteNumber(source.CPUUser),
+    CPUSystem: toFiniteNumber(source.CPUSystem),
+    CPUIOWait: toFiniteNumber(source.CPUIOWait),
+    CPUSteal: toFiniteNumber(source.CPUSteal),
+    CPUIRQ: toFiniteNumber(source.CPUIRQ),
     MemUsed: toFiniteNumber(source.MemUsed),
     SwapUsed: toFiniteNumber(source.SwapUsed),
     DiskUsed: toFiniteNumber(source.DiskUsed),
@@ -123,6 +235,9 @@ export const normalizeHostState = (state) => {
     NetOutSpeed: toFiniteNumber(source.NetOutSpeed),
     DiskReadSpeed: toFiniteNumber(source.DiskReadSpeed),
     DiskWriteSpeed: toFiniteNumber(source.DiskWriteSpeed),
+    DiskDevices: Array.isArray(source.DiskDevices) ? source.DiskDevices.map(normalizeDiskDevice) : [],
+    Sensors: Array.isArray(source.Sensors) ? source.Sensors.map(normalizeSensor) : [],
+    Probes: Array.isArray(source.Probes) ? source.Probes.map(normalizeProbe).filter((probe) => probe.name) : [],
     TCP: toFiniteNumber(source.TCP),
     UDP: toFiniteNumber(source.UDP),
     Processes: toFiniteNumber(source.Processes),
@@ -174,6 +289,32 @@ export const appendHostChartPoint = (charts, host, limit = CHART_POINT_LIMIT) =>
   trimChartData(series.mem, limit)
   trimChartData(series.net_in, limit)
   trimChartData(series.net_out, limit)
+  appendProbeChartPoints(series, state.Probes, limit)
+}
+
+// appendProbeChartPoints adds one point per probe round. Reports arrive more
+// often than probes run, so a round already charted is skipped; a round that
+// lost every packet leaves a gap in the line.
+export const appendProbeChartPoints = (series, probes, limit = CHART_POINT_LIMIT) => {
+  const names = new Set()
+  ;(Array.isArray(probes) ? probes : []).forEach((probe) => {
+    if (!probe.name || !probe.ts) {
+      return
+    }
+    names.add(probe.name)
+    const points = series.probes[probe.name] || (series.probes[probe.name] = [])
+    const timestamp = probe.ts * 1000
+    if (points.length && points[points.length - 1][0] >= timestamp) {
+      return
+    }
+    points.push([timestamp, probe.loss_percent >= 100 ? null : probe.latency_ms])
+    trimChartData(points, limit)
+  })
+  Object.keys(series.probes).forEach((name) => {
+    if (!names.has(name)) {
+      delete series.probes[name]
+    }
+  })
 }
 
 export const normalizeMonitorHosts = (hosts, now, offlineWait, charts) => {
@@ -183,6 +324,7 @@ export const normalizeMonitorHosts = (hosts, now, offlineWait, charts) => {
     .filter((host) => host.Host.Name)
   return {
     areas: collectHostAreas(normalizedHosts),
+    labels: collectHostLabels(normalizedHosts),
     hosts: normalizedHosts
   }
 }